@@ -1,11 +1,18 @@
 package main
 
+import "wm-func/common/model"
+
+// rawDate 由 schema 中的 stat_date 列生成 raw_date
+func rawDate(s *model.Schema) string {
+	return "date_format(" + s.Extract("stat_date") + ", '%Y-%m-%d') as raw_date"
+}
+
 var airbyte_raw_tables = map[string][]string{
 	"raw_tiktok_marketing_gmv_max_metrics": {
-		"date_format(cast(json_extract(_airbyte_data, '$.stat_time_day') as varchar), '%Y-%m-%d') as raw_date",
+		rawDate(model.TiktokGmvMaxMetricsSchema),
 	},
 	"raw_applovin_ads_v2_ads_metrics": {
-		"date_format(cast(json_extract (_airbyte_data, '$.day') as varchar), '%Y-%m-%d') as raw_date",
+		rawDate(model.ApplovinAdsV2MetricsSchema),
 	},
 	"raw_snapchat_marketing_ads_stats_daily": {
 		"date_format(STR_TO_DATE (substring(json_unquote (_airbyte_data ->> '$.start_time'), 1, 19), '%Y-%m-%dT%H:%i:%s'), '%Y-%m-%d') as raw_date",
@@ -1,9 +1,11 @@
 package main
 
+import "wm-func/common/secrets"
+
 func getID() string {
-	return "TODO"
+	return secrets.MustGet(secrets.AmazonVendorClientID)
 }
 
 func getKey() string {
-	return "TODO"
+	return secrets.MustGet(secrets.AmazonVendorClientSecret)
 }
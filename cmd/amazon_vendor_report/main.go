@@ -2,6 +2,7 @@ package main
 
 import (
 	"bufio"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -33,17 +34,37 @@ func runCode() {
 
 	log.Println("本次有", len(accounts), "个用户在跑")
 
-	pool := t_pool.NewWorkerPool(5)
+	// 仍然逐个账户执行，协程池只用于统一的进度日志和 panic 收集
+	pool := t_pool.NewWorkerPoolWithOptions(context.Background(), t_pool.Options{
+		Workers:          1,
+		ProgressInterval: time.Minute,
+		Name:             platform,
+		Unit:             "accounts",
+	})
 	pool.Run()
-	for _, account := range accounts {
-		if account.TenantId == 150155 {
+	defer pool.Close()
+
+	var todo []account.Account
+	for _, acc := range accounts {
+		if acc.TenantId == 150155 {
 			continue
 		}
+		todo = append(todo, acc)
+	}
+	pool.SetTotal(len(todo))
+
+	for _, acc := range todo {
+		pool.AddTaskE(fmt.Sprintf("%d", acc.TenantId), func(context.Context) error {
+			fmt.Printf("Task %d: started\n", acc.TenantId)
+			execute(acc.TenantId, acc.AccountId, acc.RefreshToken, platform)
+			fmt.Printf("Task %d: completed\n", acc.TenantId)
+			//model.SaveSyncInfo(acc.TenantId, acc.AccountId, platform)
+			return nil
+		})
+	}
 
-		fmt.Printf("Task %d: started\n", account.TenantId)
-		execute(account.TenantId, account.AccountId, account.RefreshToken, platform)
-		fmt.Printf("Task %d: completed\n", account.TenantId)
-		//model.SaveSyncInfo(account.TenantId, account.AccountId, platform)
+	for _, e := range pool.Wait() {
+		log.Printf("Task %s failed: %v", e.Label, e.Err)
 	}
 }
 
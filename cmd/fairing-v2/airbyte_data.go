@@ -1,11 +1,13 @@
 package main
 
 import (
-	"gorm.io/gorm/clause"
 	"log"
-	"wm-func/common/db/airbyte_db"
+	"wm-func/common/model"
 )
 
+// rawWriter 本次运行共用的写入器，内容没有变化的行不会重复写入
+var rawWriter = model.NewRawWriter(model.RawWriterOptions{})
+
 type AirbyteData struct {
 	TenantId            int64  `gorm:"column:wm_tenant_id"`
 	AirbyteRawId        string `gorm:"column:_airbyte_raw_id"`
@@ -17,6 +19,19 @@ type AirbyteData struct {
 	ItemType            string `gorm:"-"` // 不映射到数据库，用于确定表名
 }
 
+// Raw 转换为通用的原始数据结构
+func (d AirbyteData) Raw() model.AirbyteRawData {
+	return model.AirbyteRawData{
+		TenantId:            d.TenantId,
+		AirbyteRawId:        d.AirbyteRawId,
+		AirbyteData:         d.AirbyteData,
+		AirbyteExtractedAt:  d.AirbyteExtractedAt,
+		AirbyteLoadedAt:     d.AirbyteLoadedAt,
+		AirbyteMeta:         d.AirbyteMeta,
+		AirbyteGenerationId: d.AirbyteGenerationId,
+	}
+}
+
 func GetTableNameWithType(subType string) string {
 	if subType == SubTypeRequest {
 		return "airbyte_destination_v2.raw_fairing_questions"
@@ -31,15 +46,15 @@ func SaveToAirbyte(account FAccount, data []AirbyteData, subType string) error {
 
 	traceId := account.GetTraceIdWithSubType(subType)
 
-	db := airbyte_db.GetDB()
 	table := GetTableNameWithType(subType)
-	if err := db.Table(table).
-		Clauses(clause.OnConflict{
-			Columns:   []clause.Column{{Name: "wm_tenant_id"}, {Name: "_airbyte_raw_id"}},
-			UpdateAll: true,
-		}).CreateInBatches(data, 500).Error; err != nil {
+	raws := make([]model.AirbyteRawData, len(data))
+	for i, d := range data {
+		raws[i] = d.Raw()
+	}
+	counts, err := rawWriter.Write(table, raws)
+	if err != nil {
 		return err
 	}
-	log.Printf("[%s] successfully inserted %d knocommerce %s records", traceId, len(data), subType)
+	log.Printf("[%s] fairing %s: %s", traceId, subType, counts)
 	return nil
 }
@@ -1,9 +1,11 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"time"
+	"wm-func/common/model"
 )
 
 func RequestQuestion(account FAccount) {
@@ -20,16 +22,46 @@ func RequestResponse(account FAccount) {
 	//now := time.Now().UTC()
 
 	//for start.Before(now) {
-	data := []FairingUserResponse{}
 	st := fmt.Sprintf("%sT00:00:00Z", start.Format("2006-01-02"))
 	ed := fmt.Sprintf("%sT23:59:59Z", start.Format("2006-01-02"))
 
+	// 每页拿到后立即写入，不在内存中保留全部回复
+	ctx := context.Background()
+	loader := model.NewBulkLoader(func(rows []AirbyteData) error {
+		return SaveToAirbyte(account, rows, SubTypeResponse)
+	}, model.BulkLoaderOptions[AirbyteData]{
+		Size: func(r AirbyteData) int { return len(r.AirbyteData) },
+	})
+	add := func(page []FairingUserResponse) {
+		for _, r := range page {
+			b, err := json.Marshal(r)
+			if err != nil {
+				panic(err)
+			}
+
+			now2 := time.Now().Format("2006-01-02 15:04:05")
+
+			err = loader.Add(ctx, AirbyteData{
+				TenantId:           account.TenantId,
+				AirbyteRawId:       r.Id,
+				AirbyteData:        b,
+				AirbyteExtractedAt: now2,
+				AirbyteLoadedAt:    now2,
+				AirbyteMeta:        `{}`,
+				ItemType:           "-",
+			})
+			if err != nil {
+				panic(err)
+			}
+		}
+	}
+
 	res, err := callFairingResponsesAPI(account, st, ed, 100)
 	if err != nil {
 		panic(err)
 	}
 
-	data = append(data, res.Data...)
+	add(res.Data)
 
 	var next = res.Next
 
@@ -39,38 +71,14 @@ func RequestResponse(account FAccount) {
 			panic(err2)
 		}
 
-		data = append(data, tmp.Data...)
+		add(tmp.Data)
 		next = tmp.Next
 		time.Sleep(time.Second * 2)
 	}
 
-	var insertAirbyteData []AirbyteData
-
-	for _, r := range data {
-		var b []byte
-		b, err = json.Marshal(r)
-		if err != nil {
-			panic(err)
-		}
-
-		now2 := time.Now().Format("2006-01-02 15:04:05")
-
-		//fmt.Println(string(b))
-
-		insertAirbyteData = append(insertAirbyteData, AirbyteData{
-			TenantId:            account.TenantId,
-			AirbyteRawId:        r.Id,
-			AirbyteData:         b,
-			AirbyteExtractedAt:  now2,
-			AirbyteLoadedAt:     now2,
-			AirbyteMeta:         `{}`,
-			AirbyteGenerationId: 0,
-			ItemType:            "-",
-		})
-
+	if _, err = loader.Close(); err != nil {
+		panic(err)
 	}
-
-	SaveToAirbyte(account, insertAirbyteData, SubTypeResponse)
 	start = start.Add(time.Hour * 24)
 
 	//}
@@ -1,8 +1,14 @@
 package main
 
+import "time"
+
 const (
 	Platform   = "fairing"
 	MaxWorkers = 8 // 减少worker数量，为多实例留出资源
+
+	TaskStaleAfter    = 10 * time.Minute // 心跳超过该时长未更新视为认领实例已失效
+	HeartbeatInterval = time.Minute      // 任务运行期间的心跳间隔
+	ProgressInterval  = time.Minute      // 打印整体进度的间隔
 )
 
 var subTypes = []string{"question", "response"}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -12,22 +13,37 @@ import (
 	"wm-func/wm_account"
 )
 
+var (
+	// syncStateStore question 等子类型的同步状态
+	syncStateStore = state.NewStore[SyncState]()
+	// fairingStateStore response 的分片同步状态
+	fairingStateStore = state.NewStore[FairingSyncState]()
+	// rawStateStore 读取未解析的状态，用于兼容旧版本的 SyncState
+	rawStateStore = state.NewStore[json.RawMessage]()
+)
+
+func stateKey(account wm_account.Account, subType string) state.StateKey {
+	return state.StateKey{
+		Tenant:   account.TenantId,
+		Account:  account.AccountId,
+		Platform: Platform,
+		SubType:  subType,
+	}
+}
+
 // 获取账户的同步状态
 func getState(account wm_account.Account, subType string) (SyncState, error) {
 	traceId := getTraceIdWithSubType(account, subType)
-	syncInfo := state.GetSyncInfo(account.TenantId, account.AccountId, Platform, subType)
-
-	var syncState SyncState
-	if syncInfo == nil {
+	syncState, found, err := syncStateStore.Load(context.Background(), stateKey(account, subType))
+	if err != nil {
+		log.Printf("[%s] 读取同步状态失败: %v", traceId, err)
+		return syncState, fmt.Errorf("读取同步状态失败: %w", err)
+	}
+	if !found {
 		log.Printf("[%s] 首次同步，没有历史状态", traceId)
 		return syncState, nil
 	}
 
-	if err := json.Unmarshal(syncInfo, &syncState); err != nil {
-		log.Printf("[%s] 解析同步状态失败: %v", traceId, err)
-		return syncState, fmt.Errorf("解析同步状态失败: %w", err)
-	}
-
 	// 根据数据类型显示不同的状态信息
 	switch subType {
 	case "question":
@@ -49,10 +65,13 @@ func getState(account wm_account.Account, subType string) (SyncState, error) {
 // 获取 Fairing 专属的同步状态
 func getFairingState(account wm_account.Account, subType string) (FairingSyncState, error) {
 	traceId := getTraceIdWithSubType(account, subType)
-	syncInfo := state.GetSyncInfo(account.TenantId, account.AccountId, Platform, subType)
+	syncInfo, found, err := rawStateStore.Load(context.Background(), stateKey(account, subType))
+	if err != nil {
+		return FairingSyncState{}, fmt.Errorf("[%s] 读取同步状态失败: %w", traceId, err)
+	}
 
 	var fairingSyncState FairingSyncState
-	if syncInfo == nil {
+	if !found {
 		log.Printf("[%s] 首次同步，创建新的状态", traceId)
 		fairingSyncState = NewFairingSyncState()
 		return fairingSyncState, nil
@@ -118,13 +137,10 @@ func convertOldSyncState(oldState SyncState) FairingSyncState {
 // 更新 Fairing 专属的同步状态
 func updateFairingState(account wm_account.Account, fairingSyncState FairingSyncState, subType string) error {
 	traceId := getTraceIdWithSubType(account, subType)
-	stateData, err := json.Marshal(fairingSyncState)
-	if err != nil {
-		return fmt.Errorf("[%s] 序列化同步状态失败: %w", traceId, err)
+	if err := fairingStateStore.Save(context.Background(), stateKey(account, subType), fairingSyncState); err != nil {
+		return fmt.Errorf("[%s] 保存同步状态失败: %w", traceId, err)
 	}
 
-	state.SaveSyncInfo(account.TenantId, account.AccountId, Platform, subType, stateData)
-
 	// 详细的状态日志
 	if fairingSyncState.IsInitialSync && fairingSyncState.CurrentSliceDate != nil {
 		log.Printf("[%s] 同步状态已更新 - 首次同步进行中，当前日期: %s，已完成%d个slice",
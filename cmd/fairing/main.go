@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"log"
 	"time"
 	t_pool "wm-func/common/pool"
@@ -22,11 +23,11 @@ func main() {
 	// 确保程序结束时打印统计信息
 	defer printFinalStats()
 
-	run()
+	run(instanceConfig.InstanceId)
 	log.Println("end run fairing data sync...")
 }
 
-func run() {
+func run(ownerId string) {
 	accounts := wm_account.GetFairingAccounts()
 	log.Printf("start total accounts: %d", len(accounts))
 
@@ -34,7 +35,12 @@ func run() {
 	globalStats.TotalAccounts = len(accounts)
 
 	// 为了支持多实例并发，使用较小的worker pool
-	pool := t_pool.NewWorkerPool(MaxWorkers)
+	pool := t_pool.NewWorkerPoolWithOptions(context.Background(), t_pool.Options{
+		Workers:          MaxWorkers,
+		ProgressInterval: ProgressInterval,
+		Name:             Platform,
+		Unit:             "accounts",
+	})
 	pool.Run()
 	defer pool.Close()
 
@@ -46,7 +52,7 @@ func run() {
 		acc := account
 		pool.AddTask(func() {
 			log.Printf("[%s] start process account", acc.GetTraceId())
-			processAccount(acc)
+			processAccount(acc, ownerId)
 			log.Printf("[%s] end process account", acc.GetTraceId())
 		})
 	}
@@ -55,20 +61,31 @@ func run() {
 }
 
 // processAccount 处理单个账户的所有数据类型
-func processAccount(account wm_account.Account) {
+func processAccount(account wm_account.Account, ownerId string) {
 	// 处理question和response两种数据类型
 	for _, subType := range subTypes {
-		processTask(account, subType)
+		processTask(account, subType, ownerId)
 	}
 }
 
+// claimTask 认领任务，数据库出错时按未获取处理
+func claimTask(key state.StateKey, ownerId, traceId string) state.TaskResult {
+	taskResult, err := state.ClaimTask(key, ownerId, TaskStaleAfter)
+	if err != nil {
+		log.Printf("[%s] 认领任务失败: %v", traceId, err)
+		return state.TaskResult{Status: state.TaskStatusAlreadyRunning}
+	}
+	return taskResult
+}
+
 // processTask 处理单个任务（账户+数据类型）
-func processTask(account wm_account.Account, subType string) {
+func processTask(account wm_account.Account, subType, ownerId string) {
 	traceId := getTraceIdWithSubType(account, subType)
+	key := state.StateKey{Tenant: account.TenantId, Account: account.AccountId, Platform: Platform, SubType: subType}
 	log.Printf("[%s] 尝试获取任务锁", traceId)
 
-	// 1. 尝试获取任务锁
-	taskResult := state.GetAvailableTask(account.TenantId, account.AccountId, Platform, subType)
+	// 1. 尝试认领任务
+	taskResult := claimTask(key, ownerId, traceId)
 
 	switch taskResult.Status {
 	case state.TaskStatusNotFound:
@@ -81,32 +98,47 @@ func processTask(account wm_account.Account, subType string) {
 			// 其他类型使用原有逻辑
 			createInitialState(account, subType)
 		}
-		// 重新尝试获取任务
-		taskResult = state.GetAvailableTask(account.TenantId, account.AccountId, Platform, subType)
+		// 重新尝试认领任务
+		taskResult = claimTask(key, ownerId, traceId)
 		if taskResult.Status != state.TaskStatusAcquired {
 			log.Printf("[%s] 任务创建后仍无法获取，跳过", traceId)
 			skipTaskStats()
 			return
 		}
 	case state.TaskStatusAlreadyRunning:
-		log.Printf("[%s] 任务正在其他实例运行，跳过", traceId)
+		if taskResult.Claim != nil {
+			log.Printf("[%s] 任务正在实例 %s 运行，跳过", traceId, taskResult.Claim.OwnerId)
+		} else {
+			log.Printf("[%s] 任务正在其他实例运行，跳过", traceId)
+		}
 		skipTaskStats()
 		return
-	case state.TaskStatusAcquired:
+	}
+	if taskResult.Claim != nil && taskResult.Claim.Attempts > 1 {
+		log.Printf("[%s] 任务锁获取成功，自上次成功以来第 %d 次尝试", traceId, taskResult.Claim.Attempts)
+	} else {
 		log.Printf("[%s] 任务锁获取成功", traceId)
 	}
 
-	// 2. 确保在函数结束时释放锁
+	// 2. 运行期间保持心跳，长时间回填不会被其他实例接管
+	stopHeartbeat := state.KeepAlive(context.Background(), key, ownerId, HeartbeatInterval)
+
+	// 3. 确保在函数结束时释放锁，失败时保留尝试次数
+	success := false
 	defer func() {
 		if err := recover(); err != nil {
 			log.Printf("[%s] 任务执行出现panic: %v，释放锁", traceId, err)
 			updateTaskStats(subType, false)
 		}
-		state.SetStop(account.TenantId, account.AccountId, Platform, subType)
+		stopHeartbeat()
+		if err := state.ReleaseTask(key, ownerId, success); err != nil {
+			log.Printf("[%s] 任务锁释放失败: %v", traceId, err)
+			return
+		}
 		log.Printf("[%s] 任务锁已释放", traceId)
 	}()
 
-	// 3. 执行具体的同步任务（优先使用支持时间范围的版本）
+	// 4. 执行具体的同步任务（优先使用支持时间范围的版本）
 	var err error
 	if subType == "response" {
 		// response 使用新的时间范围同步逻辑
@@ -115,7 +147,7 @@ func processTask(account wm_account.Account, subType string) {
 		// question 继续使用原有逻辑
 		err = execTask(account, subType)
 	}
-	success := err == nil
+	success = err == nil
 	updateTaskStats(subType, success)
 
 	if err != nil {
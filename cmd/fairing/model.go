@@ -2,10 +2,9 @@ package main
 
 import (
 	"log"
-	"wm-func/common/db/airbyte_db"
+	"time"
+	"wm-func/common/model"
 	"wm-func/wm_account"
-
-	"gorm.io/gorm/clause"
 )
 
 const (
@@ -14,6 +13,39 @@ const (
 	STATUS_FAILED  = "FAILED"
 )
 
+// rawWriter 本次运行共用的写入器，内容没有变化的行不会重复写入
+var rawWriter = model.NewRawWriter(model.RawWriterOptions{})
+
+// questionTombstoneRetention 问题在上游删除后墓碑行保留的时长，超过后物理删除
+var questionTombstoneRetention = 30 * 24 * time.Hour
+
+// questionKeyPrefix 问题 _airbyte_raw_id 的前缀，按账户区分同一租户下的多个账户，也是全量刷新的范围
+func questionKeyPrefix(account wm_account.Account) string {
+	return account.AccountId + "|"
+}
+
+// saveFairingQuestions 全量刷新问题，上游已删除的问题在 _airbyte_meta 中标记为墓碑
+// data 必须是账户的完整问题列表，接口调用失败时不能调用
+func saveFairingQuestions(account wm_account.Account, data []FairingData) error {
+	traceId := getTraceIdWithSubType(account, "question")
+
+	refresh := rawWriter.BeginFullRefresh("airbyte_destination_v2.raw_fairing_questions", account.TenantId, model.FullRefreshOptions{
+		HardDeleteAfter: questionTombstoneRetention,
+		KeyPrefix:       questionKeyPrefix(account),
+	})
+	counts, err := refresh.Write(rawData(data))
+	if err != nil {
+		refresh.Abort()
+		return err
+	}
+	res, err := refresh.Commit()
+	if err != nil {
+		return err
+	}
+	log.Printf("[%s] fairing question: %s, %s", traceId, counts, res)
+	return nil
+}
+
 // 保存fairing数据到数据库
 func saveFairingData(account wm_account.Account, data []FairingData, subType string) error {
 	if len(data) == 0 {
@@ -21,7 +53,6 @@ func saveFairingData(account wm_account.Account, data []FairingData, subType str
 	}
 
 	traceId := getTraceIdWithSubType(account, subType)
-	db := airbyte_db.GetDB()
 
 	// 根据数据类型分组保存到不同的表
 	questionsData := make([]FairingData, 0)
@@ -38,32 +69,35 @@ func saveFairingData(account wm_account.Account, data []FairingData, subType str
 
 	// 保存 questions 数据
 	if len(questionsData) > 0 {
-		if err := db.Table("airbyte_destination_v2.raw_fairing_questions").
-			Clauses(clause.OnConflict{
-				Columns:   []clause.Column{{Name: "wm_tenant_id"}, {Name: "_airbyte_raw_id"}},
-				UpdateAll: true,
-			}).CreateInBatches(questionsData, 500).Error; err != nil {
+		counts, err := rawWriter.Write("airbyte_destination_v2.raw_fairing_questions", rawData(questionsData))
+		if err != nil {
 			return err
 		}
-		log.Printf("[%s] successfully inserted %d fairing question records", traceId, len(questionsData))
+		log.Printf("[%s] fairing question: %s", traceId, counts)
 	}
 
 	// 保存 responses 数据
 	if len(responsesData) > 0 {
-		if err := db.Table("airbyte_destination_v2.raw_fairing_responses").
-			Clauses(clause.OnConflict{
-				Columns:   []clause.Column{{Name: "wm_tenant_id"}, {Name: "_airbyte_raw_id"}},
-				UpdateAll: true,
-			}).CreateInBatches(responsesData, 500).Error; err != nil {
+		counts, err := rawWriter.Write("airbyte_destination_v2.raw_fairing_responses", rawData(responsesData))
+		if err != nil {
 			return err
 		}
-		log.Printf("[%s] successfully inserted %d fairing response records", traceId, len(responsesData))
+		log.Printf("[%s] fairing response: %s", traceId, counts)
 	}
 
-	log.Printf("[%s] successfully inserted %d fairing records total", traceId, len(data))
+	log.Printf("[%s] successfully saved %d fairing records total", traceId, len(data))
 	return nil
 }
 
+// rawData 转换为 RawWriter 写入的原始数据
+func rawData(data []FairingData) []model.AirbyteRawData {
+	raws := make([]model.AirbyteRawData, len(data))
+	for i, d := range data {
+		raws[i] = d.Raw()
+	}
+	return raws
+}
+
 // create table airbyte_destination_v2.raw_fairing_questions
 // (
 //     wm_tenant_id           bigint                    null,
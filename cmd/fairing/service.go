@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -8,7 +9,6 @@ import (
 	"strconv"
 	"time"
 	"wm-func/common/http_request"
-	"wm-func/common/state"
 	"wm-func/wm_account"
 )
 
@@ -137,7 +137,10 @@ func processQuestionsData(account wm_account.Account, questions []FairingQuestio
 	var data []FairingData
 
 	for _, question := range questions {
-		data = append(data, question.TransformToFairingData(account.TenantId))
+		d := question.TransformToFairingData(account.TenantId)
+		// 问题 ID 只在账户内唯一，加上账户前缀后才能按账户全量刷新
+		d.AirbyteRawId = questionKeyPrefix(account) + d.AirbyteRawId
+		data = append(data, d)
 	}
 
 	return data, nil
@@ -157,12 +160,9 @@ func processResponsesData(account wm_account.Account, responses []FairingUserRes
 // 更新同步状态
 func updateSyncState(account wm_account.Account, syncState SyncState, subType string) error {
 	traceId := getTraceIdWithSubType(account, subType)
-	stateData, err := json.Marshal(syncState)
-	if err != nil {
-		return fmt.Errorf("[%s] 序列化同步状态失败: %w", traceId, err)
+	if err := syncStateStore.Save(context.Background(), stateKey(account, subType), syncState); err != nil {
+		return fmt.Errorf("[%s] 保存同步状态失败: %w", traceId, err)
 	}
-
-	state.SaveSyncInfo(account.TenantId, account.AccountId, Platform, subType, stateData)
 	log.Printf("[%s] 同步状态已更新, 状态: %s", traceId, syncState.Status)
 
 	return nil
@@ -186,25 +186,15 @@ func syncQuestionsData(account wm_account.Account, syncState SyncState) error {
 		return fmt.Errorf("Questions数据处理失败: %w", err)
 	}
 
-	// 3. 检查数据是否有变化
+	// 3. 全量刷新保存，记录数相同也可能有删除和新增，内容没有变化的行由 RawWriter 跳过
 	currentCount := int64(len(fairingData))
-	if currentCount == syncState.RecordCount {
-		log.Printf("[%s] Questions数据无变化，跳过保存。记录数: %d", traceId, currentCount)
-		syncState.Status = STATUS_SUCCESS
-		syncState.Message = fmt.Sprintf("Questions数据无变化，记录数: %d", currentCount)
-		syncState.UpdatedAt = time.Now().UTC().Truncate(time.Millisecond)
-		updateSyncState(account, syncState, "question")
-		return nil
-	}
-
-	// 4. 保存数据
 	if len(fairingData) > 0 {
-		if err = saveFairingData(account, fairingData, "question"); err != nil {
+		if err = saveFairingQuestions(account, fairingData); err != nil {
 			return fmt.Errorf("Questions数据保存失败: %w", err)
 		}
 	}
 
-	// 5. 更新状态
+	// 4. 更新状态
 	syncState.Status = STATUS_SUCCESS
 	syncState.Message = fmt.Sprintf("Questions全量同步完成，共%d条记录（上次: %d）", currentCount, syncState.RecordCount)
 	syncState.RecordCount = currentCount
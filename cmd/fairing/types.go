@@ -6,6 +6,7 @@ import (
 	"regexp"
 	"strings"
 	"time"
+	"wm-func/common/model"
 )
 
 // Fairing Questions API 响应结构（根据真实文档）
@@ -142,6 +143,19 @@ type FairingData struct {
 	ItemType            string `gorm:"-"` // 不映射到数据库，用于确定表名
 }
 
+// Raw 转换为通用的原始数据结构
+func (d FairingData) Raw() model.AirbyteRawData {
+	return model.AirbyteRawData{
+		TenantId:            d.TenantId,
+		AirbyteRawId:        d.AirbyteRawId,
+		AirbyteData:         d.AirbyteData,
+		AirbyteExtractedAt:  d.AirbyteExtractedAt,
+		AirbyteLoadedAt:     d.AirbyteLoadedAt,
+		AirbyteMeta:         d.AirbyteMeta,
+		AirbyteGenerationId: d.AirbyteGenerationId,
+	}
+}
+
 // 根据数据类型返回对应的表名
 func (f *FairingData) TableName() string {
 	switch f.ItemType {
@@ -195,14 +209,13 @@ func (q FairingQuestion) TransformToFairingData(tenantId int64) FairingData {
 	now := time.Now().Format("2006-01-02 15:04:05")
 
 	return FairingData{
-		TenantId:            tenantId,
-		AirbyteRawId:        rawId,
-		AirbyteData:         jsonData,
-		AirbyteExtractedAt:  now,
-		AirbyteLoadedAt:     now,
-		AirbyteMeta:         `{}`,
-		AirbyteGenerationId: 0,
-		ItemType:            "question", // 设置类型用于确定表名
+		TenantId:           tenantId,
+		AirbyteRawId:       rawId,
+		AirbyteData:        jsonData,
+		AirbyteExtractedAt: now,
+		AirbyteLoadedAt:    now,
+		AirbyteMeta:        `{}`,
+		ItemType:           "question", // 设置类型用于确定表名
 	}
 }
 
@@ -246,14 +259,13 @@ func (r FairingUserResponse) TransformToFairingData(tenantId int64) FairingData
 	now := time.Now().Format("2006-01-02 15:04:05")
 
 	return FairingData{
-		TenantId:            tenantId,
-		AirbyteRawId:        rawId,
-		AirbyteData:         jsonData,
-		AirbyteExtractedAt:  now,
-		AirbyteLoadedAt:     now,
-		AirbyteMeta:         `{}`,
-		AirbyteGenerationId: 0,
-		ItemType:            "response", // 设置类型用于确定表名
+		TenantId:           tenantId,
+		AirbyteRawId:       rawId,
+		AirbyteData:        jsonData,
+		AirbyteExtractedAt: now,
+		AirbyteLoadedAt:    now,
+		AirbyteMeta:        `{}`,
+		ItemType:           "response", // 设置类型用于确定表名
 	}
 }
 
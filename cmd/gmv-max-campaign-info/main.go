@@ -3,9 +3,8 @@ package main
 import (
 	"encoding/json"
 	"fmt"
-	"gorm.io/gorm/clause"
 	"time"
-	"wm-func/common/db/airbyte_db"
+	"wm-func/common/model"
 	"wm-func/wm_account"
 )
 
@@ -20,6 +19,7 @@ func main() {
 		150096: true,
 	}
 
+	rawWriter := model.NewRawWriter(model.RawWriterOptions{})
 	for _, account := range accounts {
 		if tenantIds[account.TenantId] {
 			continue
@@ -32,30 +32,27 @@ func main() {
 			campaignInfo = append(campaignInfo, *RequestById(account.AccountId, id, account.AccessToken))
 		}
 
-		var insertData []AribyteDate
+		var insertData []model.AirbyteRawData
 		for _, data := range campaignInfo {
 			b, err := json.Marshal(data)
 			if err != nil {
 				panic(err)
 			}
 
-			insertData = append(insertData, AribyteDate{
-				TenantId:            account.TenantId,
-				AirbyteRawId:        fmt.Sprintf("%s|%s", account.AccountId, data.CampaignId),
-				AirbyteData:         b,
-				AirbyteExtractedAt:  time.Now().Format("2006-01-02 15:04:05"),
-				AirbyteLoadedAt:     time.Now().Format("2006-01-02 15:04:05"),
-				AirbyteMeta:         `{}`,
-				AirbyteGenerationId: 0,
-				ItemType:            "-",
+			insertData = append(insertData, model.AirbyteRawData{
+				TenantId:           account.TenantId,
+				AirbyteRawId:       fmt.Sprintf("%s|%s", account.AccountId, data.CampaignId),
+				AirbyteData:        b,
+				AirbyteExtractedAt: time.Now().Format("2006-01-02 15:04:05"),
+				AirbyteMeta:        `{}`,
 			})
 
 		}
-		db := airbyte_db.GetDB()
-		if err := db.Clauses(clause.OnConflict{UpdateAll: true}).CreateInBatches(insertData, 1).Error; err != nil {
+		counts, err := rawWriter.Write(AribyteDate{}.TableName(), insertData)
+		if err != nil {
 			panic(err)
 		}
 
-		fmt.Println(ids)
+		fmt.Println(ids, counts)
 	}
 }
@@ -3,12 +3,23 @@ package main
 import (
 	"fmt"
 	lock2 "wm-func/common/lock"
+	"wm-func/common/model"
 	"wm-func/wm_account"
 )
 
 type KAccount struct {
 	wm_account.Account
 	lock2.Locker
+
+	// fence 获取账户锁时分配的 fencing 凭证，保存同步状态和原始数据时校验
+	fence lock2.Fence
+	// raw 带 fence 的原始数据写入器，内容没有变化的行不会重复写入
+	raw *model.RawWriter
+}
+
+// RawKeyPrefix 问题、问卷 _airbyte_raw_id 的前缀，按 wm 账户区分同一租户下的多个账户，也是全量刷新的范围
+func (ka KAccount) RawKeyPrefix() string {
+	return fmt.Sprintf("%d|%s|", ka.TenantId, ka.AccountId)
 }
 
 // GetSimpleTraceId 获取简化的跟踪ID (只包含TenantId，不包含AccountId)
@@ -1,5 +1,7 @@
 package main
 
+import "wm-func/common/model"
+
 type AirbyteData struct {
 	TenantId            int64  `gorm:"column:wm_tenant_id"`
 	AirbyteRawId        string `gorm:"column:_airbyte_raw_id"`
@@ -10,3 +12,16 @@ type AirbyteData struct {
 	AirbyteGenerationId int64  `gorm:"column:_airbyte_generation_id"`
 	ItemType            string `gorm:"-"` // 不映射到数据库，用于确定表名
 }
+
+// Raw 转换为通用的原始数据结构
+func (d AirbyteData) Raw() model.AirbyteRawData {
+	return model.AirbyteRawData{
+		TenantId:            d.TenantId,
+		AirbyteRawId:        d.AirbyteRawId,
+		AirbyteData:         d.AirbyteData,
+		AirbyteExtractedAt:  d.AirbyteExtractedAt,
+		AirbyteLoadedAt:     d.AirbyteLoadedAt,
+		AirbyteMeta:         d.AirbyteMeta,
+		AirbyteGenerationId: d.AirbyteGenerationId,
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
@@ -12,7 +13,7 @@ import (
 	"time"
 )
 
-func RefreshToken(account KAccount) (*RefreshTokenResponse, error) {
+func RefreshToken(ctx context.Context, account KAccount) (*RefreshTokenResponse, error) {
 	traceId := account.GetTraceId()
 	log.Printf("[%s] 开始RefreshToken，请求授权token", traceId)
 
@@ -26,11 +27,11 @@ func RefreshToken(account KAccount) (*RefreshTokenResponse, error) {
 
 	// 3. 构造完整的请求 URL
 	fullURL := apiURL + "?" + params.Encode()
-	log.Printf("[%s] 构建请求URL: %s", traceId, fullURL)
+	log.Printf("[%s] 构建请求URL: %s", traceId, apiURL)
 
 	// 4. 创建一个新的 POST 请求
 	// 第三个参数是请求体，这里我们没有请求体，所以是 nil
-	req, err := http.NewRequest("POST", fullURL, nil)
+	req, err := http.NewRequestWithContext(ctx, "POST", fullURL, nil)
 	if err != nil {
 		log.Printf("[%s] 创建HTTP请求失败: %v", traceId, err)
 		return nil, fmt.Errorf("创建请求失败: %w", err)
@@ -87,6 +88,7 @@ func RefreshToken(account KAccount) (*RefreshTokenResponse, error) {
 
 // TokenManager 管理token的生命周期，支持过期自动续期
 type TokenManager struct {
+	ctx        context.Context       // 刷新token的请求受其控制
 	mutex      sync.RWMutex          // 读写锁，保证并发安全
 	account    KAccount              // 账户信息
 	token      *RefreshTokenResponse // 当前token
@@ -94,8 +96,9 @@ type TokenManager struct {
 }
 
 // NewTokenManager 创建新的token管理器
-func NewTokenManager(account KAccount) *TokenManager {
+func NewTokenManager(ctx context.Context, account KAccount) *TokenManager {
 	return &TokenManager{
+		ctx:     ctx,
 		account: account,
 	}
 }
@@ -127,7 +130,7 @@ func (tm *TokenManager) GetValidToken() (*RefreshTokenResponse, error) {
 	log.Printf("[%s] token已过期或不存在，开始刷新token", traceId)
 
 	// 刷新token
-	newToken, err := RefreshToken(tm.account)
+	newToken, err := RefreshToken(tm.ctx, tm.account)
 	if err != nil {
 		return nil, fmt.Errorf("刷新token失败: %w", err)
 	}
@@ -163,7 +166,7 @@ func (tm *TokenManager) GetAccessToken() string {
 // 使用示例：
 /*
 // 1. 创建token管理器（一般在应用启动时创建，作为全局实例）
-tokenManager := NewTokenManager(account)
+tokenManager := NewTokenManager(ctx, account)
 
 // 2. 在需要使用token的地方，直接调用GetValidToken或GetAccessToken
 // 方法会自动处理token过期和刷新逻辑
@@ -1,5 +1,7 @@
 package main
 
+import "time"
+
 var subTypeList = []string{SUBTYPE_QUESTION, SUBTYPE_RESPONSE, SUBTYPE_RESPONSE_COUNT, SUBTYPE_SURVEY}
 
 var timeRageHourMap = map[string]int{
@@ -16,3 +18,6 @@ var timeStepDailyMap = map[string]int{
 }
 
 var preDays = 365
+
+// tombstoneRetention 问题、问卷在上游删除后墓碑行保留的时长，超过后物理删除
+var tombstoneRetention = 30 * 24 * time.Hour
@@ -1,17 +1,27 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 	lock2 "wm-func/common/lock"
+	"wm-func/common/model"
 	t_pool "wm-func/common/pool"
 	"wm-func/wm_account"
 )
 
 const Platform = "knocommerce"
 
+// shutdownGrace 收到 SIGTERM 后等待运行中任务结束的时间，需小于 Cloud Run 的终止宽限期
+const shutdownGrace = 8 * time.Second
+
+// hostname 锁持有者 ID 的一部分，容器内 PID 往往都是 1，需要主机名区分实例
+var hostname, _ = os.Hostname()
+
 func main() {
 	log.Printf("[%s] Knocommerce数据同步程序启动", Platform)
 
@@ -22,44 +32,67 @@ func main() {
 	var kaccounts = []KAccount{}
 	for _, account := range accounts {
 		kaccounts = append(kaccounts, KAccount{
-			account,
-			lock,
+			Account: account,
+			Locker:  lock,
 		})
 	}
 
-	pool := t_pool.NewWorkerPool(10)
+	// 按租户轮流调度，同一租户的账户依次执行，账户很多的租户不会占满所有 worker
+	pool := t_pool.NewKeyedPool(10, 1)
 	pool.Run()
+	defer pool.Close()
+
+	// 收到 SIGTERM 时停止派发新账户，等待运行中的账户结束
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownGrace)
+		defer cancel()
+		if err := pool.Shutdown(shutdownCtx); err != nil {
+			log.Printf("[%s] 等待运行中任务结束超时: %v", Platform, err)
+		}
+	}()
 
 	for _, account := range kaccounts {
-		ac := account
-		lockKey := fmt.Sprintf("knocommerce:%d:%s", ac.TenantId, ac.AccountId)
-		ownerID := fmt.Sprintf("process-%d", os.Getpid())
-		// 先写死180分钟
-		lockDuration := 180 * time.Minute
-
-		err := ac.TryLock(lockKey, ownerID, lockDuration)
-		if err == nil {
-			// 成功获取锁
-			pool.AddTask(func() {
-				run(ac)
-				// 任务完成后释放锁
-				ac.Unlock(lockKey, ownerID)
-			})
-		} else {
-			// 获取锁失败
-			log.Printf("[%s] 无法获取锁，跳过该账户: %v", ac.GetSimpleTraceId(), err)
+		if ctx.Err() != nil {
+			log.Printf("[%s] 收到终止信号，停止派发剩余账户", Platform)
+			break
 		}
+		ac := account
+		pool.AddTaskE(ac.GetSimpleTraceId(), ac.GetSimpleTraceId(), func(taskCtx context.Context) error {
+			lockKey := fmt.Sprintf("knocommerce:%d:%s", ac.TenantId, ac.AccountId)
+			ownerID := fmt.Sprintf("process-%s-%d-%d", hostname, os.Getpid(), time.Now().UnixNano())
+
+			// 在任务开始执行时才获取锁，被 Shutdown 跳过的账户不会持有锁或续期
+			session, err := lock2.LockWithHeartbeat(taskCtx, ac, lockKey, ownerID, lock2.DefaultLeaseTTL)
+			if err != nil {
+				log.Printf("[%s] 无法获取锁，跳过该账户: %v", ac.GetSimpleTraceId(), err)
+				return nil
+			}
+			// 任务完成后停止续期并释放锁，run 中的 panic 由协程池恢复
+			defer session.Close()
+			ac.fence = session.Fence()
+			ac.raw = model.NewRawWriter(model.RawWriterOptions{Generation: generation, Fence: ac.fence})
+
+			// 锁丢失或收到终止信号超时时，session 的 Context 被取消，请求和写库随之中止
+			run(session.Context(), ac)
+			return session.Err()
+		})
+	}
+	errs := pool.Wait()
+	for _, e := range errs {
+		log.Printf("[%s] 账户处理失败: %v", Platform, e)
 	}
-	pool.Wait()
 
-	log.Printf("[%s] Knocommerce数据同步程序结束", Platform)
+	log.Printf("[%s] Knocommerce数据同步程序结束，失败账户: %d", Platform, len(errs))
 }
 
-func run(account KAccount) {
+func run(ctx context.Context, account KAccount) {
 	traceId := account.GetSimpleTraceId()
 	log.Printf("[%s] 开始处理账户", traceId)
 
-	token := NewTokenManager(account)
+	token := NewTokenManager(ctx, account)
 	//token, err := RefreshToken(account)
 	//if err != nil {
 	//	log.Printf("[%s] RefreshToken失败: %v", traceId, err)
@@ -69,22 +102,22 @@ func run(account KAccount) {
 
 	questionTraceId := account.GetTraceIdWithSubType(SUBTYPE_QUESTION)
 	log.Printf("[%s] 开始RequestQuestion", questionTraceId)
-	RequestQuestion(account, token)
+	RequestQuestion(ctx, account, token)
 	log.Printf("[%s] RequestQuestion完成", questionTraceId)
 
 	surveyTraceId := account.GetTraceIdWithSubType(SUBTYPE_SURVEY)
 	log.Printf("[%s] 开始RequestSurvey", surveyTraceId)
-	RequestSurvey(account, token)
+	RequestSurvey(ctx, account, token)
 	log.Printf("[%s] RequestSurvey完成", surveyTraceId)
 
 	responseCountTraceId := account.GetTraceIdWithSubType(SUBTYPE_RESPONSE_COUNT)
 	log.Printf("[%s] 开始RequestResponseCount", responseCountTraceId)
-	RequestResponseCount(account, token)
+	RequestResponseCount(ctx, account, token)
 	log.Printf("[%s] RequestResponseCount完成", responseCountTraceId)
 
 	responseTraceId := account.GetTraceIdWithSubType(SUBTYPE_RESPONSE)
 	log.Printf("[%s] 开始RequestResponse", responseTraceId)
-	RequestResponse(account, token)
+	RequestResponse(ctx, account, token)
 	log.Printf("[%s] RequestResponse完成", responseTraceId)
 
 	log.Printf("[%s] 账户处理完成", traceId)
@@ -1,7 +1,7 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
 	"time"
 	"wm-func/common/state"
 )
@@ -14,10 +14,25 @@ type State struct {
 	LastRunningTime time.Time `json:"last_running_time"`
 }
 
-func GetState(account KAccount, subType string) (*State, error) {
-	syncInfo := state.GetSyncInfo(account.TenantId, account.AccountId, Platform, subType)
+// stateStore knocommerce 各子类型的同步状态
+var stateStore = state.NewStore[State]()
 
-	if syncInfo == nil {
+func stateKey(account KAccount, subType string) state.StateKey {
+	return state.StateKey{
+		Tenant:   account.TenantId,
+		Account:  account.AccountId,
+		Platform: Platform,
+		SubType:  subType,
+	}
+}
+
+func GetState(ctx context.Context, account KAccount, subType string) (*State, error) {
+	res, found, err := stateStore.Load(ctx, stateKey(account, subType))
+	if err != nil {
+		return nil, err
+	}
+
+	if !found {
 		lastYear := time.Now().Add(Day * -1 * time.Duration(preDays))
 		return &State{
 			Name:            subType,
@@ -28,15 +43,10 @@ func GetState(account KAccount, subType string) (*State, error) {
 		}, nil
 	}
 
-	res := State{}
-	if err := json.Unmarshal(syncInfo, &res); err != nil {
-		return nil, err
-	}
-
 	return &res, nil
 }
 
-func SaveState(account KAccount, s *State) error {
+func SaveState(ctx context.Context, account KAccount, s *State) error {
 	if s == nil {
 		panic("nil state")
 	}
@@ -50,12 +60,9 @@ func SaveState(account KAccount, s *State) error {
 
 	s.NextRunningTime = time.Now().Add(time.Hour * time.Duration(s.TimeRange))
 
-	var b []byte
-	var err error
-	if b, err = json.Marshal(s); err != nil {
+	// 锁已被其他实例重新获取时拒绝写入，避免过期的持有者回退同步进度
+	if err := stateStore.SaveWithFence(ctx, stateKey(account, s.Name), *s, account.fence); err != nil {
 		panic(err)
 	}
-
-	state.SaveSyncInfo(account.TenantId, account.AccountId, Platform, s.Name, b)
 	return nil
 }
@@ -50,7 +50,7 @@ type BenchmarkQuestion struct {
 }
 
 func (b BenchmarkQuestion) GetKey(account KAccount) string {
-	return fmt.Sprintf("%d|%s", account.TenantId, b.ID)
+	return account.RawKeyPrefix() + b.ID
 }
 
 func TransToAirbyte(account KAccount, data Key) *AirbyteData {
@@ -62,14 +62,13 @@ func TransToAirbyte(account KAccount, data Key) *AirbyteData {
 	now := time.Now().UTC().Format("2006-01-02 15:04:05")
 
 	return &AirbyteData{
-		TenantId:            account.TenantId,
-		AirbyteRawId:        data.GetKey(account),
-		AirbyteData:         byteData,
-		AirbyteExtractedAt:  now,
-		AirbyteLoadedAt:     now,
-		AirbyteMeta:         `{}`,
-		AirbyteGenerationId: 0,
-		ItemType:            "-",
+		TenantId:           account.TenantId,
+		AirbyteRawId:       data.GetKey(account),
+		AirbyteData:        byteData,
+		AirbyteExtractedAt: now,
+		AirbyteLoadedAt:    now,
+		AirbyteMeta:        `{}`,
+		ItemType:           "-",
 	}
 }
 
@@ -151,7 +150,7 @@ type Survey struct {
 }
 
 func (s Survey) GetKey(account KAccount) string {
-	return fmt.Sprintf("%d|%s|%s", account.TenantId, s.AccountID, s.ID)
+	return fmt.Sprintf("%s%s|%s", account.RawKeyPrefix(), s.AccountID, s.ID)
 }
 
 // SurveyQuestion 代表调查问卷中的一个问题
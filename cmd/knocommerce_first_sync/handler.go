@@ -1,20 +1,22 @@
 package main
 
 import (
+	"context"
 	"log"
 	"time"
-	"wm-func/common/db/airbyte_db"
-
-	"gorm.io/gorm/clause"
+	"wm-func/common/model"
 )
 
 var dateFormatDate = "2006-01-02"
 
-func RequestResponseCount(account KAccount, token *TokenManager) {
+// generation 本次运行的 generation id，各账户的写入器共用
+var generation = time.Now().Unix()
+
+func RequestResponseCount(ctx context.Context, account KAccount, token *TokenManager) {
 	traceId := account.GetTraceIdWithSubType(SUBTYPE_RESPONSE_COUNT)
 	log.Printf("[%s] 开始RequestResponseCount，获取回复统计数据", traceId)
 
-	st, err := GetState(account, SUBTYPE_RESPONSE_COUNT)
+	st, err := GetState(ctx, account, SUBTYPE_RESPONSE_COUNT)
 	if err != nil {
 		log.Printf("[%s] GetState失败: %v", traceId, err)
 		panic(err)
@@ -36,7 +38,7 @@ func RequestResponseCount(account KAccount, token *TokenManager) {
 
 		// 获取开始日期的count
 		var count int64
-		count, err = GetKnoCommerceResponsesCount(token, v.Start, v.Start)
+		count, err = GetKnoCommerceResponsesCount(ctx, token, v.Start, v.Start)
 		if err != nil {
 			log.Printf("[%s] GetKnoCommerceResponsesCount失败(开始日期%s): %v", traceId, v.Start, err)
 			panic(err)
@@ -47,10 +49,12 @@ func RequestResponseCount(account KAccount, token *TokenManager) {
 			Count:    count,
 			StatDate: v.Start,
 		})
-		time.Sleep(time.Second * 2)
+		if err := sleepContext(ctx, time.Second*2); err != nil {
+			panic(err)
+		}
 
 		// 获取结束日期的count
-		count, err = GetKnoCommerceResponsesCount(token, v.End, v.End)
+		count, err = GetKnoCommerceResponsesCount(ctx, token, v.End, v.End)
 		if err != nil {
 			log.Printf("[%s] GetKnoCommerceResponsesCount失败(结束日期%s): %v", traceId, v.End, err)
 			panic(err)
@@ -61,7 +65,9 @@ func RequestResponseCount(account KAccount, token *TokenManager) {
 			Count:    count,
 			StatDate: v.End,
 		})
-		time.Sleep(time.Second * 2)
+		if err := sleepContext(ctx, time.Second*2); err != nil {
+			panic(err)
+		}
 	}
 
 	log.Printf("[%s] 总共收集到统计数据条数: %d", traceId, len(insertData))
@@ -71,7 +77,7 @@ func RequestResponseCount(account KAccount, token *TokenManager) {
 	for _, v := range insertData {
 		airbyteData = append(airbyteData, *TransToAirbyte(account, v))
 	}
-	SaveAirbyteData(account, airbyteData, SUBTYPE_RESPONSE_COUNT)
+	SaveAirbyteData(ctx, account, airbyteData, SUBTYPE_RESPONSE_COUNT)
 
 	log.Printf("[%s] 更新同步状态", traceId)
 
@@ -85,15 +91,15 @@ func RequestResponseCount(account KAccount, token *TokenManager) {
 		log.Printf("[%s] lastSyncTime不在最近7天内，直接设置为: %v", traceId, st.LastSync)
 	}
 
-	SaveState(account, st)
+	SaveState(ctx, account, st)
 	log.Printf("[%s] RequestResponseCount完成", traceId)
 }
 
-func RequestResponse(account KAccount, token *TokenManager) {
+func RequestResponse(ctx context.Context, account KAccount, token *TokenManager) {
 	traceId := account.GetTraceIdWithSubType(SUBTYPE_RESPONSE)
 	log.Printf("[%s] 开始RequestResponse，获取回复数据", traceId)
 
-	st, err := GetState(account, SUBTYPE_RESPONSE)
+	st, err := GetState(ctx, account, SUBTYPE_RESPONSE)
 	if err != nil {
 		log.Printf("[%s] GetState失败: %v", traceId, err)
 		panic(err)
@@ -114,7 +120,7 @@ func RequestResponse(account KAccount, token *TokenManager) {
 		log.Printf("[%s] 正在处理第%d个分片，时间范围: %s - %s", traceId, i+1, v.Start, v.End)
 
 		var tmp []Result
-		tmp, err = GetAllKnoCommerceResponses(account, token, v.Start, v.End)
+		tmp, err = GetAllKnoCommerceResponses(ctx, account, token, v.Start, v.End)
 		if err != nil {
 			log.Printf("[%s] GetAllKnoCommerceResponses失败: %v", traceId, err)
 			panic(err)
@@ -134,7 +140,7 @@ func RequestResponse(account KAccount, token *TokenManager) {
 				for _, d := range insertData {
 					airbyteData = append(airbyteData, *TransToAirbyte(account, d))
 				}
-				SaveAirbyteData(account, airbyteData, SUBTYPE_RESPONSE)
+				SaveAirbyteData(ctx, account, airbyteData, SUBTYPE_RESPONSE)
 				insertData = []Result{}
 
 				log.Printf("[%s] 更新同步状态", traceId)
@@ -149,7 +155,7 @@ func RequestResponse(account KAccount, token *TokenManager) {
 					log.Printf("[%s] lastSyncTime不在最近7天内，直接设置为: %v", traceId, st.LastSync)
 				}
 
-				SaveState(account, st)
+				SaveState(ctx, account, st)
 			}
 		}
 	}
@@ -157,11 +163,11 @@ func RequestResponse(account KAccount, token *TokenManager) {
 	log.Printf("[%s] RequestResponse完成", traceId)
 }
 
-func RequestQuestion(account KAccount, token *TokenManager) {
+func RequestQuestion(ctx context.Context, account KAccount, token *TokenManager) {
 	traceId := account.GetTraceIdWithSubType(SUBTYPE_QUESTION)
 	log.Printf("[%s] 开始RequestQuestion，获取问题基准数据", traceId)
 
-	res, err := GetKnoCommerceQuestion(token.GetAccessToken())
+	res, err := GetKnoCommerceQuestion(ctx, token.GetAccessToken())
 	if err != nil {
 		log.Printf("[%s] GetKnoCommerceQuestion失败: %v", traceId, err)
 		return
@@ -175,15 +181,15 @@ func RequestQuestion(account KAccount, token *TokenManager) {
 	}
 
 	log.Printf("[%s] 开始保存问题数据到Airbyte", traceId)
-	SaveAirbyteData(account, data, SUBTYPE_QUESTION)
+	SaveAirbyteData(ctx, account, data, SUBTYPE_QUESTION)
 	log.Printf("[%s] RequestQuestion完成", traceId)
 }
 
-func RequestSurvey(account KAccount, token *TokenManager) {
+func RequestSurvey(ctx context.Context, account KAccount, token *TokenManager) {
 	traceId := account.GetTraceIdWithSubType(SUBTYPE_SURVEY)
 	log.Printf("[%s] 开始RequestSurvey，获取调查问卷数据", traceId)
 
-	res, err := GetAllKnoCommerceSurveys(account, token)
+	res, err := GetAllKnoCommerceSurveys(ctx, account, token)
 	if err != nil {
 		log.Printf("[%s] GetAllKnoCommerceSurveys失败: %v", traceId, err)
 		return
@@ -197,26 +203,26 @@ func RequestSurvey(account KAccount, token *TokenManager) {
 	}
 
 	log.Printf("[%s] 开始保存调查问卷数据到Airbyte", traceId)
-	SaveAirbyteData(account, data, SUBTYPE_SURVEY)
+	SaveAirbyteData(ctx, account, data, SUBTYPE_SURVEY)
 	log.Printf("[%s] RequestSurvey完成", traceId)
 }
 
-func SaveAirbyteData(account KAccount, data []AirbyteData, subType string) error {
+func SaveAirbyteData(ctx context.Context, account KAccount, data []AirbyteData, subType string) error {
 	if len(data) == 0 {
 		return nil
 	}
 
 	traceId := account.GetTraceIdWithSubType(subType)
 
-	db := airbyte_db.GetDB()
 	table := GetAirbyteTableNameWithSubType(subType)
-	if err := db.Table(table).
-		Clauses(clause.OnConflict{
-			Columns:   []clause.Column{{Name: "wm_tenant_id"}, {Name: "_airbyte_raw_id"}},
-			UpdateAll: true,
-		}).CreateInBatches(data, 500).Error; err != nil {
+	raws := make([]model.AirbyteRawData, len(data))
+	for i, d := range data {
+		raws[i] = d.Raw()
+	}
+	counts, err := account.raw.WriteContext(ctx, table, raws)
+	if err != nil {
 		return err
 	}
-	log.Printf("[%s] successfully inserted %d knocommerce %s records", traceId, len(data), subType)
+	log.Printf("[%s] knocommerce %s: %s", traceId, subType, counts)
 	return nil
 }
@@ -1,16 +1,27 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"strconv"
+	"time"
 	lock2 "wm-func/common/lock"
+	"wm-func/common/model"
 	"wm-func/wm_account"
 )
 
 const Platform = "knocommerce"
 
+// lockWaitTimeout 请求等待账户锁的最长时间，超时后直接返回，避免挂住 Cloud Run 请求
+const lockWaitTimeout = 30 * time.Second
+
+// hostname 锁持有者 ID 的一部分，容器内 PID 往往都是 1，需要主机名区分实例
+var hostname, _ = os.Hostname()
+
 func main() {
 	// 健康检查端点
 	http.HandleFunc("/", func(writer http.ResponseWriter, r *http.Request) {
@@ -18,6 +29,9 @@ func main() {
 		writer.Write([]byte("knocommerce-first-sync service is running"))
 	})
 
+	// 锁管理端点：查看和强制释放遗留的账户锁
+	http.Handle("/admin/", http.StripPrefix("/admin", lock2.NewAdminHandler(lock2.NewMySQLLocker(), os.Getenv("LOCK_ADMIN_TOKEN"))))
+
 	// 主业务端点
 	http.HandleFunc("/run/", func(writer http.ResponseWriter, r *http.Request) {
 		tenantId := r.URL.Path[len("/run/"):]
@@ -26,7 +40,17 @@ func main() {
 			writer.WriteHeader(http.StatusBadRequest)
 			return
 		}
-		RunWithTenantId(tenantIdInt64)
+		if err := RunWithTenantId(r.Context(), tenantIdInt64); err != nil {
+			var timeoutErr *lock2.LockTimeoutError
+			if errors.As(err, &timeoutErr) {
+				writer.WriteHeader(http.StatusConflict)
+				writer.Write([]byte(err.Error()))
+				return
+			}
+			writer.WriteHeader(http.StatusInternalServerError)
+			writer.Write([]byte(err.Error()))
+			return
+		}
 		writer.WriteHeader(http.StatusOK)
 		writer.Write([]byte("success"))
 	})
@@ -41,7 +65,7 @@ func main() {
 	log.Fatal(http.ListenAndServe(":"+port, nil))
 }
 
-func RunWithTenantId(tenantId int64) {
+func RunWithTenantId(ctx context.Context, tenantId int64) error {
 	log.Printf("[%s] Knocommerce数据同步程序启动", Platform)
 
 	accounts := wm_account.GetAccountsWithPlatform(Platform)
@@ -54,24 +78,40 @@ func RunWithTenantId(tenantId int64) {
 			continue
 		}
 		kaccounts = append(kaccounts, KAccount{
-			account,
-			lock,
+			Account: account,
+			Locker:  lock,
 		})
 	}
 
 	for _, account := range kaccounts {
 		ac := account
-		run(ac)
+		// 与定时任务使用相同的锁键，避免首次同步和增量同步同时写入
+		lockKey := fmt.Sprintf("knocommerce:%d:%s", ac.TenantId, ac.AccountId)
+		ownerID := fmt.Sprintf("first-sync-%s-%d-%d", hostname, os.Getpid(), time.Now().UnixNano())
+
+		session, err := lock2.WaitLockWithHeartbeat(ctx, ac, lockKey, ownerID, lock2.DefaultLeaseTTL, lockWaitTimeout)
+		if err != nil {
+			log.Printf("[%s] 无法获取锁: %v", ac.GetSimpleTraceId(), err)
+			return err
+		}
+		func() {
+			// run 出错会 panic，确保心跳停止并释放锁
+			defer session.Close()
+			ac.fence = session.Fence()
+			ac.raw = model.NewRawWriter(model.RawWriterOptions{Generation: generation, Fence: ac.fence})
+			run(session.Context(), ac)
+		}()
 	}
 
 	log.Printf("[%s] Knocommerce数据同步程序结束", Platform)
+	return nil
 }
 
-func run(account KAccount) {
+func run(ctx context.Context, account KAccount) {
 	traceId := account.GetSimpleTraceId()
 	log.Printf("[%s] 开始处理账户", traceId)
 
-	token := NewTokenManager(account)
+	token := NewTokenManager(ctx, account)
 	//token, err := RefreshToken(account)
 	//if err != nil {
 	//	log.Printf("[%s] RefreshToken失败: %v", traceId, err)
@@ -81,22 +121,22 @@ func run(account KAccount) {
 
 	questionTraceId := account.GetTraceIdWithSubType(SUBTYPE_QUESTION)
 	log.Printf("[%s] 开始RequestQuestion", questionTraceId)
-	RequestQuestion(account, token)
+	RequestQuestion(ctx, account, token)
 	log.Printf("[%s] RequestQuestion完成", questionTraceId)
 
 	surveyTraceId := account.GetTraceIdWithSubType(SUBTYPE_SURVEY)
 	log.Printf("[%s] 开始RequestSurvey", surveyTraceId)
-	RequestSurvey(account, token)
+	RequestSurvey(ctx, account, token)
 	log.Printf("[%s] RequestSurvey完成", surveyTraceId)
 
 	//responseCountTraceId := account.GetTraceIdWithSubType(SUBTYPE_RESPONSE_COUNT)
 	//log.Printf("[%s] 开始RequestResponseCount", responseCountTraceId)
-	//RequestResponseCount(account, token)
+	//RequestResponseCount(ctx, account, token)
 	//log.Printf("[%s] RequestResponseCount完成", responseCountTraceId)
 	//
 	//responseTraceId := account.GetTraceIdWithSubType(SUBTYPE_RESPONSE)
 	//log.Printf("[%s] 开始RequestResponse", responseTraceId)
-	//RequestResponse(account, token)
+	//RequestResponse(ctx, account, token)
 	//log.Printf("[%s] RequestResponse完成", responseTraceId)
 
 	log.Printf("[%s] 账户处理完成", traceId)
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -11,7 +12,7 @@ import (
 )
 
 // GetAllKnoCommerceResponses 函数会自动处理分页，获取指定日期范围内的所有回复
-func GetAllKnoCommerceResponses(account KAccount, token *TokenManager, startDate, endDate string) ([]Result, error) {
+func GetAllKnoCommerceResponses(ctx context.Context, account KAccount, token *TokenManager, startDate, endDate string) ([]Result, error) {
 	traceId := account.GetTraceIdWithSubType(SUBTYPE_RESPONSE)
 	var allResults []Result
 	page := 1
@@ -22,7 +23,7 @@ func GetAllKnoCommerceResponses(account KAccount, token *TokenManager, startDate
 	log.Printf("[%s] 开始分页获取回复数据，日期范围: %s 至 %s", traceId, startDate, endDate)
 
 	for {
-		response, err := GetKnoCommerceResponses(token, startDate, endDate, page, pageSize)
+		response, err := GetKnoCommerceResponses(ctx, token, startDate, endDate, page, pageSize)
 		if err != nil {
 			// 如果在获取某一页时出错，返回已获取的数据和错误
 			return allResults, fmt.Errorf("获取第 %d 页数据时出错: %w", page, err)
@@ -68,7 +69,9 @@ func GetAllKnoCommerceResponses(account KAccount, token *TokenManager, startDate
 
 		// 准备获取下一页
 		page++
-		time.Sleep(time.Second * 2)
+		if err := sleepContext(ctx, time.Second*2); err != nil {
+			return allResults, err
+		}
 	}
 
 	log.Printf("[%s] 分页获取完成，最终获得 %d 条回复数据", traceId, len(allResults))
@@ -77,7 +80,7 @@ func GetAllKnoCommerceResponses(account KAccount, token *TokenManager, startDate
 
 // GetKnoCommerceResponses 函数用于获取调查问卷的回复列表
 // 它接收分页参数、日期范围和访问令牌
-func GetKnoCommerceResponses(token *TokenManager, startDate, endDate string, page, pageSize int) (*APIResponse, error) {
+func GetKnoCommerceResponses(ctx context.Context, token *TokenManager, startDate, endDate string, page, pageSize int) (*APIResponse, error) {
 	// 1. 定义 API 基础 URL
 	baseURL := "https://app-api.knocommerce.com/api/rest/responses"
 
@@ -93,7 +96,7 @@ func GetKnoCommerceResponses(token *TokenManager, startDate, endDate string, pag
 	fullURL := baseURL + "?" + params.Encode()
 
 	// 4. 创建一个新的 GET 请求
-	req, err := http.NewRequest("GET", fullURL, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", fullURL, nil)
 	if err != nil {
 		return nil, fmt.Errorf("创建 responses 请求失败: %w", err)
 	}
@@ -132,7 +135,7 @@ func GetKnoCommerceResponses(token *TokenManager, startDate, endDate string, pag
 }
 
 // GetKnoCommerceResponsesCount 函数用于获取指定日期范围内的回复总数
-func GetKnoCommerceResponsesCount(token *TokenManager, startDate, endDate string) (int64, error) {
+func GetKnoCommerceResponsesCount(ctx context.Context, token *TokenManager, startDate, endDate string) (int64, error) {
 	startDate = fmt.Sprintf("%sT00:00:00.000Z", startDate)
 	endDate = fmt.Sprintf("%sT23:59:59.999Z", endDate)
 	// 1. 定义 API 基础 URL
@@ -148,7 +151,7 @@ func GetKnoCommerceResponsesCount(token *TokenManager, startDate, endDate string
 	fullURL := baseURL + "?" + params.Encode()
 
 	// 4. 创建一个新的 GET 请求
-	req, err := http.NewRequest("GET", fullURL, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", fullURL, nil)
 	if err != nil {
 		return 0, fmt.Errorf("创建 count 请求失败: %w", err)
 	}
@@ -186,12 +189,12 @@ func GetKnoCommerceResponsesCount(token *TokenManager, startDate, endDate string
 	return responseCount.Count, nil
 }
 
-func GetKnoCommerceQuestion(accessToken string) (*BenchmarkResponse, error) {
+func GetKnoCommerceQuestion(ctx context.Context, accessToken string) (*BenchmarkResponse, error) {
 	// 1. 定义 API URL
 	apiURL := "https://app-api.knocommerce.com/api/rest/questions/benchmarks"
 
 	// 2. 创建一个新的 GET 请求
-	req, err := http.NewRequest("GET", apiURL, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
 	if err != nil {
 		return nil, fmt.Errorf("创建 benchmarks 请求失败: %w", err)
 	}
@@ -228,7 +231,7 @@ func GetKnoCommerceQuestion(accessToken string) (*BenchmarkResponse, error) {
 	// 8. 返回解析后的数据
 	return &benchmarkResponse, nil
 }
-func GetAllKnoCommerceSurveys(account KAccount, token *TokenManager) ([]Survey, error) {
+func GetAllKnoCommerceSurveys(ctx context.Context, account KAccount, token *TokenManager) ([]Survey, error) {
 	traceId := account.GetTraceIdWithSubType(SUBTYPE_SURVEY)
 	var allSurveys []Survey
 	page := 1
@@ -238,7 +241,7 @@ func GetAllKnoCommerceSurveys(account KAccount, token *TokenManager) ([]Survey,
 	log.Printf("[%s] 开始分页获取调查问卷数据", traceId)
 
 	for {
-		response, err := GetKnoCommerceSurveys(token.GetAccessToken(), page, pageSize)
+		response, err := GetKnoCommerceSurveys(ctx, token.GetAccessToken(), page, pageSize)
 		if err != nil {
 			return allSurveys, fmt.Errorf("获取第 %d 页调查问卷时出错: %w", page, err)
 		}
@@ -283,7 +286,9 @@ func GetAllKnoCommerceSurveys(account KAccount, token *TokenManager) ([]Survey,
 
 		// 准备获取下一页
 		page++
-		time.Sleep(time.Second * 2)
+		if err := sleepContext(ctx, time.Second*2); err != nil {
+			return allSurveys, err
+		}
 	}
 
 	log.Printf("[%s] 分页获取完成，最终获得 %d 条调查问卷数据", traceId, len(allSurveys))
@@ -291,7 +296,7 @@ func GetAllKnoCommerceSurveys(account KAccount, token *TokenManager) ([]Survey,
 }
 
 // GetKnoCommerceSurveys 函数用于获取调查问卷列表
-func GetKnoCommerceSurveys(accessToken string, page, pageSize int) (*SurveysResponse, error) {
+func GetKnoCommerceSurveys(ctx context.Context, accessToken string, page, pageSize int) (*SurveysResponse, error) {
 	// 1. 定义 API 基础 URL
 	baseURL := "https://app-api.knocommerce.com/api/rest/surveys"
 
@@ -304,7 +309,7 @@ func GetKnoCommerceSurveys(accessToken string, page, pageSize int) (*SurveysResp
 	fullURL := baseURL + "?" + params.Encode()
 
 	// 4. 创建一个新的 GET 请求
-	req, err := http.NewRequest("GET", fullURL, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", fullURL, nil)
 	if err != nil {
 		return nil, fmt.Errorf("创建 surveys 请求失败: %w", err)
 	}
@@ -341,3 +346,15 @@ func GetKnoCommerceSurveys(accessToken string, page, pageSize int) (*SurveysResp
 	// 10. 返回解析后的数据
 	return &surveysResponse, nil
 }
+
+// sleepContext 等待 d，ctx 结束时提前返回 ctx.Err()
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -1,16 +1,15 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
 	"time"
-	"wm-func/common/db/airbyte_db"
 	"wm-func/common/http_request"
+	"wm-func/common/model"
 	"wm-func/common/state"
 	"wm-func/wm_account"
-
-	"gorm.io/gorm/clause"
 )
 
 const (
@@ -97,6 +96,19 @@ type MetaAirbyteData struct {
 	AirbyteGenerationId int64  `gorm:"column:_airbyte_generation_id"`
 }
 
+// Raw 转换为通用的原始数据结构
+func (d MetaAirbyteData) Raw() model.AirbyteRawData {
+	return model.AirbyteRawData{
+		TenantId:            d.TenantId,
+		AirbyteRawId:        d.AirbyteRawId,
+		AirbyteData:         d.AirbyteData,
+		AirbyteExtractedAt:  d.AirbyteExtractedAt,
+		AirbyteLoadedAt:     d.AirbyteLoadedAt,
+		AirbyteMeta:         d.AirbyteMeta,
+		AirbyteGenerationId: d.AirbyteGenerationId,
+	}
+}
+
 // 生成 Raw ID
 func generateRawId(data MetaData) string {
 	// 使用账户ID、日期和创建时间生成唯一标识
@@ -116,22 +128,35 @@ func (m MetaData) TransformToAirbyteData(tenantId int64) MetaAirbyteData {
 	now := time.Now().Format("2006-01-02 15:04:05")
 
 	return MetaAirbyteData{
-		TenantId:            tenantId,
-		AirbyteRawId:        rawId,
-		AirbyteData:         jsonData,
-		AirbyteExtractedAt:  now,
-		AirbyteLoadedAt:     now,
-		AirbyteMeta:         `{}`,
-		AirbyteGenerationId: 0,
+		TenantId:           tenantId,
+		AirbyteRawId:       rawId,
+		AirbyteData:        jsonData,
+		AirbyteExtractedAt: now,
+		AirbyteLoadedAt:    now,
+		AirbyteMeta:        `{}`,
+	}
+}
+
+// stateStore 广告数据的同步状态
+var stateStore = state.NewStore[SyncState]()
+
+func stateKey(account wm_account.Account) state.StateKey {
+	return state.StateKey{
+		Tenant:   account.TenantId,
+		Account:  account.AccountId,
+		Platform: Platform,
+		SubType:  SubType,
 	}
 }
 
 // getState 获取账户的同步状态
 func getState(account wm_account.Account) (SyncState, error) {
-	syncInfo := state.GetSyncInfo(account.TenantId, account.AccountId, Platform, SubType)
-
-	var syncState SyncState
-	if syncInfo == nil {
+	syncState, found, err := stateStore.Load(context.Background(), stateKey(account))
+	if err != nil {
+		log.Printf("[%s] 读取同步状态失败: %v", account.GetTraceId(), err)
+		return syncState, fmt.Errorf("读取同步状态失败: %w", err)
+	}
+	if !found {
 
 		syncState.UpdatedAt = time.Now().Add(time.Hour * 24 * -1)
 
@@ -139,11 +164,6 @@ func getState(account wm_account.Account) (SyncState, error) {
 		return syncState, nil
 	}
 
-	if err := json.Unmarshal(syncInfo, &syncState); err != nil {
-		log.Printf("[%s] 解析同步状态失败: %v", account.GetTraceId(), err)
-		return syncState, fmt.Errorf("解析同步状态失败: %w", err)
-	}
-
 	log.Printf("[%s] 获取同步状态成功", account.GetTraceId())
 	return syncState, nil
 }
@@ -152,12 +172,9 @@ func getState(account wm_account.Account) (SyncState, error) {
 func updateSyncState(account wm_account.Account, syncState SyncState) error {
 	syncState.UpdatedAt = time.Now().UTC()
 
-	data, err := json.Marshal(syncState)
-	if err != nil {
-		return fmt.Errorf("序列化同步状态失败: %w", err)
+	if err := stateStore.Save(context.Background(), stateKey(account), syncState); err != nil {
+		return fmt.Errorf("保存同步状态失败: %w", err)
 	}
-
-	state.SaveSyncInfo(account.TenantId, account.AccountId, Platform, SubType, data)
 	log.Printf("[%s] 更新同步状态成功", account.GetTraceId())
 	return nil
 }
@@ -383,33 +400,31 @@ func fetchAndStoreInsights(account wm_account.Account, reportRunId string) error
 	return nil
 }
 
+// rawWriter 本次运行共用的写入器
+var rawWriter = model.NewRawWriter(model.RawWriterOptions{})
+
 // storeInsightsData 存储洞察数据到数据库 (airbyte 格式)
 func storeInsightsData(account wm_account.Account, data []MetaData) error {
 	if len(data) == 0 {
 		return nil
 	}
 
-	db := airbyte_db.GetDB()
-
 	// 转换为 airbyte 格式
-	airbyteRecords := make([]MetaAirbyteData, len(data))
+	airbyteRecords := make([]model.AirbyteRawData, len(data))
 	for i, item := range data {
-		airbyteRecords[i] = item.TransformToAirbyteData(account.TenantId)
+		airbyteRecords[i] = item.TransformToAirbyteData(account.TenantId).Raw()
 	}
 
 	// 生成动态表名 (根据租户ID)
 	tableName := "airbyte_destination_v2.raw_facebook_marketing_ads_insights"
 
-	// 批量插入数据，使用 OnConflict 处理重复数据
-	if err := db.Table(tableName).
-		Clauses(clause.OnConflict{
-			Columns:   []clause.Column{{Name: "wm_tenant_id"}, {Name: "_airbyte_raw_id"}},
-			UpdateAll: true,
-		}).CreateInBatches(airbyteRecords, 500).Error; err != nil {
+	// 批量写入，内容没有变化的行不会重复写入
+	counts, err := rawWriter.Write(tableName, airbyteRecords)
+	if err != nil {
 		return fmt.Errorf("批量插入洞察数据失败: %w", err)
 	}
 
-	log.Printf("[%s] 成功存储 %d 条洞察数据到表 %s", account.GetTraceId(), len(airbyteRecords), tableName)
+	log.Printf("[%s] 存储洞察数据到表 %s: %s", account.GetTraceId(), tableName, counts)
 	return nil
 }
 
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"log"
 	"time"
 	"wm-func/common/model"
 )
@@ -49,23 +50,44 @@ func (f RawPinterestAdGroupAnalytics) TableName() string {
 }
 
 func Save(data []OdsCampaignCache) error {
-	return model.SaveAirbyteData(data)
+	return saveRaw(data)
 }
 
 func SaveAdGroups(data []RawPinterestAdGroups) error {
-	return model.SaveAirbyteData(data)
+	return saveRaw(data)
 }
 
 func SaveAds(data []RawPinterestAds) error {
-	return model.SaveAirbyteData(data)
+	return saveRaw(data)
 }
 
 func SaveAdAnalytics(data []RawPinterestAdAnalytics) error {
-	return model.SaveAirbyteData(data)
+	return saveRaw(data)
 }
 
 func SaveAdGroupAnalytics(data []RawPinterestAdGroupAnalytics) error {
-	return model.SaveAirbyteData(data)
+	return saveRaw(data)
+}
+
+// rawWriter 本次运行共用的写入器，内容没有变化的行不会重复写入
+var rawWriter = model.NewRawWriter(model.RawWriterOptions{})
+
+// 报表数据写入前按结构体校验，字段漂移记录在 _airbyte_meta.changes 中
+var (
+	_ = model.RegisterSchema[AdMetrics]("raw_pinterest_ad_analytics")
+	_ = model.RegisterSchema[AdGroupMetrics]("raw_pinterest_ad_group_analytics")
+)
+
+// saveRaw 写入原始数据表并记录新增、更新和未变化的行数
+func saveRaw[T model.RawRecord](data []T) error {
+	counts, err := model.WriteRaw(rawWriter, data)
+	if err != nil {
+		return err
+	}
+	if len(data) > 0 {
+		log.Printf("写入 %s: %s", data[0].TableName(), counts)
+	}
+	return nil
 }
 
 // 生成唯一的 Raw ID
@@ -92,13 +114,12 @@ func TransformCampaignToAirbyte(campaign Campaign, tenantId int64) OdsCampaignCa
 
 	return OdsCampaignCache{
 		AirbyteRawData: model.AirbyteRawData{
-			TenantId:            tenantId,
-			AirbyteRawId:        rawId,
-			AirbyteData:         jsonData,
-			AirbyteExtractedAt:  now,
-			AirbyteLoadedAt:     now,
-			AirbyteMeta:         `{"changes":[]}`,
-			AirbyteGenerationId: time.Now().Unix(),
+			TenantId:           tenantId,
+			AirbyteRawId:       rawId,
+			AirbyteData:        jsonData,
+			AirbyteExtractedAt: now,
+			AirbyteLoadedAt:    now,
+			AirbyteMeta:        `{"changes":[]}`,
 		},
 	}
 }
@@ -172,13 +193,12 @@ func TransformAdGroupToAirbyte(adGroup AdGroup, tenantId int64) RawPinterestAdGr
 
 	return RawPinterestAdGroups{
 		AirbyteRawData: model.AirbyteRawData{
-			TenantId:            tenantId,
-			AirbyteRawId:        rawId,
-			AirbyteData:         jsonData,
-			AirbyteExtractedAt:  now,
-			AirbyteLoadedAt:     now,
-			AirbyteMeta:         `{"changes":[]}`,
-			AirbyteGenerationId: time.Now().Unix(),
+			TenantId:           tenantId,
+			AirbyteRawId:       rawId,
+			AirbyteData:        jsonData,
+			AirbyteExtractedAt: now,
+			AirbyteLoadedAt:    now,
+			AirbyteMeta:        `{"changes":[]}`,
 		},
 	}
 }
@@ -199,10 +219,18 @@ func SaveAdGroupsToAirbyte(adGroups []AdGroup, tenantId int64) error {
 	return SaveAdGroups(airbyteData)
 }
 
+// adTombstoneRetention Ad 在上游删除后墓碑行保留的时长，超过后物理删除
+const adTombstoneRetention = 30 * 24 * time.Hour
+
+// adRawIdPrefix Campaign 下所有 Ad 的 Raw ID 前缀，也是 Ad 全量刷新的范围
+func adRawIdPrefix(campaignId string) string {
+	return campaignId + "|"
+}
+
 // 生成唯一的 Ad Raw ID
 func generateAdRawId(ad Ad) string {
-	// 使用 Ad ID 生成唯一标识
-	return fmt.Sprintf("%s", ad.Id)
+	// 使用 Campaign ID 和 Ad ID 生成唯一标识，按 Campaign 划定全量刷新的范围
+	return adRawIdPrefix(ad.CampaignId) + ad.Id
 }
 
 // 将 Ad 转换为 RawPinterestAds
@@ -218,13 +246,12 @@ func TransformAdToAirbyte(ad Ad, tenantId int64) RawPinterestAds {
 
 	return RawPinterestAds{
 		AirbyteRawData: model.AirbyteRawData{
-			TenantId:            tenantId,
-			AirbyteRawId:        rawId,
-			AirbyteData:         jsonData,
-			AirbyteExtractedAt:  now,
-			AirbyteLoadedAt:     now,
-			AirbyteMeta:         `{"changes":[]}`,
-			AirbyteGenerationId: time.Now().Unix(),
+			TenantId:           tenantId,
+			AirbyteRawId:       rawId,
+			AirbyteData:        jsonData,
+			AirbyteExtractedAt: now,
+			AirbyteLoadedAt:    now,
+			AirbyteMeta:        `{"changes":[]}`,
 		},
 	}
 }
@@ -245,6 +272,34 @@ func SaveAdsToAirbyte(ads []Ad, tenantId int64) error {
 	return SaveAds(airbyteData)
 }
 
+// SaveAdsFullRefresh 按 Campaign 全量刷新 Ad，ads 必须是所属 Campaign 下的完整列表
+// Campaign 下没有再出现的 Ad 标记为墓碑，没有任何 Ad 的 Campaign 不处理
+func SaveAdsFullRefresh(ads []Ad, tenantId int64) error {
+	byCampaign := make(map[string][]model.AirbyteRawData)
+	for _, ad := range ads {
+		byCampaign[ad.CampaignId] = append(byCampaign[ad.CampaignId], TransformAdToAirbyte(ad, tenantId).Raw())
+	}
+
+	table := RawPinterestAds{}.TableName()
+	for campaignId, rows := range byCampaign {
+		refresh := rawWriter.BeginFullRefresh(table, tenantId, model.FullRefreshOptions{
+			HardDeleteAfter: adTombstoneRetention,
+			KeyPrefix:       adRawIdPrefix(campaignId),
+		})
+		counts, err := refresh.Write(rows)
+		if err != nil {
+			refresh.Abort()
+			return fmt.Errorf("campaign %s: %w", campaignId, err)
+		}
+		res, err := refresh.Commit()
+		if err != nil {
+			return fmt.Errorf("campaign %s: %w", campaignId, err)
+		}
+		log.Printf("写入 %s campaign %s: %s, %s", table, campaignId, counts, res)
+	}
+	return nil
+}
+
 // 生成唯一的 Ad Analytics Raw ID
 func generateAdAnalyticsRawId(adMetrics AdMetrics) string {
 	// 使用 DATE|0|CAMPAIGN_ID|AD_GROUP_ID|AD_ID 格式生成唯一标识
@@ -268,13 +323,12 @@ func TransformAdAnalyticsToAirbyte(adMetrics AdMetrics, tenantId int64) RawPinte
 
 	return RawPinterestAdAnalytics{
 		AirbyteRawData: model.AirbyteRawData{
-			TenantId:            tenantId,
-			AirbyteRawId:        rawId,
-			AirbyteData:         jsonData,
-			AirbyteExtractedAt:  now,
-			AirbyteLoadedAt:     now,
-			AirbyteMeta:         `{"changes":[]}`,
-			AirbyteGenerationId: time.Now().Unix(),
+			TenantId:           tenantId,
+			AirbyteRawId:       rawId,
+			AirbyteData:        jsonData,
+			AirbyteExtractedAt: now,
+			AirbyteLoadedAt:    now,
+			AirbyteMeta:        `{"changes":[]}`,
 		},
 	}
 }
@@ -317,13 +371,12 @@ func TransformAdGroupAnalyticsToAirbyte(adGroupMetrics AdGroupMetrics, tenantId
 
 	return RawPinterestAdGroupAnalytics{
 		AirbyteRawData: model.AirbyteRawData{
-			TenantId:            tenantId,
-			AirbyteRawId:        rawId,
-			AirbyteData:         jsonData,
-			AirbyteExtractedAt:  now,
-			AirbyteLoadedAt:     now,
-			AirbyteMeta:         `{"changes":[]}`,
-			AirbyteGenerationId: time.Now().Unix(),
+			TenantId:           tenantId,
+			AirbyteRawId:       rawId,
+			AirbyteData:        jsonData,
+			AirbyteExtractedAt: now,
+			AirbyteLoadedAt:    now,
+			AirbyteMeta:        `{"changes":[]}`,
 		},
 	}
 }
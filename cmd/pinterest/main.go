@@ -1,8 +1,11 @@
 package main
 
 import (
+	"context"
+	"iter"
 	"log"
 	"time"
+	"wm-func/common/model"
 	t_pool "wm-func/common/pool"
 	"wm-func/wm_account"
 )
@@ -13,13 +16,18 @@ func main() {
 	pool := t_pool.NewWorkerPool(1)
 	pool.Run()
 
+	// 所有账户共用一个 Analytics 协程池，按广告账户限流
+	analytics := t_pool.NewKeyedPool(analyticsWorkers, analyticsPerAccount)
+	analytics.Run()
+	defer analytics.Close()
+
 	for _, account := range accounts {
 		if account.TenantId != 150091 {
 			continue
 		}
 		ac := account
 		pool.AddTask(func() {
-			pinterest := NewPinterest(ac)
+			pinterest := NewPinterest(ac, analytics)
 			traceId := pinterest.getTraceId()
 
 			log.Printf("[%s] 开始处理Pinterest账户", traceId)
@@ -87,82 +95,89 @@ func (p *Pinterest) PullOdsCampaignsAndSave() error {
 	traceId := p.getTraceId()
 	log.Printf("[%s] 开始处理Campaign数据", traceId)
 
-	// 拉取Campaign数据
-	data, err := p.PullOdsCampaigns(p.IdForCampaigns)
+	// 边拉取边保存，内存中最多保留一批 Campaign
+	loader := model.NewBulkLoader(model.RawFlush[OdsCampaignCache](rawWriter), model.BulkLoaderOptions[OdsCampaignCache]{
+		Size: model.RawSize[OdsCampaignCache],
+		OnFlush: func(r model.FlushResult) {
+			if r.Err != nil {
+				log.Printf("[%s] 保存Campaign数据到Airbyte失败: %v", traceId, r.Err)
+			}
+		},
+	})
+	ctx := context.Background()
+	for campaign := range p.PullOdsCampaigns(p.IdForCampaigns) {
+		if err := loader.Add(ctx, TransformCampaignToAirbyte(campaign, p.Account.TenantId)); err != nil {
+			break
+		}
+	}
+	stats, err := loader.Close()
 	if err != nil {
-		log.Printf("[%s] 拉取Campaign数据失败: %v", traceId, err)
 		return err
 	}
 
-	// 保存 Campaign 数据到 Airbyte
-	if len(data) > 0 {
-		if err := SaveCampaignsToAirbyte(data, p.Account.TenantId); err != nil {
-			log.Printf("[%s] 保存Campaign数据到Airbyte失败: %v", traceId, err)
-			return err
-		}
-		log.Printf("[%s] 成功保存%d条Campaign数据到Airbyte", traceId, len(data))
-	} else {
-		log.Printf("[%s] 没有Campaign数据需要保存", traceId)
-	}
-
-	log.Printf("[%s] Campaign数据处理完成", traceId)
+	log.Printf("[%s] Campaign数据处理完成: %s", traceId, stats)
 	return nil
 }
 
-func (p *Pinterest) PullOdsCampaigns(ids []string) ([]Campaign, error) {
-	traceId := p.getTraceId()
-	log.Printf("[%s] 开始拉取Campaign数据，共%d个IDs", traceId, len(ids))
-
-	if len(ids) == 0 {
-		log.Printf("[%s] 没有Campaign IDs需要处理", traceId)
-		return nil, nil
-	}
+// PullOdsCampaigns 按批拉取Campaign，每批获取后逐个交给调用方，调用方处理不过来时暂停拉取
+// 某一批获取失败时跳过该批继续处理
+func (p *Pinterest) PullOdsCampaigns(ids []string) iter.Seq[Campaign] {
+	return func(yield func(Campaign) bool) {
+		traceId := p.getTraceId()
+		log.Printf("[%s] 开始拉取Campaign数据，共%d个IDs", traceId, len(ids))
 
-	// 每批处理50个ID
-	batchSize := 50
-	totalBatches := (len(ids) + batchSize - 1) / batchSize
+		if len(ids) == 0 {
+			log.Printf("[%s] 没有Campaign IDs需要处理", traceId)
+			return
+		}
 
-	data := []Campaign{}
+		// 每批处理50个ID
+		batchSize := 50
+		totalBatches := (len(ids) + batchSize - 1) / batchSize
 
-	log.Printf("[%s] 将分%d批处理，每批最多%d个IDs", traceId, totalBatches, batchSize)
+		log.Printf("[%s] 将分%d批处理，每批最多%d个IDs", traceId, totalBatches, batchSize)
 
-	for i := 0; i < len(ids); i += batchSize {
-		end := i + batchSize
-		if end > len(ids) {
-			end = len(ids)
-		}
+		for i := 0; i < len(ids); i += batchSize {
+			end := i + batchSize
+			if end > len(ids) {
+				end = len(ids)
+			}
 
-		batch := ids[i:end]
-		batchNum := (i / batchSize) + 1
+			batch := ids[i:end]
+			batchNum := (i / batchSize) + 1
 
-		log.Printf("[%s] 处理第%d/%d批，包含%d个IDs", traceId, batchNum, totalBatches, len(batch))
+			log.Printf("[%s] 处理第%d/%d批，包含%d个IDs", traceId, batchNum, totalBatches, len(batch))
 
-		// 调用ListCampaigns获取Campaign详情
-		campaigns, err := p.ListCampaigns(batch)
-		if err != nil {
-			log.Printf("[%s] 第%d批Campaign数据获取失败: %v", traceId, batchNum, err)
-			// 继续处理下一批，不返回错误
-			continue
-		}
+			// 调用ListCampaigns获取Campaign详情
+			campaigns, err := p.ListCampaigns(batch)
+			if err != nil {
+				log.Printf("[%s] 第%d批Campaign数据获取失败: %v", traceId, batchNum, err)
+				// 继续处理下一批，不返回错误
+				continue
+			}
 
-		if campaigns == nil || len(campaigns.Items) == 0 {
-			log.Printf("[%s] 第%d批未获取到Campaign数据", traceId, batchNum)
-			continue
-		}
+			if campaigns == nil || len(campaigns.Items) == 0 {
+				log.Printf("[%s] 第%d批未获取到Campaign数据", traceId, batchNum)
+				continue
+			}
 
-		log.Printf("[%s] 第%d批成功获取%d个Campaign", traceId, batchNum, len(campaigns.Items))
+			log.Printf("[%s] 第%d批成功获取%d个Campaign", traceId, batchNum, len(campaigns.Items))
 
-		data = append(data, campaigns.Items...)
+			for _, campaign := range campaigns.Items {
+				if !yield(campaign) {
+					return
+				}
+			}
 
-		// 在批次之间添加短暂延迟，避免API限流
-		if batchNum < totalBatches {
-			log.Printf("[%s] 等待1秒后处理下一批", traceId)
-			time.Sleep(1 * time.Second)
+			// 在批次之间添加短暂延迟，避免API限流
+			if batchNum < totalBatches {
+				log.Printf("[%s] 等待1秒后处理下一批", traceId)
+				time.Sleep(1 * time.Second)
+			}
 		}
-	}
 
-	log.Printf("[%s] Campaign数据拉取完成", traceId)
-	return data, nil
+		log.Printf("[%s] Campaign数据拉取完成", traceId)
+	}
 }
 
 // PullOdsAdGroups 拉取并保存AdGroup数据
@@ -1,11 +1,11 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
 	"strings"
-	"sync"
 	"time"
 )
 
@@ -62,7 +62,6 @@ func (p *Pinterest) PullAdAnalytics() error {
 
 	// 2. 分组处理Ad IDs（每组2个）
 	const groupSize = 10
-	const maxWorkers = 8
 
 	groups := make([][]string, 0)
 	for i := 0; i < len(adIds); i += groupSize {
@@ -76,55 +75,32 @@ func (p *Pinterest) PullAdAnalytics() error {
 	log.Printf("[%s] 将%d个Ad IDs分成%d组，每组最多%d个", traceId, len(adIds), len(groups), groupSize)
 
 	// 3. 使用工作池并发处理
-	return p.processAdAnalyticsWithWorkerPool(groups, maxWorkers, traceId)
+	return p.processAdAnalyticsWithWorkerPool(groups, traceId)
 }
 
 // processAdAnalyticsWithWorkerPool 使用工作池并发处理Ad Analytics
-func (p *Pinterest) processAdAnalyticsWithWorkerPool(groups [][]string, maxWorkers int, traceId string) error {
-	// 创建工作通道和结果通道
-	jobChan := make(chan []string, len(groups))
-	resultChan := make(chan error, len(groups))
-
-	maxWorkers = 1
-	// 启动工作协程
-	var wg sync.WaitGroup
-	for i := 0; i < maxWorkers; i++ {
-		wg.Add(1)
-		go func(workerID int) {
-			defer wg.Done()
-			for adIds := range jobChan {
-				log.Printf("[%s] Worker %d 开始处理%d个Ad IDs: %v", traceId, workerID, len(adIds), adIds)
-				err := p.fetchAndSaveAdAnalytics(adIds, workerID, traceId)
-				resultChan <- err
-				if err != nil {
-					log.Printf("[%s] Worker %d 处理失败: %v", traceId, workerID, err)
-				} else {
-					log.Printf("[%s] Worker %d 处理完成", traceId, workerID)
-				}
+// 任务提交到整个运行共用的协程池，同一个广告账户共享 API 配额，按账户限制并发
+func (p *Pinterest) processAdAnalyticsWithWorkerPool(groups [][]string, traceId string) error {
+	tasks := p.analytics.Group()
+
+	for i, group := range groups {
+		groupNum := i + 1
+		adIds := group
+		label := fmt.Sprintf("%s-group-%d", traceId, groupNum)
+		tasks.AddTaskE(p.Account.AccountId, label, func(ctx context.Context) error {
+			log.Printf("[%s] 第%d组开始处理%d个Ad IDs: %v", traceId, groupNum, len(adIds), adIds)
+			err := p.fetchAndSaveAdAnalytics(adIds, groupNum, traceId)
+			if err != nil {
+				log.Printf("[%s] 第%d组处理失败: %v", traceId, groupNum, err)
+				return err
 			}
-		}(i)
+			log.Printf("[%s] 第%d组处理完成", traceId, groupNum)
+			return nil
+		})
 	}
 
-	// 发送任务到工作通道
-	for _, group := range groups {
-		jobChan <- group
-	}
-	close(jobChan)
-
-	// 等待所有工作协程完成
-	go func() {
-		wg.Wait()
-		close(resultChan)
-	}()
-
 	// 收集结果
-	var errors []error
-	for err := range resultChan {
-		if err != nil {
-			errors = append(errors, err)
-		}
-	}
-
+	errors := tasks.Wait()
 	if len(errors) > 0 {
 		log.Printf("[%s] Ad Analytics处理完成，但有%d个错误", traceId, len(errors))
 		return fmt.Errorf("部分Ad Analytics处理失败，%d个错误: %v", len(errors), errors)
@@ -135,8 +111,8 @@ func (p *Pinterest) processAdAnalyticsWithWorkerPool(groups [][]string, maxWorke
 }
 
 // fetchAndSaveAdAnalytics 获取并保存指定Ad IDs的Analytics数据
-func (p *Pinterest) fetchAndSaveAdAnalytics(adIds []string, workerID int, traceId string) error {
-	log.Printf("[%s] Worker %d 开始获取Ad Analytics数据，Ad IDs: %v", traceId, workerID, adIds)
+func (p *Pinterest) fetchAndSaveAdAnalytics(adIds []string, groupNum int, traceId string) error {
+	log.Printf("[%s] 第%d组开始获取Ad Analytics数据，Ad IDs: %v", traceId, groupNum, adIds)
 
 	// 获取最近30天的数据
 	now := time.Now().UTC()
@@ -209,7 +185,7 @@ func (p *Pinterest) fetchAndSaveAdAnalytics(adIds []string, workerID int, traceI
 	// 构建API URL
 	apiURL := fmt.Sprintf("%s/ad_accounts/%s/ads/analytics", PinterestAPIBase, p.Account.AccountId)
 
-	log.Printf("[%s] Worker %d 调用Ad Analytics API: %s", traceId, workerID, apiURL)
+	log.Printf("[%s] 第%d组调用Ad Analytics API: %s", traceId, groupNum, apiURL)
 
 	// 发送HTTP请求
 	respData, err := p.makeHTTPRequestWithRetry("GET", apiURL, headers, params, nil)
@@ -223,14 +199,14 @@ func (p *Pinterest) fetchAndSaveAdAnalytics(adIds []string, workerID int, traceI
 		return fmt.Errorf("解析Ad Analytics响应失败: %w", err)
 	}
 
-	log.Printf("[%s] Worker %d 获取到%d条Ad Analytics数据", traceId, workerID, len(analyticsResp))
+	log.Printf("[%s] 第%d组获取到%d条Ad Analytics数据", traceId, groupNum, len(analyticsResp))
 
 	// 保存到数据库
 	if len(analyticsResp) > 0 {
 		if err := SaveAdAnalyticsToAirbyte(analyticsResp, p.Account.TenantId); err != nil {
 			return fmt.Errorf("保存Ad Analytics数据失败: %w", err)
 		}
-		log.Printf("[%s] Worker %d 成功保存%d条Ad Analytics数据", traceId, workerID, len(analyticsResp))
+		log.Printf("[%s] 第%d组成功保存%d条Ad Analytics数据", traceId, groupNum, len(analyticsResp))
 	}
 
 	return nil
@@ -4,6 +4,9 @@ import (
 	"encoding/json"
 	"os"
 	"testing"
+	"time"
+	"wm-func/common/http_request"
+	"wm-func/wm_account"
 )
 
 func TestName(t *testing.T) {
@@ -173,9 +176,45 @@ func TestAdStructure(t *testing.T) {
 	airbyteData := TransformAdToAirbyte(ad, 150219)
 
 	// 验证转换结果
-	if airbyteData.AirbyteRawId != ad.Id {
-		t.Errorf("期望 AirbyteRawId 为 '%s'，但得到: %s", ad.Id, airbyteData.AirbyteRawId)
+	if want := ad.CampaignId + "|" + ad.Id; airbyteData.AirbyteRawId != want {
+		t.Errorf("期望 AirbyteRawId 为 '%s'，但得到: %s", want, airbyteData.AirbyteRawId)
 	}
 
 	t.Logf("✓ Ad转换为Airbyte格式成功")
 }
+
+// TestListCampaignsReplay 使用录制的接口响应离线测试，HTTP_CASSETTE=record 时访问真实接口重新录制
+func TestListCampaignsReplay(t *testing.T) {
+	cassette, err := http_request.NewCassette("testdata/cassettes/list_campaigns.json", http_request.CassetteModeFromEnv())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := cassette.Save(); err != nil {
+			t.Errorf("保存录制失败: %v", err)
+		}
+	}()
+
+	origin := pinterestClient
+	pinterestClient = http_request.NewClient(http_request.Config{Transport: cassette})
+	defer func() { pinterestClient = origin }()
+
+	p := NewPinterest(wm_account.Account{
+		TenantId:    150091,
+		AccountId:   "549756365874",
+		AccessToken: os.Getenv("PINTEREST_ACCESS_TOKEN"),
+	}, nil)
+	p.TokenExpiresAt = time.Now().Add(time.Hour)
+
+	// 录制中的第一次响应为 429，客户端应按 Retry-After 重试
+	resp, err := p.ListCampaigns([]string{"626754086067", "626754086068"})
+	if err != nil {
+		t.Fatalf("ListCampaigns 失败: %v", err)
+	}
+	if len(resp.Items) != 2 || resp.Items[0].Name != "Spring Sale" || resp.Items[1].Status != "PAUSED" {
+		t.Fatalf("Campaign 列表不符合预期: %+v", resp.Items)
+	}
+	if unused := cassette.Unused(); len(unused) != 0 {
+		t.Fatalf("有 %d 条录制没有被请求", len(unused))
+	}
+}
@@ -7,6 +7,8 @@ import (
 	"net/url"
 	"strings"
 	"time"
+	t_pool "wm-func/common/pool"
+	"wm-func/common/secrets"
 	"wm-func/wm_account"
 )
 
@@ -17,6 +19,8 @@ type Pinterest struct {
 	IdForAds         []string  // 存储已拉取的Ad IDs
 	TokenExpiresAt   time.Time // AccessToken 过期时间
 	RefreshExpiresAt time.Time // RefreshToken 过期时间
+
+	analytics *t_pool.KeyedPool // 整个运行共用的 Analytics 协程池，按广告账户限流
 }
 
 type IdForCampaign struct {
@@ -63,9 +67,11 @@ type ReportData struct {
 }
 
 // NewPinterest 创建新的 Pinterest 实例
-func NewPinterest(account wm_account.Account) *Pinterest {
+// analytics 为整个运行共用的协程池，按广告账户限制 Analytics 请求的并发
+func NewPinterest(account wm_account.Account, analytics *t_pool.KeyedPool) *Pinterest {
 	return &Pinterest{
 		Account:          account,
+		analytics:        analytics,
 		TokenExpiresAt:   time.Time{}, // 初始为零值，表示需要检查
 		RefreshExpiresAt: time.Time{}, // 初始为零值
 	}
@@ -107,7 +113,10 @@ func (p *Pinterest) GetAccessToken() error {
 // refreshAccessToken 刷新 AccessToken 的内部实现
 func (p *Pinterest) refreshAccessToken() error {
 	const apiURL = "https://api.pinterest.com/v5/oauth/token"
-	const clientCredentials = "MTQ5NDYwOTpiZjk2M2I1MGE3NjRlZjUwNzhiMDY4N2ZlOWQxNWU5MDFkNzc1OWNl"
+	clientCredentials, err := secrets.Get(secrets.PinterestClientCredentials)
+	if err != nil {
+		return fmt.Errorf("获取客户端凭证失败: %w", err)
+	}
 
 	// 准备表单数据
 	formData := url.Values{
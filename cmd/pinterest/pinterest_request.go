@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -16,14 +17,35 @@ const (
 	DefaultTimeout   = 30 * time.Second
 	MaxRetries       = 3
 	RetryDelay       = 5 * time.Second
+	RateLimit        = 5 // 每秒最多请求数
 
 	// 分页和批处理配置
 	DefaultPageSize      = 100 // API每页默认大小
 	CampaignBatchSize    = 50  // Campaign ID批处理大小
 	AdGroupSaveBatchSize = 500 // AdGroup保存批处理大小
 	MaxPagesLimit        = 100 // 最大页数限制，防止无限循环
+
+	// 同一广告账户同时进行的 Analytics 请求数，账户之间共享 API 配额
+	analyticsPerAccount = 1
+	// analyticsWorkers 整个运行的 Analytics 请求并发数
+	analyticsWorkers = 8
 )
 
+// pinterestClient Pinterest API 共用的客户端，所有账户共享按 host 的限流
+var pinterestClient = http_request.NewClient(http_request.Config{
+	Timeout: DefaultTimeout,
+	Retry: &http_request.RetryPolicy{
+		MaxRetries:    MaxRetries - 1, // MaxRetries 为包含首次请求在内的总次数
+		Initial:       RetryDelay,
+		Max:           time.Minute,
+		Multiplier:    2,
+		MaxRetryAfter: 2 * time.Minute,
+	},
+	Limiter: func(string) http_request.Limiter {
+		return http_request.NewTokenBucket(RateLimit, RateLimit)
+	},
+})
+
 // CreateReport 创建Pinterest广告报告
 // 返回报告token，用于后续查询报告状态
 func (p *Pinterest) CreateReport() (*ReportResponse, error) {
@@ -201,40 +223,19 @@ func (p *Pinterest) DownloadReport(reportURL string) (map[string][]ReportData, e
 }
 
 // makeHTTPRequestWithRetry 带重试机制的HTTP请求
+// 非 2xx 返回 *http_request.HTTPError；429 和 5xx 按退避重试，遵守 Retry-After
 func (p *Pinterest) makeHTTPRequestWithRetry(method, url string, headers, params map[string]string, body []byte) ([]byte, error) {
-	traceId := p.getTraceId()
-
-	var lastErr error
-	for attempt := 1; attempt <= MaxRetries; attempt++ {
-		log.Printf("[%s] 发送HTTP请求，第%d次尝试: %s %s", traceId, attempt, method, url)
-
-		var respData []byte
-		var err error
-
-		if method == "GET" {
-			respData, err = http_request.Get(url, headers, params)
-		} else if method == "POST" {
-			respData, err = http_request.Post(url, headers, params, body)
-		} else {
-			return nil, fmt.Errorf("不支持的HTTP方法: %s", method)
-		}
-
-		if err == nil {
-			log.Printf("[%s] HTTP请求成功", traceId)
-			return respData, nil
-		}
-
-		lastErr = err
-		log.Printf("[%s] HTTP请求失败，第%d次尝试: %v", traceId, attempt, err)
-
-		// 如果不是最后一次尝试，等待后重试
-		if attempt < MaxRetries {
-			log.Printf("[%s] 等待%v后重试", traceId, RetryDelay)
-			time.Sleep(RetryDelay)
-		}
+	// 请求和重试日志由 pinterestClient 的 slog 日志输出（HTTP_LOG 控制，已脱敏），这里不再单独打印
+	ctx := http_request.WithTraceID(context.Background(), p.getTraceId())
+	if method == "POST" {
+		// 只有创建异步报告使用 POST，重复创建只会多生成一个报告 token，可以安全重试
+		ctx = http_request.WithRetryUnsafe(ctx)
 	}
-
-	return nil, fmt.Errorf("HTTP请求重试%d次后仍然失败: %w", MaxRetries, lastErr)
+	respData, err := pinterestClient.Do(ctx, method, url, headers, params, body)
+	if err != nil {
+		return nil, fmt.Errorf("HTTP请求失败: %w", err)
+	}
+	return respData, nil
 }
 
 // getTraceId 获取跟踪ID
@@ -845,7 +846,8 @@ func (p *Pinterest) ListAllAds(campaignIds []string) error {
 	}
 
 	// 检查是否达到最大页数限制
-	if pageCount >= maxPages {
+	complete := pageCount < maxPages
+	if !complete {
 		log.Printf("[%s] 警告：达到最大页数限制(%d)，可能还有未获取的数据", traceId, maxPages)
 	}
 
@@ -853,6 +855,15 @@ func (p *Pinterest) ListAllAds(campaignIds []string) error {
 	p.IdForAds = adIds
 	log.Printf("[%s] 共获取到%d个Ad，%d个Ad IDs已存储", traceId, len(allAds), len(adIds))
 
+	// 拿到了这批 Campaign 的完整列表时全量刷新，上游已删除的 Ad 标记为墓碑
+	if complete && len(allAds) > 0 {
+		if err := SaveAdsFullRefresh(allAds, p.Account.TenantId); err != nil {
+			return fmt.Errorf("保存Ad数据到数据库失败: %w", err)
+		}
+		log.Printf("[%s] 成功全量刷新%d个Ad", traceId, len(allAds))
+		return nil
+	}
+
 	// 分批保存到数据库，避免单次插入数据过多
 	if len(allAds) > 0 {
 		if err := p.saveAdsInBatches(allAds, traceId); err != nil {
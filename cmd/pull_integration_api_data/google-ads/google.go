@@ -11,14 +11,12 @@ import (
 	"strings"
 	"time"
 	"wm-func/common/db/platform_db"
+	"wm-func/common/secrets"
 )
 
 const (
-	GoogleAds      = "googleAds"
-	DeveloperToken = "xhBTEWbeFONUH5kvHWcl2A"
-	ID             = "TODO"
-	KEY            = "TODO"
-	DateFormat     = "2006-01-02"
+	GoogleAds  = "googleAds"
+	DateFormat = "2006-01-02"
 )
 
 // Connection 连接配置
@@ -138,12 +136,17 @@ func getGoogleAccountInsightsRange(accountID, refreshToken, startDate, endDate s
 		return nil, err
 	}
 
+	developerToken, err := secrets.Get(secrets.GoogleAdsDeveloperToken)
+	if err != nil {
+		return nil, err
+	}
+
 	url := fmt.Sprintf("https://googleads.googleapis.com/v19/customers/%s/googleAds:search", accountID)
 
 	headers := map[string]string{
 		"Authorization":   "Bearer " + accessToken,
 		"Content-Type":    "application/json",
-		"developer-token": DeveloperToken,
+		"developer-token": developerToken,
 	}
 	if mccID != "" {
 		headers["login-customer-id"] = mccID
@@ -232,11 +235,19 @@ func makeGoogleAdsRequest(url string, headers map[string]string, query string) (
 // refreshTokenFunc 刷新访问令牌
 func refreshTokenFunc(refreshToken string) (string, error) {
 	tokenURL := "https://oauth2.googleapis.com/token"
+	clientID, err := secrets.Get(secrets.GoogleAdsClientID)
+	if err != nil {
+		return "", err
+	}
+	clientSecret, err := secrets.Get(secrets.GoogleAdsClientSecret)
+	if err != nil {
+		return "", err
+	}
 
 	data := url.Values{}
 	data.Set("grant_type", "refresh_token")
-	data.Set("client_id", ID)
-	data.Set("client_secret", KEY)
+	data.Set("client_id", clientID)
+	data.Set("client_secret", clientSecret)
 	data.Set("refresh_token", refreshToken)
 
 	req, err := http.NewRequest("POST", tokenURL, strings.NewReader(data.Encode()))
@@ -9,7 +9,7 @@ import (
 	"regexp"
 	"strings"
 	"time"
-	"wm-func/common/apollo"
+	"wm-func/common/config"
 )
 
 // Gemini API客户端
@@ -22,17 +22,9 @@ type GeminiClient struct {
 // 创建Gemini客户端
 func NewGeminiClient() *GeminiClient {
 	// 使用安全的配置读取方法
-	conf, err := apollo.GetInstance().GetLLMConfigSafe()
+	conf, err := config.GetLLMConfigSafe()
 	if err != nil {
-		// 降级处理：使用改进的Apollo客户端
-		fmt.Printf("警告: 使用原Apollo客户端读取配置失败: %v\n", err)
-		fmt.Println("尝试使用改进的Apollo客户端...")
-
-		improvedConf, improvedErr := apollo.GetImprovedInstance().GetLLMConfigSafe()
-		if improvedErr != nil {
-			panic(fmt.Sprintf("Apollo配置读取完全失败: %v", improvedErr))
-		}
-		conf = improvedConf
+		panic(fmt.Sprintf("Apollo配置读取失败: %v", err))
 	}
 
 	fmt.Printf("Apollo Init Success: BaseUrl=%s, Key=***...\n", conf.BaseUrl)
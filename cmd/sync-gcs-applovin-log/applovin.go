@@ -11,6 +11,8 @@ import (
 	"google.golang.org/api/option"
 	"log"
 	"wm-func/common/cache"
+	"wm-func/common/model"
+	"wm-func/common/secrets"
 )
 
 type Applovin struct {
@@ -23,7 +25,8 @@ type Applovin struct {
 
 func NewApplovin(tenantId int64) *Applovin {
 	ctx := context.Background()
-	client, err := storage.NewClient(ctx, option.WithCredentialsJSON(credentials))
+	credentials := secrets.MustGet(secrets.ApplovinGCSCredentials)
+	client, err := storage.NewClient(ctx, option.WithCredentialsJSON([]byte(credentials)))
 	if err != nil {
 		panic(err)
 	}
@@ -43,16 +46,26 @@ func (a *Applovin) Sync() {
 	it := a.GcsClient.Bucket(bucket).Objects(ctx, query)
 	log.Printf("获取文件夹 %s/%s 中的文件:", bucket, a.Prefix)
 
-	insert := []OrderJoinSource{}
-	insertKey := []cache.Cache{}
-	for {
+	// 文件逐行写入，写入成功后才记录到缓存，避免内存中保留整个文件
+	var loaded []cache.Cache
+	loader := model.NewBulkLoader(InsertOrderJoinSource, model.BulkLoaderOptions[OrderJoinSource]{
+		Size: func(o OrderJoinSource) int { return len(o.MetaData) },
+		OnFlush: func(r model.FlushResult) {
+			if r.Err == nil && len(loaded) > 0 {
+				a.SaveCache(loaded)
+				loaded = nil
+			}
+		},
+	})
+	var syncErr error
+	for syncErr == nil {
 		attrs, err := it.Next()
 		if errors.Is(err, iterator.Done) {
 			break
 		}
 
 		if err != nil {
-			fmt.Errorf("遍历文件失败: %w", err)
+			panic(fmt.Errorf("遍历文件失败: %w", err))
 		}
 		log.Printf("文件: %s, 大小: %d bytes", attrs.Name, attrs.Size)
 
@@ -60,34 +73,32 @@ func (a *Applovin) Sync() {
 
 		if cache.IsNeedUpdate(a.cache, a.TenantId, key, attrs.Updated) {
 			fmt.Println("need load")
-			insert = append(insert, a.Download(attrs.Name)...)
-			insertKey = append(insertKey, cache.Cache{
-				Key:          key,
-				LastModified: attrs.Updated,
-			})
+			if syncErr = a.Download(ctx, attrs.Name, loader); syncErr != nil {
+				break
+			}
+			c := cache.Cache{Key: key, LastModified: attrs.Updated}
+			syncErr = loader.Checkpoint(ctx, func() { loaded = append(loaded, c) })
 		} else {
 			log.Printf("跳过下载 %s (未修改)", key)
 		}
-
-		if len(insert) > 500 {
-			a.Save(insert, insertKey)
-			insert = []OrderJoinSource{}
-			insertKey = []cache.Cache{}
-		}
 	}
 
-	if len(insert) > 0 {
-		a.Save(insert, insertKey)
+	_, err := loader.Close()
+	if len(loaded) > 0 {
+		a.SaveCache(loaded)
+	}
+	if err = errors.Join(syncErr, err); err != nil {
+		panic(err)
 	}
-	return
 }
 
-func (a *Applovin) Save(data []OrderJoinSource, keys []cache.Cache) {
-	InsertOrderJoinSource(data)
+// SaveCache 记录已经写入的文件
+func (a *Applovin) SaveCache(keys []cache.Cache) {
 	cache.SaveS3CacheWithArr(a.cache, a.TenantId, keys)
 }
 
-func (a *Applovin) Download(prefix string) []OrderJoinSource {
+// Download 逐行读取文件并写入 loader
+func (a *Applovin) Download(ctx context.Context, prefix string, loader *model.BulkLoader[OrderJoinSource]) error {
 	if prefix[len(prefix)-5:] != ".json" {
 		log.Printf("skip %s", prefix)
 		return nil
@@ -96,7 +107,6 @@ func (a *Applovin) Download(prefix string) []OrderJoinSource {
 	//	log.Printf("skip %s", prefix)
 	//	return nil
 	//}
-	ctx := context.Background()
 
 	reader, err := a.GcsClient.Bucket(a.Bucket).Object(prefix).NewReader(ctx)
 	if err != nil {
@@ -106,7 +116,6 @@ func (a *Applovin) Download(prefix string) []OrderJoinSource {
 
 	// 一行一行读取
 	scanner := bufio.NewScanner(reader)
-	var insertData = []OrderJoinSource{}
 	for scanner.Scan() {
 		var applovinData ResponseData
 
@@ -119,7 +128,7 @@ func (a *Applovin) Download(prefix string) []OrderJoinSource {
 		}
 
 		tp := fmt.Sprintf("applovin_log_%s", applovinData.EventType)
-		insertData = append(insertData, OrderJoinSource{
+		err = loader.Add(ctx, OrderJoinSource{
 			TenantId:      a.TenantId,
 			ImportingType: tp,
 			OrderId:       applovinData.OrderId,
@@ -133,13 +142,11 @@ func (a *Applovin) Download(prefix string) []OrderJoinSource {
 			SrcCampaignId: applovinData.CampaignId,
 			MetaData:      scanner.Text(),
 		})
+		if err != nil {
+			return err
+		}
 	}
-
-	if len(insertData) == 0 {
-		return nil
-	}
-	return insertData
-	//InsertOrderJoinSource(insertData)
+	return scanner.Err()
 }
 
 type ResponseData struct {
@@ -1,11 +1,34 @@
 package main
 
+import (
+	"log"
+	"time"
+	"wm-func/common/config"
+)
+
+// connectorName 同步任务的连接器配置 application.connector.applovinLogSync
+// 与 alter-data-v2 的 applovinLog 分开：那里的 tenant_allow 表示需要监控的租户，这里表示需要同步的租户
+const connectorName = "applovinLogSync"
+
+// defaultInterval 默认每 10 分钟同步一次，配置的间隔不是正数时也使用该值
+const defaultInterval = 10 * time.Minute
+
+// defaultSettings Apollo 中没有配置时的默认值
+var defaultSettings = config.ConnectorSettings{
+	SyncWindow: config.SyncWindow{Interval: config.Duration{Duration: defaultInterval}},
+}
+
+// syncInterval 返回配置的同步间隔，不是正数时使用 defaultInterval
+func syncInterval(s config.ConnectorSettings) time.Duration {
+	if d := s.SyncWindow.Interval.Duration; d > 0 {
+		return d
+	}
+	log.Printf("同步间隔 %v 无效，使用默认值 %v", s.SyncWindow.Interval, defaultInterval)
+	return defaultInterval
+}
+
 var bucket string = "workmagic-partner-in-applovin"
 
 var tenantAccountMap = map[int64]string{
 	150090: "view-log/account-760952228",
 }
-
-var credentials = []byte(`{
-   
-}`)
@@ -3,19 +3,33 @@ package main
 import (
 	"log"
 	"time"
+	"wm-func/common/config"
 )
 
 func main() {
-	run()
-	ticker := time.NewTicker(10 * time.Minute)
+	settings := config.Connector(connectorName, defaultSettings)
+
+	run(settings.Get())
+	ticker := time.NewTicker(syncInterval(settings.Get()))
+	settings.OnChange(func(old, new config.ConnectorSettings) {
+		if new.SyncWindow.Interval != old.SyncWindow.Interval {
+			interval := syncInterval(new)
+			log.Printf("同步间隔从 %v 调整为 %v", old.SyncWindow.Interval, interval)
+			ticker.Reset(interval)
+		}
+	})
 	for range ticker.C {
-		run()
+		run(settings.Get())
 	}
 
 }
 
-func run() {
+func run(settings config.ConnectorSettings) {
 	for tenantId := range tenantAccountMap {
+		if !settings.TenantAllowed(tenantId) {
+			log.Printf("Skip Applovin log for tenant %d: not allowed by connector settings", tenantId)
+			continue
+		}
 		p := NewApplovin(tenantId)
 		p.Sync()
 		log.Printf("Sync Applovin log for tenant %d completed", tenantId)
@@ -25,7 +25,7 @@ func (o *OrderJoinSource) TableName() string {
 	return "platform_offline.dwd_attr_3p_ref_order_join_source_v20250721jz"
 }
 
-func InsertOrderJoinSource(reports []OrderJoinSource) {
+func InsertOrderJoinSource(reports []OrderJoinSource) error {
 	batchSize := 500
 	db := platform_db.GetDB()
 	log.Printf("Start inserting OrderJoinSource into DB, batch size: %d", batchSize)
@@ -37,9 +37,10 @@ func InsertOrderJoinSource(reports []OrderJoinSource) {
 		batch := reports[i:end]
 		if err := db.Clauses(clause.OnConflict{UpdateAll: true}).CreateInBatches(batch, len(batch)).Error; err != nil {
 			log.Printf("failed to insert batch [%d:%d]: %v", i, end, err)
-			panic(err)
+			return err
 		} else {
 			log.Printf("successfully inserted batch [%d:%d]", i, end)
 		}
 	}
+	return nil
 }
@@ -1,11 +1,12 @@
+// Package apollo 旧的 Apollo 配置入口，读取已统一到 config 包，按 WM_ENV 选择 Apollo、环境变量或本地文件
+//
+// Deprecated: 使用 config 包
 package apollo
 
 import (
-	"encoding/json"
-	"github.com/philchia/agollo/v4"
-	"log"
-	"os"
 	"sync"
+
+	"wm-func/common/config"
 )
 
 // ApolloClient 单例结构体
@@ -18,96 +19,42 @@ var (
 	once     sync.Once
 )
 
-// GetInstance 获取 Apollo 单例实例
+// GetInstance 获取单例实例，首次调用时按 WM_ENV 初始化配置来源
 func GetInstance() *ApolloClient {
 	once.Do(func() {
+		config.Default()
 		instance = &ApolloClient{}
-		instance.init()
 	})
 	return instance
 }
 
-// init 初始化 Apollo 连接
-func (c *ApolloClient) init() {
-	errr := agollo.Start(&agollo.Conf{
-		AppID:           "platform-api",
-		Cluster:         "UAT",
-		NameSpaceNames:  []string{"copilot", "application", "datasource"},
-		MetaAddr:        "http://internal-apollo-meta-server-preview.workmagic.io",
-		AccesskeySecret: "88b67fe6bcde46e59359f41ea9f3cd07",
-		//CacheDir:        "/tmp",
-	}, agollo.WithLogger(&logger{log: log.New(os.Stdout, "[agollo] ", log.LstdFlags)}))
-	if errr != nil {
-		panic(errr)
-	}
-}
-
 // GetS3Config 获取 S3 配置
 func (c *ApolloClient) GetS3Config() S3Config {
-	key := agollo.GetString("application.service.aws.workmagicTatariS3Key")
-	secret := agollo.GetString("application.service.aws.workmagicTatariS3Secret")
-
-	//val := agollo.GetString("application.service.aws.iam.develop")
-	res := S3Config{
-		AccessKeyID:     key,
-		SecretAccessKey: secret,
-		Region:          "us-east-1",
-	}
-
-	return res
+	return config.GetS3Config()
 }
 
 // GetDevelopS3Config 获取开发环境 S3 配置
 func (c *ApolloClient) GetDevelopS3Config() S3Config {
-	val := agollo.GetString("application.service.aws.iam.develop")
-	res := S3Config{}
-	if err := json.Unmarshal([]byte(val), &res); err != nil {
-		panic(err)
-	}
-	return res
+	return config.GetDevelopS3Config()
 }
 
 // GetAirbyteMysqlConfig 获取 Airbyte MySQL 配置
 func (c *ApolloClient) GetAirbyteMysqlConfig() MysqlConfig {
-	host := agollo.GetString("airbyte.datasource.api.url", withDataSource())
-	name := agollo.GetString("airbyte.datasource.api.name", withDataSource())
-	password := agollo.GetString("airbyte.datasource.api.password", withDataSource())
-	return MysqlConfig{
-		Host:     host,
-		Name:     name,
-		Password: password,
-	}
+	return config.GetAirbyteMysqlConfig()
 }
 
 // GetPinterestSourceSetting 获取 Pinterest 源设置
 func (c *ApolloClient) GetPinterestSourceSetting() string {
-	return agollo.GetString("application.service.integration.airbyte.source.pinterest")
+	return config.GetPinterestSourceSetting()
 }
 
 // GetXkMysqlConfig 获取 XK MySQL 配置
 func (c *ApolloClient) GetXkMysqlConfig() DBConfig {
-	res := agollo.GetString("application.service.integration.xk.mysql.conf")
-
-	cfg := DBConfig{}
-	err := json.Unmarshal([]byte(res), &cfg)
-	if err != nil {
-		panic(err)
-	}
-	return cfg
-}
-func withDataSource() agollo.OpOption {
-	return agollo.WithNamespace("datasource")
+	return config.GetXkMysqlConfig()
 }
 
 func (c *ApolloClient) GetMysqlConfig() MysqlConfig {
-	host := agollo.GetString("gcs_rw.datasource.api.url", withDataSource())
-	name := agollo.GetString("gcs_rw.datasource.api.name", withDataSource())
-	password := agollo.GetString("gcs_rw.datasource.api.password", withDataSource())
-	return MysqlConfig{
-		Host:     host,
-		Name:     name,
-		Password: password,
-	}
+	return config.GetMysqlConfig()
 }
 
 // 为了保持向后兼容，提供全局函数
@@ -136,17 +83,10 @@ func GetMysqlConfig() MysqlConfig {
 }
 
 func (c *ApolloClient) GetLLMConfig() LLMConfig {
-	key := agollo.GetString("copilot.llm.gemini.key", agollo.WithNamespace("copilot"))
-	baseUrl := agollo.GetString("copilot.llm.gemini.base.url", agollo.WithNamespace("copilot"))
-
-	if key == "" || baseUrl == "" {
-		panic("apollo: get config fail")
-	}
-	//val := agollo.GetString("application.service.aws.iam.develop")
-	res := LLMConfig{
-		Key:     key,
-		BaseUrl: baseUrl,
-	}
-
-	return res
+	return config.GetLLMConfig()
+}
+
+// GetLLMConfigSafe 获取 Gemini 配置，缺少配置时返回错误而不是 panic
+func (c *ApolloClient) GetLLMConfigSafe() (LLMConfig, error) {
+	return config.GetLLMConfigSafe()
 }
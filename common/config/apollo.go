@@ -0,0 +1,123 @@
+package config
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/philchia/agollo/v4"
+)
+
+// ApolloSettings Apollo 连接参数
+type ApolloSettings struct {
+	AppID           string
+	Cluster         string
+	MetaAddr        string
+	AccessKeySecret string
+	Namespaces      []string
+}
+
+// uatApollo UAT 环境的默认连接参数，其他环境需要通过环境变量提供
+// AccessKeySecret 没有默认值，所有环境都必须通过 APOLLO_ACCESS_KEY_SECRET 提供。
+// 之前写在代码中的 UAT 密钥仍留在 git 历史里，视为已泄露，必须在 Apollo 中轮换
+var uatApollo = ApolloSettings{
+	AppID:    "platform-api",
+	Cluster:  "UAT",
+	MetaAddr: "http://internal-apollo-meta-server-preview.workmagic.io",
+}
+
+// ApolloSettingsFor 返回指定环境的连接参数
+// APOLLO_APP_ID、APOLLO_CLUSTER、APOLLO_META_ADDR 可覆盖默认值，APOLLO_ACCESS_KEY_SECRET 必须设置
+func ApolloSettingsFor(env string) ApolloSettings {
+	s := ApolloSettings{AppID: uatApollo.AppID, Cluster: strings.ToUpper(env)}
+	if env == EnvUAT {
+		s = uatApollo
+	}
+	s.Namespaces = []string{NamespaceCopilot, NamespaceApplication, NamespaceDatasource}
+
+	override := func(dst *string, name string) {
+		if v := os.Getenv(name); v != "" {
+			*dst = v
+		}
+	}
+	override(&s.AppID, "APOLLO_APP_ID")
+	override(&s.Cluster, "APOLLO_CLUSTER")
+	override(&s.MetaAddr, "APOLLO_META_ADDR")
+	override(&s.AccessKeySecret, "APOLLO_ACCESS_KEY_SECRET")
+	return s
+}
+
+// ApolloProvider 从 Apollo 读取配置，agollo 是进程内单例，只能创建一次
+type ApolloProvider struct {
+	settings ApolloSettings
+
+	mu       sync.Mutex
+	watchers []func(namespace, key, old, new string)
+}
+
+// NewApolloProvider 连接 Apollo 并拉取配置
+func NewApolloProvider(s ApolloSettings) (*ApolloProvider, error) {
+	if s.MetaAddr == "" {
+		return nil, fmt.Errorf("apollo: meta addr for cluster %q is not set (APOLLO_META_ADDR)", s.Cluster)
+	}
+	if s.AccessKeySecret == "" {
+		return nil, fmt.Errorf("apollo: access key secret for cluster %q is not set (APOLLO_ACCESS_KEY_SECRET)", s.Cluster)
+	}
+	err := agollo.Start(&agollo.Conf{
+		AppID:           s.AppID,
+		Cluster:         s.Cluster,
+		NameSpaceNames:  s.Namespaces,
+		MetaAddr:        s.MetaAddr,
+		AccesskeySecret: s.AccessKeySecret,
+	}, agollo.WithLogger(&apolloLogger{log: log.New(os.Stdout, "[agollo] ", log.LstdFlags)}))
+	if err != nil {
+		return nil, fmt.Errorf("apollo: start %s/%s: %w", s.AppID, s.Cluster, err)
+	}
+	p := &ApolloProvider{settings: s}
+	agollo.OnUpdate(p.onUpdate)
+	return p, nil
+}
+
+func (p *ApolloProvider) Get(namespace, key string) (string, bool) {
+	v := agollo.GetString(key, agollo.WithNamespace(namespace))
+	return v, v != ""
+}
+
+// Watch 实现 Watcher，Apollo 推送配置变更时回调
+func (p *ApolloProvider) Watch(fn func(namespace, key, old, new string)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.watchers = append(p.watchers, fn)
+}
+
+// onUpdate agollo 只支持一个变更回调，由这里分发给所有 Watch
+func (p *ApolloProvider) onUpdate(e *agollo.ChangeEvent) {
+	p.mu.Lock()
+	watchers := append([]func(namespace, key, old, new string){}, p.watchers...)
+	p.mu.Unlock()
+
+	for _, c := range e.Changes {
+		for _, fn := range watchers {
+			fn(e.Namespace, c.Key, c.OldValue, c.NewValue)
+		}
+	}
+}
+
+func (p *ApolloProvider) Name() string {
+	return "apollo:" + p.settings.Cluster
+}
+
+// apolloLogger agollo 的日志适配，只输出错误
+type apolloLogger struct {
+	log *log.Logger
+}
+
+func (l *apolloLogger) Infof(format string, args ...interface{}) {
+	//l.log.Printf("[INFO] "+format, args...)
+}
+
+func (l *apolloLogger) Errorf(format string, args ...interface{}) {
+	l.log.Printf("[ERROR] "+format, args...)
+}
@@ -0,0 +1,64 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Duration 可以从 JSON 字符串（如 "10m"）解析的时间间隔
+type Duration struct {
+	time.Duration
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("duration must be a string like \"10m\": %w", err)
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	d.Duration = v
+	return nil
+}
+
+// SyncWindow 同步的时间范围和频率
+type SyncWindow struct {
+	LookbackDays int      `json:"lookback_days"` // 每次回溯同步的天数
+	Interval     Duration `json:"interval"`      // 两次同步之间的间隔
+}
+
+// ConnectorSettings 连接器的运行参数，存放在 Apollo application namespace 的
+// application.connector.<platform>，值为 JSON，修改后无需重新部署即可生效
+type ConnectorSettings struct {
+	BatchSize   int        `json:"batch_size"`   // 每批写入或请求的条数
+	RateLimit   float64    `json:"rate_limit"`   // 每秒请求数，0 表示不限流
+	TenantAllow []int64    `json:"tenant_allow"` // 非空时只处理这些租户
+	TenantDeny  []int64    `json:"tenant_deny"`  // 跳过的租户，优先于 TenantAllow
+	SyncWindow  SyncWindow `json:"sync_window"`
+}
+
+// TenantAllowed 判断租户是否需要处理
+func (s ConnectorSettings) TenantAllowed(tenantId int64) bool {
+	if slices.Contains(s.TenantDeny, tenantId) {
+		return false
+	}
+	return len(s.TenantAllow) == 0 || slices.Contains(s.TenantAllow, tenantId)
+}
+
+// ConnectorKey 连接器配置在 Apollo 中的 key
+func ConnectorKey(platform string) string {
+	return "application.connector." + platform
+}
+
+// Connector 加载连接器配置并订阅变更，def 为 Apollo 中没有配置时的默认值
+func Connector(platform string, def ConnectorSettings) *Value[ConnectorSettings] {
+	return NewValue(NamespaceApplication, ConnectorKey(platform), def)
+}
@@ -22,3 +22,17 @@ type DBConfig struct {
 		DisableTypeDedupe bool   `json:"disable_type_dedupe"`
 	} `json:"configuration"`
 }
+
+type MysqlConfig struct {
+	Host     string
+	Name     string
+	Password string
+	Port     int      // 为 0 时使用默认端口
+	Database string   // 为空时由调用方决定库名
+	Replicas []string // 只读副本地址
+}
+
+type LLMConfig struct {
+	Key     string
+	BaseUrl string
+}
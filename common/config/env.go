@@ -0,0 +1,117 @@
+package config
+
+import (
+	"context"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// EnvName 选择运行环境的环境变量
+const EnvName = "WM_ENV"
+
+// FileEnvName local 环境下配置文件路径的环境变量
+const FileEnvName = "WM_CONFIG_FILE"
+
+// 支持的运行环境
+const (
+	EnvUAT   = "uat"   // 默认，使用 UAT 集群的 Apollo，需要 APOLLO_ACCESS_KEY_SECRET
+	EnvProd  = "prod"  // 使用 PROD 集群的 Apollo，连接参数由 APOLLO_* 环境变量提供
+	EnvLocal = "local" // 使用本地配置文件，不连接 Apollo
+	EnvEnv   = "env"   // 只使用环境变量，不连接 Apollo
+)
+
+// DefaultConfigFile local 环境下未设置 WM_CONFIG_FILE 时使用的配置文件
+const DefaultConfigFile = "config.local.yaml"
+
+// FileReloadInterval local 环境下检查配置文件是否修改的间隔
+const FileReloadInterval = 10 * time.Second
+
+var (
+	defaultProvider Provider
+	defaultGen      int
+	defaultMu       sync.Mutex
+)
+
+// CurrentEnv 返回 WM_ENV 指定的环境，未设置时为 uat
+func CurrentEnv() string {
+	if env := strings.ToLower(strings.TrimSpace(os.Getenv(EnvName))); env != "" {
+		return env
+	}
+	return EnvUAT
+}
+
+// NewProvider 按环境创建配置来源，环境变量始终优先于 Apollo 和配置文件
+func NewProvider(env string) (Provider, error) {
+	switch env {
+	case EnvEnv:
+		return EnvProvider{}, nil
+	case EnvLocal:
+		path := os.Getenv(FileEnvName)
+		if path == "" {
+			path = DefaultConfigFile
+		}
+		file, err := NewFileProvider(path)
+		if err != nil {
+			return nil, err
+		}
+		file.StartReload(context.Background(), FileReloadInterval)
+		return Chain(EnvProvider{}, file), nil
+	default:
+		apollo, err := NewApolloProvider(ApolloSettingsFor(env))
+		if err != nil {
+			return nil, err
+		}
+		return Chain(EnvProvider{}, apollo), nil
+	}
+}
+
+// Default 返回当前环境的配置来源，首次调用时创建，创建失败时 panic
+func Default() Provider {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+
+	if defaultProvider == nil {
+		env := CurrentEnv()
+		p, err := NewProvider(env)
+		if err != nil {
+			panic(err)
+		}
+		log.Printf("config: env=%s, provider=%s", env, p.Name())
+		setDefaultLocked(p)
+	}
+	return defaultProvider
+}
+
+// SetDefault 替换默认配置来源，用于测试或在 main 中显式指定
+func SetDefault(p Provider) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	setDefaultLocked(p)
+}
+
+// setDefaultLocked 替换默认来源，并把它的变更通知转给 Subscribe 的订阅者
+// 只转发当前默认来源的通知，被替换的来源之后的变更会被忽略
+func setDefaultLocked(p Provider) {
+	defaultProvider = p
+	defaultGen++
+	if w, ok := p.(Watcher); ok {
+		gen := defaultGen
+		w.Watch(func(namespace, key, old, new string) {
+			defaultMu.Lock()
+			current := gen == defaultGen
+			defaultMu.Unlock()
+			if current {
+				dispatch(namespace, key, old, new)
+			}
+		})
+	}
+}
+
+// GetString 从默认配置来源读取配置，不存在时返回空字符串
+func GetString(namespace, key string) string {
+	v, _ := Default().Get(namespace, key)
+	return v
+}
@@ -0,0 +1,255 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// 配置所在的 Apollo namespace，文件配置按同样的名称分组
+const (
+	NamespaceApplication = "application"
+	NamespaceDatasource  = "datasource"
+	NamespaceCopilot     = "copilot"
+)
+
+// Provider 配置来源，key 为 Apollo 风格的点分名称
+type Provider interface {
+	// Get 返回 namespace 下 key 的值，不存在或为空时 ok 为 false
+	Get(namespace, key string) (value string, ok bool)
+	// Name 配置来源的名称，用于日志和错误信息
+	Name() string
+}
+
+// chain 按顺序查找，前面的来源优先
+type chain struct {
+	providers []Provider
+}
+
+// Chain 组合多个配置来源，前面的来源覆盖后面的
+func Chain(providers ...Provider) Provider {
+	return &chain{providers: providers}
+}
+
+func (c *chain) Get(namespace, key string) (string, bool) {
+	for _, p := range c.providers {
+		if v, ok := p.Get(namespace, key); ok {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// Watch 转发给支持 Watcher 的来源
+func (c *chain) Watch(fn func(namespace, key, old, new string)) {
+	for _, p := range c.providers {
+		if w, ok := p.(Watcher); ok {
+			w.Watch(fn)
+		}
+	}
+}
+
+func (c *chain) Name() string {
+	names := make([]string, len(c.providers))
+	for i, p := range c.providers {
+		names[i] = p.Name()
+	}
+	return strings.Join(names, "+")
+}
+
+// EnvProvider 从环境变量读取配置，变量名为 WM_ 加上大写的 key，点和中划线换成下划线
+// 如 gcs_rw.datasource.api.url 对应 WM_GCS_RW_DATASOURCE_API_URL，namespace 不参与命名
+type EnvProvider struct{}
+
+// EnvKey 返回 key 对应的环境变量名
+func EnvKey(key string) string {
+	return "WM_" + strings.ToUpper(strings.NewReplacer(".", "_", "-", "_").Replace(key))
+}
+
+func (EnvProvider) Get(namespace, key string) (string, bool) {
+	v := os.Getenv(EnvKey(key))
+	return v, v != ""
+}
+
+func (EnvProvider) Name() string {
+	return "env"
+}
+
+// FileProvider 从本地 YAML/JSON 文件读取配置，按扩展名选择格式
+// 文件顶层为 namespace，其下为点分的 key；值为对象或数组时按 JSON 字符串返回，例如：
+//
+//	datasource:
+//	  gcs_rw.datasource.api.url: 127.0.0.1
+//	application:
+//	  application.service.integration.xk.mysql.conf:
+//	    name: xk
+type FileProvider struct {
+	path string
+
+	mu       sync.RWMutex
+	values   map[string]map[string]string
+	modTime  time.Time
+	watchers []func(namespace, key, old, new string)
+}
+
+// NewFileProvider 读取并解析配置文件
+func NewFileProvider(path string) (*FileProvider, error) {
+	p := &FileProvider{path: path}
+	if err := p.Reload(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *FileProvider) Get(namespace, key string) (string, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	v, ok := p.values[namespace][key]
+	return v, ok && v != ""
+}
+
+func (p *FileProvider) Name() string {
+	return "file:" + p.path
+}
+
+// Watch 实现 Watcher，Reload 发现变化时回调
+func (p *FileProvider) Watch(fn func(namespace, key, old, new string)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.watchers = append(p.watchers, fn)
+}
+
+// Reload 重新读取文件，并把变化的 key 通知给 Watch 注册的回调；解析失败时保留原配置
+func (p *FileProvider) Reload() error {
+	info, err := os.Stat(p.path)
+	if err != nil {
+		return err
+	}
+	values, err := loadFile(p.path)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	old := p.values
+	p.values = values
+	p.modTime = info.ModTime()
+	watchers := append([]func(namespace, key, old, new string){}, p.watchers...)
+	p.mu.Unlock()
+
+	for _, c := range diff(old, values) {
+		for _, fn := range watchers {
+			fn(c.namespace, c.key, c.old, c.new)
+		}
+	}
+	return nil
+}
+
+// StartReload 每隔 interval 检查文件修改时间，有变化时 Reload，ctx 结束后停止
+func (p *FileProvider) StartReload(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			info, err := os.Stat(p.path)
+			if err != nil {
+				log.Printf("config: stat %s: %v", p.path, err)
+				continue
+			}
+			p.mu.RLock()
+			changed := !info.ModTime().Equal(p.modTime)
+			p.mu.RUnlock()
+			if !changed {
+				continue
+			}
+			if err := p.Reload(); err != nil {
+				log.Printf("config: reload %s: %v", p.path, err)
+			}
+		}
+	}()
+}
+
+func loadFile(path string) (map[string]map[string]string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	raw := map[string]map[string]any{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = json.Unmarshal(b, &raw)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(b, &raw)
+	default:
+		return nil, fmt.Errorf("unsupported config file %s: want .yaml, .yml or .json", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("decode config file %s: %w", path, err)
+	}
+
+	values := make(map[string]map[string]string, len(raw))
+	for ns, kv := range raw {
+		values[ns] = make(map[string]string, len(kv))
+		for key, v := range kv {
+			s, err := stringify(v)
+			if err != nil {
+				return nil, fmt.Errorf("config file %s: %s.%s: %w", path, ns, key, err)
+			}
+			values[ns][key] = s
+		}
+	}
+	return values, nil
+}
+
+type change struct {
+	namespace, key, old, new string
+}
+
+// diff 比较两份配置，返回新增、修改和删除的 key
+func diff(old, cur map[string]map[string]string) []change {
+	var res []change
+	for ns, kv := range cur {
+		for key, v := range kv {
+			if o := old[ns][key]; o != v {
+				res = append(res, change{ns, key, o, v})
+			}
+		}
+	}
+	for ns, kv := range old {
+		for key, o := range kv {
+			if _, ok := cur[ns][key]; !ok {
+				res = append(res, change{ns, key, o, ""})
+			}
+		}
+	}
+	return res
+}
+
+// stringify 标量按原样转为字符串，对象和数组编码为 JSON，与 Apollo 中存放 JSON 配置的方式一致
+func stringify(v any) (string, error) {
+	switch v := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case map[string]any, []any:
+		b, err := json.Marshal(v)
+		return string(b), err
+	default:
+		return fmt.Sprint(v), nil
+	}
+}
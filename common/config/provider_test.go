@@ -0,0 +1,82 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFileProvider(t *testing.T) {
+	dir := t.TempDir()
+	yamlPath := filepath.Join(dir, "local.yaml")
+	os.WriteFile(yamlPath, []byte(`
+datasource:
+  gcs_rw.datasource.api.url: 127.0.0.1
+  gcs_rw.datasource.api.name: root
+  gcs_rw.datasource.api.password: pwd
+application:
+  application.service.integration.xk.mysql.conf:
+    name: xk
+    configuration:
+      port: 3306
+copilot:
+  copilot.llm.gemini.key: k
+  copilot.llm.gemini.base.url: http://localhost
+`), 0o644)
+	jsonPath := filepath.Join(dir, "local.json")
+	os.WriteFile(jsonPath, []byte(`{"datasource":{"gcs_rw.datasource.api.url":"10.0.0.1"}}`), 0o644)
+
+	p, err := NewFileProvider(yamlPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetDefault(p)
+	defer SetDefault(nil)
+
+	if cfg := GetMysqlConfig(); !reflect.DeepEqual(cfg, MysqlConfig{Host: "127.0.0.1", Name: "root", Password: "pwd"}) {
+		t.Fatalf("MySQL 配置不符合预期: %+v", cfg)
+	}
+	if xk := GetXkMysqlConfig(); xk.Name != "xk" || xk.Configuration.Port != 3306 {
+		t.Fatalf("对象值应按 JSON 解析: %+v", xk)
+	}
+	if _, err := GetLLMConfigSafe(); err != nil {
+		t.Fatal(err)
+	}
+	if cfg := GetAirbyteMysqlConfig(); !reflect.DeepEqual(cfg, MysqlConfig{}) {
+		t.Fatalf("缺少的配置应为空: %+v", cfg)
+	}
+
+	j, err := NewFileProvider(jsonPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(EnvKey("gcs_rw.datasource.api.password"), "from-env")
+	SetDefault(Chain(EnvProvider{}, j, p))
+	if cfg := GetMysqlConfig(); !reflect.DeepEqual(cfg, MysqlConfig{Host: "10.0.0.1", Name: "root", Password: "from-env"}) {
+		t.Fatalf("应按环境变量、JSON、YAML 的顺序覆盖: %+v", cfg)
+	}
+}
+
+func TestNewProviderLocal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yml")
+	os.WriteFile(path, []byte("copilot:\n  copilot.llm.gemini.key: k\n"), 0o644)
+	t.Setenv(FileEnvName, path)
+
+	p, err := NewProvider(EnvLocal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := p.Get(NamespaceCopilot, "copilot.llm.gemini.key"); !ok || v != "k" {
+		t.Fatalf("local 环境应读取配置文件: %q %v", v, ok)
+	}
+	t.Setenv("APOLLO_META_ADDR", "")
+	if _, err := NewProvider(EnvProd); err == nil {
+		t.Fatal("prod 环境未设置 APOLLO_META_ADDR 时应返回错误")
+	}
+	t.Setenv("APOLLO_ACCESS_KEY_SECRET", "")
+	if _, err := NewProvider(EnvUAT); err == nil || !strings.Contains(err.Error(), "APOLLO_ACCESS_KEY_SECRET") {
+		t.Fatalf("未设置 APOLLO_ACCESS_KEY_SECRET 时应返回错误: %v", err)
+	}
+}
@@ -0,0 +1,104 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// GetS3Config 获取 S3 配置
+func GetS3Config() S3Config {
+	return S3Config{
+		AccessKeyID:     GetString(NamespaceApplication, "application.service.aws.workmagicTatariS3Key"),
+		SecretAccessKey: GetString(NamespaceApplication, "application.service.aws.workmagicTatariS3Secret"),
+		Region:          "us-east-1",
+	}
+}
+
+// GetDevelopS3Config 获取开发环境 S3 配置
+func GetDevelopS3Config() S3Config {
+	res := S3Config{}
+	if err := getJSON(NamespaceApplication, "application.service.aws.iam.develop", &res); err != nil {
+		panic(err)
+	}
+	return res
+}
+
+// GetMysqlConfig 获取平台库 MySQL 配置
+func GetMysqlConfig() MysqlConfig {
+	return getMysqlConfig("gcs_rw")
+}
+
+// GetAirbyteMysqlConfig 获取 Airbyte MySQL 配置
+func GetAirbyteMysqlConfig() MysqlConfig {
+	return getMysqlConfig("airbyte")
+}
+
+// getMysqlConfig 读取 <prefix>.datasource.api.* 下的连接配置
+// port、database、replicas（逗号分隔）为可选项
+func getMysqlConfig(prefix string) MysqlConfig {
+	key := prefix + ".datasource.api."
+	cfg := MysqlConfig{
+		Host:     GetString(NamespaceDatasource, key+"url"),
+		Name:     GetString(NamespaceDatasource, key+"name"),
+		Password: GetString(NamespaceDatasource, key+"password"),
+		Database: GetString(NamespaceDatasource, key+"database"),
+	}
+	if port := GetString(NamespaceDatasource, key+"port"); port != "" {
+		cfg.Port, _ = strconv.Atoi(port)
+	}
+	for _, host := range strings.Split(GetString(NamespaceDatasource, key+"replicas"), ",") {
+		if host = strings.TrimSpace(host); host != "" {
+			cfg.Replicas = append(cfg.Replicas, host)
+		}
+	}
+	return cfg
+}
+
+// GetPinterestSourceSetting 获取 Pinterest 源设置
+func GetPinterestSourceSetting() string {
+	return GetString(NamespaceApplication, "application.service.integration.airbyte.source.pinterest")
+}
+
+// GetXkMysqlConfig 获取 XK MySQL 配置
+func GetXkMysqlConfig() DBConfig {
+	cfg := DBConfig{}
+	if err := getJSON(NamespaceApplication, "application.service.integration.xk.mysql.conf", &cfg); err != nil {
+		panic(err)
+	}
+	return cfg
+}
+
+// GetLLMConfigSafe 获取 Gemini 配置，缺少配置时返回错误
+func GetLLMConfigSafe() (LLMConfig, error) {
+	res := LLMConfig{
+		Key:     GetString(NamespaceCopilot, "copilot.llm.gemini.key"),
+		BaseUrl: GetString(NamespaceCopilot, "copilot.llm.gemini.base.url"),
+	}
+	if res.Key == "" || res.BaseUrl == "" {
+		return res, fmt.Errorf("config: copilot.llm.gemini.key or copilot.llm.gemini.base.url not found in %s", Default().Name())
+	}
+	return res, nil
+}
+
+// GetLLMConfig 获取 Gemini 配置，缺少配置时 panic
+func GetLLMConfig() LLMConfig {
+	res, err := GetLLMConfigSafe()
+	if err != nil {
+		panic(err)
+	}
+	return res
+}
+
+// getJSON 读取 JSON 格式的配置值
+func getJSON(namespace, key string, v any) error {
+	s, ok := Default().Get(namespace, key)
+	if !ok {
+		return fmt.Errorf("config: %s not found in %s", key, Default().Name())
+	}
+	if err := json.Unmarshal([]byte(s), v); err != nil {
+		return fmt.Errorf("config: decode %s: %w", key, err)
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package config
+
+import (
+	"encoding/json"
+	"log"
+	"reflect"
+	"sync"
+)
+
+// Watcher 能推送配置变更的来源，Apollo 和本地文件都实现了该接口
+type Watcher interface {
+	// Watch 注册变更回调，new 为空表示 key 被删除
+	Watch(fn func(namespace, key, old, new string))
+}
+
+// subscription 订阅的配置项，不同 namespace 下的同名 key 互不影响
+type subscription struct {
+	namespace string
+	key       string
+}
+
+var subscribers = struct {
+	mu   sync.Mutex
+	next int
+	m    map[subscription]map[int]func(old, new string)
+}{m: make(map[subscription]map[int]func(old, new string))}
+
+// Subscribe 订阅 namespace 下 key 的变更，返回取消订阅的函数
+// 回调在推送变更的 goroutine 中执行，耗时操作需要自行异步处理
+func Subscribe(namespace, key string, fn func(old, new string)) (cancel func()) {
+	// 确保默认来源已创建并开始推送变更
+	Default()
+
+	subscribers.mu.Lock()
+	defer subscribers.mu.Unlock()
+
+	sub := subscription{namespace, key}
+	id := subscribers.next
+	subscribers.next++
+	if subscribers.m[sub] == nil {
+		subscribers.m[sub] = make(map[int]func(old, new string))
+	}
+	subscribers.m[sub][id] = fn
+
+	return func() {
+		subscribers.mu.Lock()
+		defer subscribers.mu.Unlock()
+		delete(subscribers.m[sub], id)
+	}
+}
+
+func dispatch(namespace, key, old, new string) {
+	sub := subscription{namespace, key}
+	subscribers.mu.Lock()
+	fns := make([]func(old, new string), 0, len(subscribers.m[sub]))
+	for _, fn := range subscribers.m[sub] {
+		fns = append(fns, fn)
+	}
+	subscribers.mu.Unlock()
+
+	for _, fn := range fns {
+		fn(old, new)
+	}
+}
+
+// Value 从 JSON 配置解析的类型化配置，配置变更后自动重新加载，可并发使用
+// 配置中缺少的字段使用默认值；解析失败时保留当前值
+type Value[T any] struct {
+	namespace string
+	key       string
+	def       []byte
+
+	mu        sync.RWMutex
+	cur       T
+	listeners []func(old, new T)
+}
+
+// NewValue 加载 namespace 下 key 的配置并订阅变更，def 为默认值
+func NewValue[T any](namespace, key string, def T) *Value[T] {
+	b, err := json.Marshal(def)
+	if err != nil {
+		panic(err)
+	}
+	v := &Value[T]{namespace: namespace, key: key, def: b}
+	v.cur, _ = v.load()
+	Subscribe(namespace, key, func(_, _ string) { v.reload() })
+	return v
+}
+
+// Get 返回当前配置
+func (v *Value[T]) Get() T {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return v.cur
+}
+
+// OnChange 注册配置变更回调，只有解析后的值发生变化时才调用
+func (v *Value[T]) OnChange(fn func(old, new T)) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.listeners = append(v.listeners, fn)
+}
+
+// defaults 每次都从默认值的 JSON 重新解析，避免多次加载共享切片和 map
+func (v *Value[T]) defaults() T {
+	var res T
+	if err := json.Unmarshal(v.def, &res); err != nil {
+		panic(err)
+	}
+	return res
+}
+
+// load 在默认值的基础上覆盖配置中的字段，解析失败时返回默认值和 false
+func (v *Value[T]) load() (T, bool) {
+	res := v.defaults()
+	s, ok := Default().Get(v.namespace, v.key)
+	if !ok {
+		return res, true
+	}
+	if err := json.Unmarshal([]byte(s), &res); err != nil {
+		log.Printf("config: decode %s: %v", v.key, err)
+		return v.defaults(), false
+	}
+	return res, true
+}
+
+// reload 重新加载配置，变化时通知 OnChange 的回调
+func (v *Value[T]) reload() {
+	next, ok := v.load()
+	if !ok {
+		return
+	}
+
+	v.mu.Lock()
+	old := v.cur
+	if reflect.DeepEqual(old, next) {
+		v.mu.Unlock()
+		return
+	}
+	v.cur = next
+	listeners := append([]func(old, new T){}, v.listeners...)
+	v.mu.Unlock()
+
+	log.Printf("config: %s 已更新", v.key)
+	for _, fn := range listeners {
+		fn(old, next)
+	}
+}
@@ -0,0 +1,98 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestValueReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "local.yaml")
+	write := func(s string) {
+		if err := os.WriteFile(path, []byte(s), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(`
+application:
+  application.connector.demo:
+    batch_size: 100
+    tenant_deny: [2]
+`)
+	p, err := NewFileProvider(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetDefault(p)
+	defer SetDefault(nil)
+
+	var raw []string
+	cancel := Subscribe(NamespaceApplication, ConnectorKey("demo"), func(old, new string) { raw = append(raw, new) })
+	defer cancel()
+	// 其他 namespace 下的同名 key 不应收到通知
+	cancelOther := Subscribe(NamespaceCopilot, ConnectorKey("demo"), func(old, new string) {
+		t.Errorf("不应收到 %s 的变更通知", NamespaceCopilot)
+	})
+	defer cancelOther()
+
+	v := Connector("demo", ConnectorSettings{
+		RateLimit:  5,
+		SyncWindow: SyncWindow{Interval: Duration{10 * time.Minute}},
+	})
+	got := v.Get()
+	if got.BatchSize != 100 || got.RateLimit != 5 || got.SyncWindow.Interval.Duration != 10*time.Minute {
+		t.Fatalf("应在默认值的基础上覆盖配置: %+v", got)
+	}
+	if got.TenantAllowed(2) || !got.TenantAllowed(3) {
+		t.Fatalf("租户过滤不符合预期: %+v", got)
+	}
+
+	var changes []ConnectorSettings
+	v.OnChange(func(old, new ConnectorSettings) { changes = append(changes, new) })
+
+	write(`
+application:
+  application.connector.demo:
+    batch_size: 50
+    tenant_allow: [1]
+    sync_window:
+      interval: 30s
+`)
+	if err := p.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	got = v.Get()
+	if got.BatchSize != 50 || got.SyncWindow.Interval.Duration != 30*time.Second || !got.TenantAllowed(1) || got.TenantAllowed(3) {
+		t.Fatalf("配置变更后应重新加载: %+v", got)
+	}
+	if len(changes) != 1 || len(raw) != 1 {
+		t.Fatalf("期望各收到 1 次变更通知，实际 %d %d", len(changes), len(raw))
+	}
+
+	// 解析失败时保留当前值，不通知
+	write(`
+application:
+  application.connector.demo:
+    batch_size: "many"
+`)
+	if err := p.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if v.Get().BatchSize != 50 || len(changes) != 1 {
+		t.Fatalf("解析失败时应保留当前配置: %+v", v.Get())
+	}
+
+	// 取消订阅后不再收到原始变更
+	cancel()
+	write("application: {}\n")
+	if err := p.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if len(raw) != 2 {
+		t.Fatalf("取消订阅后不应再收到通知，实际 %d 次", len(raw))
+	}
+	if got := v.Get(); got.BatchSize != 0 || got.RateLimit != 5 {
+		t.Fatalf("配置删除后应恢复默认值: %+v", got)
+	}
+}
@@ -1,51 +1,79 @@
 package airbyte_db
 
 import (
-	"fmt"
-	"gorm.io/driver/mysql"
-	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
-	"log"
 	"sync"
-	"time"
-	"wm-func/common/apollo"
+	"wm-func/common/config"
+	"wm-func/common/db"
+
+	"gorm.io/gorm"
 )
 
+// Database Airbyte 目标库的默认库名，配置中的 airbyte.datasource.api.database 可覆盖
+const Database = "airbyte_destination_v2"
+
 var (
-	db   *gorm.DB
-	once sync.Once
+	cluster *db.Cluster
+	mu      sync.Mutex
 )
 
-// InitDB 初始化 MySQL 数据库连接池
-func InitDB() {
-	//application.service.integration.airbyte.default.destination.mysql
-	once.Do(func() {
-		cfg := apollo.GetAirbyteMysqlConfig()
-
-		var err error
-		// 使用 gorm.Open() 和 mysql.Open() 连接 MySQL 数据库
-		db, err = gorm.Open(mysql.New(mysql.Config{
-			DSN: fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=utf8mb4&parseTime=True&loc=Local", cfg.Name, cfg.Password, cfg.Host, 3306, "airbyte_destination_v2"),
-		}), &gorm.Config{
-			Logger: logger.Default.LogMode(logger.Silent), // 禁用所有日志
-		})
-		if err != nil {
-			log.Fatalf("MySQL 连接失败: %v", err)
-		}
+// Config 从配置中心读取 Airbyte 目标库的连接配置
+func Config() db.Config {
+	cfg := config.GetAirbyteMysqlConfig()
+	database := cfg.Database
+	if database == "" {
+		database = Database
+	}
+	return db.Config{
+		Host:     cfg.Host,
+		Port:     cfg.Port,
+		User:     cfg.Name,
+		Password: cfg.Password,
+		Database: database,
+		Replicas: cfg.Replicas,
+	}
+}
 
-		// 配置连接池
-		sqlDB, err := db.DB()
+// InitDB 初始化 MySQL 数据库连接池，只在第一次调用时连接；连接失败时下次调用会重试
+func InitDB() error {
+	_, err := getCluster()
+	return err
+}
+
+func getCluster() (*db.Cluster, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if cluster == nil {
+		c, err := db.OpenCluster(Config())
 		if err != nil {
-			log.Fatalf("获取数据库连接失败: %v", err)
+			return nil, err
 		}
-		sqlDB.SetMaxOpenConns(100)                 // 最大连接数
-		sqlDB.SetMaxIdleConns(10)                  // 最大空闲连接数
-		sqlDB.SetConnMaxLifetime(30 * time.Minute) // 连接的最大存活时间
-	})
+		cluster = c
+	}
+	return cluster, nil
 }
 
-// GetDB 返回数据库实例
+// SetDB 注入连接，用于测试或使用自定义配置，replicas 为只读副本
+func SetDB(primary *gorm.DB, replicas ...*gorm.DB) {
+	mu.Lock()
+	defer mu.Unlock()
+	cluster = db.NewCluster(primary, replicas...)
+}
+
+// GetDB 返回数据库实例，连接失败时 panic
 func GetDB() *gorm.DB {
-	InitDB()
-	return db
+	c, err := getCluster()
+	if err != nil {
+		panic(err)
+	}
+	return c.Primary()
+}
+
+// GetReadDB 返回只读副本，没有配置副本时返回主库
+func GetReadDB() *gorm.DB {
+	c, err := getCluster()
+	if err != nil {
+		panic(err)
+	}
+	return c.Reader()
 }
@@ -0,0 +1,206 @@
+// Package db 创建 gorm 连接的工厂，platform_db、airbyte_db 等包在此基础上提供单例
+package db
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	driver "github.com/go-sql-driver/mysql"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// DefaultPort MySQL 默认端口
+const DefaultPort = 3306
+
+// 连接池默认值，与原先 platform_db/airbyte_db 的设置一致
+const (
+	DefaultMaxOpenConns    = 100
+	DefaultMaxIdleConns    = 10
+	DefaultConnMaxLifetime = 30 * time.Minute
+)
+
+// Config 连接配置
+type Config struct {
+	// DSN 完整的 go-sql-driver DSN，设置后忽略 Host、Port、User、Password、Database、TLS
+	DSN string
+
+	Host     string // 可以带端口，如 10.0.0.1:3307
+	Port     int    // 为 0 时使用 DefaultPort
+	User     string
+	Password string
+	Database string
+
+	// TLS 为 go-sql-driver 的 tls 参数：true、skip-verify、preferred 或已注册的名称
+	// 设置 TLSConfig 时会以 Host 为名注册并忽略 TLS
+	TLS       string
+	TLSConfig *tls.Config
+
+	// Replicas 只读副本的地址（可以带端口），与主库共用账号和库名
+	Replicas []string
+
+	MaxOpenConns    int           // 为 0 时使用 DefaultMaxOpenConns
+	MaxIdleConns    int           // 为 0 时使用 DefaultMaxIdleConns
+	ConnMaxLifetime time.Duration // 为 0 时使用 DefaultConnMaxLifetime
+
+	LogLevel logger.LogLevel // gorm 日志级别，为 0 时为 Silent
+
+	// Dialector 注入已创建好的连接（如测试用的 SQLite 或 docker 中的 MySQL），设置后忽略其他连接参数
+	Dialector gorm.Dialector
+}
+
+// dsn 返回主库的 DSN
+func (c Config) dsn() (string, error) {
+	if c.DSN != "" {
+		return c.DSN, nil
+	}
+	return c.hostDSN(c.Host)
+}
+
+// hostDSN 返回指定 host 的 DSN，参数与原先手写的一致：utf8mb4、parseTime、本地时区
+func (c Config) hostDSN(host string) (string, error) {
+	if host == "" {
+		return "", errors.New("db: host is empty")
+	}
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		port := c.Port
+		if port == 0 {
+			port = DefaultPort
+		}
+		host = net.JoinHostPort(host, strconv.Itoa(port))
+	}
+
+	cfg := driver.NewConfig()
+	cfg.User = c.User
+	cfg.Passwd = c.Password
+	cfg.Net = "tcp"
+	cfg.Addr = host
+	cfg.DBName = c.Database
+	cfg.ParseTime = true
+	cfg.Loc = time.Local
+	cfg.Params = map[string]string{"charset": "utf8mb4"}
+	cfg.TLSConfig = c.TLS
+	if c.TLSConfig != nil {
+		name := "db-" + host
+		if err := driver.RegisterTLSConfig(name, c.TLSConfig); err != nil {
+			return "", fmt.Errorf("db: register tls config: %w", err)
+		}
+		cfg.TLSConfig = name
+	}
+	return cfg.FormatDSN(), nil
+}
+
+// Open 按配置创建连接并设置连接池，失败时返回错误而不是退出进程
+func Open(cfg Config) (*gorm.DB, error) {
+	dialector := cfg.Dialector
+	if dialector == nil {
+		dsn, err := cfg.dsn()
+		if err != nil {
+			return nil, err
+		}
+		dialector = mysql.New(mysql.Config{DSN: dsn})
+	}
+	return open(dialector, cfg)
+}
+
+func open(dialector gorm.Dialector, cfg Config) (*gorm.DB, error) {
+	level := cfg.LogLevel
+	if level == 0 {
+		level = logger.Silent
+	}
+	db, err := gorm.Open(dialector, &gorm.Config{
+		Logger: logger.Default.LogMode(level),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("db: open %s: %w", cfg.describe(), err)
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("db: get sql.DB: %w", err)
+	}
+	sqlDB.SetMaxOpenConns(orDefault(cfg.MaxOpenConns, DefaultMaxOpenConns))
+	sqlDB.SetMaxIdleConns(orDefault(cfg.MaxIdleConns, DefaultMaxIdleConns))
+	sqlDB.SetConnMaxLifetime(orDefault(cfg.ConnMaxLifetime, DefaultConnMaxLifetime))
+	return db, nil
+}
+
+// describe 用于错误信息，不包含密码
+func (c Config) describe() string {
+	if c.Dialector != nil {
+		return c.Dialector.Name()
+	}
+	if c.DSN != "" {
+		if parsed, err := driver.ParseDSN(c.DSN); err == nil {
+			return parsed.Addr + "/" + parsed.DBName
+		}
+		return "dsn"
+	}
+	return c.Host + "/" + c.Database
+}
+
+func orDefault[T int | time.Duration](v, def T) T {
+	if v == 0 {
+		return def
+	}
+	return v
+}
+
+// Cluster 主库和只读副本
+type Cluster struct {
+	primary  *gorm.DB
+	replicas []*gorm.DB
+	next     atomic.Uint64
+}
+
+// OpenCluster 创建主库和所有只读副本的连接，副本连接失败时返回错误
+func OpenCluster(cfg Config) (*Cluster, error) {
+	primary, err := Open(cfg)
+	if err != nil {
+		return nil, err
+	}
+	c := &Cluster{primary: primary}
+	if cfg.Dialector != nil {
+		// 注入的连接没有副本
+		return c, nil
+	}
+	for _, host := range cfg.Replicas {
+		dsn, err := cfg.hostDSN(host)
+		if err != nil {
+			return nil, err
+		}
+		replicaCfg := cfg
+		replicaCfg.Host = host
+		replica, err := open(mysql.New(mysql.Config{DSN: dsn}), replicaCfg)
+		if err != nil {
+			return nil, err
+		}
+		c.replicas = append(c.replicas, replica)
+	}
+	return c, nil
+}
+
+// NewCluster 由已有连接组成 Cluster，用于测试注入
+func NewCluster(primary *gorm.DB, replicas ...*gorm.DB) *Cluster {
+	return &Cluster{primary: primary, replicas: replicas}
+}
+
+// Primary 返回主库连接，写入和要求强一致的读取使用
+func (c *Cluster) Primary() *gorm.DB {
+	return c.primary
+}
+
+// Reader 轮流返回只读副本，没有副本时返回主库
+func (c *Cluster) Reader() *gorm.DB {
+	if len(c.replicas) == 0 {
+		return c.primary
+	}
+	i := c.next.Add(1) - 1
+	return c.replicas[i%uint64(len(c.replicas))]
+}
@@ -0,0 +1,70 @@
+package db
+
+import (
+	"strings"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestConfigDSN(t *testing.T) {
+	cfg := Config{Host: "10.0.0.1", User: "root", Password: "p@ss", Database: "platform_offline"}
+	dsn, err := cfg.dsn()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"root:p@ss@tcp(10.0.0.1:3306)/platform_offline", "charset=utf8mb4", "parseTime=true", "loc=Local"} {
+		if !strings.Contains(dsn, want) {
+			t.Fatalf("DSN %q 缺少 %q", dsn, want)
+		}
+	}
+
+	cfg.Port, cfg.TLS = 3307, "skip-verify"
+	if dsn, _ = cfg.dsn(); !strings.Contains(dsn, "tcp(10.0.0.1:3307)") || !strings.Contains(dsn, "tls=skip-verify") {
+		t.Fatalf("端口或 TLS 不符合预期: %s", dsn)
+	}
+	if dsn, _ = cfg.hostDSN("10.0.0.2:3310"); !strings.Contains(dsn, "tcp(10.0.0.2:3310)") {
+		t.Fatalf("地址自带端口时不应再追加: %s", dsn)
+	}
+	if _, err := (Config{}).dsn(); err == nil {
+		t.Fatal("缺少 host 时应返回错误")
+	}
+}
+
+// sqliteDB 通过 Dialector 注入 SQLite 连接，演示测试中不依赖 MySQL
+func sqliteDB(t *testing.T) *gorm.DB {
+	db, err := Open(Config{Dialector: sqlite.Open(":memory:"), MaxOpenConns: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if conn, err := db.DB(); err == nil {
+			conn.Close()
+		}
+	})
+	return db
+}
+
+func TestOpenWithDialectorAndCluster(t *testing.T) {
+	primary, replica := sqliteDB(t), sqliteDB(t)
+	if err := primary.Exec("CREATE TABLE t (name TEXT)").Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := primary.Exec("INSERT INTO t VALUES ('a')").Error; err != nil {
+		t.Fatal(err)
+	}
+	var n int
+	if err := primary.Raw("SELECT COUNT(*) FROM t").Scan(&n).Error; err != nil || n != 1 {
+		t.Fatalf("注入的连接不可用: %d %v", n, err)
+	}
+
+	c := NewCluster(primary)
+	if c.Reader() != primary {
+		t.Fatal("没有副本时 Reader 应返回主库")
+	}
+	c = NewCluster(primary, replica)
+	if c.Reader() != replica || c.Primary() != primary {
+		t.Fatal("有副本时 Reader 应返回副本")
+	}
+}
@@ -0,0 +1,218 @@
+package http_request
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// CassetteMode 录制/回放模式
+type CassetteMode string
+
+const (
+	ModeReplay CassetteMode = "replay" // 只从磁带回放，不访问网络
+	ModeRecord CassetteMode = "record" // 访问真实接口并把请求/响应写入磁带
+)
+
+// CassetteModeEnv 设置为 record 时重新录制，其他值或未设置时回放
+const CassetteModeEnv = "HTTP_CASSETTE"
+
+var (
+	ErrCassetteNotFound = errors.New("cassette not found")
+	ErrNoInteraction    = errors.New("no recorded interaction matches request")
+)
+
+// Interaction 一次录制的请求和响应
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+type RecordedRequest struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+type RecordedResponse struct {
+	Status int         `json:"status"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+// Cassette 录制/回放用的 http.RoundTripper
+// 回放时按方法、URL 和请求体匹配，多条匹配时按录制顺序依次返回，保证轮询、分页等重复请求结果确定
+type Cassette struct {
+	path string
+	mode CassetteMode
+	real http.RoundTripper
+
+	mu           sync.Mutex
+	interactions []Interaction
+	used         []bool
+}
+
+// CassetteModeFromEnv 从 HTTP_CASSETTE 读取模式，默认回放
+func CassetteModeFromEnv() CassetteMode {
+	if CassetteMode(os.Getenv(CassetteModeEnv)) == ModeRecord {
+		return ModeRecord
+	}
+	return ModeReplay
+}
+
+// NewCassette 打开磁带文件；回放模式下文件不存在时返回 ErrCassetteNotFound
+func NewCassette(path string, mode CassetteMode) (*Cassette, error) {
+	c := &Cassette{path: path, mode: mode, real: http.DefaultTransport}
+	if mode == ModeRecord {
+		return c, nil
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("%w: %s", ErrCassetteNotFound, path)
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &c.interactions); err != nil {
+		return nil, fmt.Errorf("decode cassette %s: %w", path, err)
+	}
+	c.used = make([]bool, len(c.interactions))
+	return c, nil
+}
+
+// RoundTrip 实现 http.RoundTripper
+func (c *Cassette) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		b, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		body = b
+		req.Body = io.NopCloser(bytes.NewReader(b))
+	}
+	recorded := RecordedRequest{
+		Method: req.Method,
+		URL:    defaultRedactor.URL(req.URL),
+		Header: defaultRedactor.Header(req.Header),
+		Body:   defaultRedactor.Body(string(body)),
+	}
+
+	if c.mode == ModeRecord {
+		return c.record(req, recorded)
+	}
+	return c.replay(req, recorded)
+}
+
+func (c *Cassette) record(req *http.Request, recorded RecordedRequest) (*http.Response, error) {
+	resp, err := c.real.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.interactions = append(c.interactions, Interaction{
+		Request: recorded,
+		Response: RecordedResponse{
+			Status: resp.StatusCode,
+			Header: defaultRedactor.Header(resp.Header),
+			Body:   defaultRedactor.Body(string(body)),
+		},
+	})
+	c.mu.Unlock()
+
+	// 调用方拿到的是未脱敏的真实响应
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	return resp, nil
+}
+
+func (c *Cassette) replay(req *http.Request, recorded RecordedRequest) (*http.Response, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i, in := range c.interactions {
+		if c.used[i] || !in.Request.matches(recorded) {
+			continue
+		}
+		c.used[i] = true
+		header := in.Response.Header.Clone()
+		if header == nil {
+			header = http.Header{}
+		}
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", in.Response.Status, http.StatusText(in.Response.Status)),
+			StatusCode:    in.Response.Status,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        header,
+			Body:          io.NopCloser(strings.NewReader(in.Response.Body)),
+			ContentLength: int64(len(in.Response.Body)),
+			Request:       req,
+		}, nil
+	}
+	return nil, fmt.Errorf("%w: %s %s", ErrNoInteraction, recorded.Method, recorded.URL)
+}
+
+// Save 录制模式下把磁带写入文件，回放模式下什么都不做
+func (c *Cassette) Save() error {
+	if c.mode != ModeRecord {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	b, err := json.MarshalIndent(c.interactions, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(c.path, append(b, '\n'), 0o644)
+}
+
+// Unused 返回回放模式下没有被请求到的录制，用于检查请求是否少于录制时
+func (c *Cassette) Unused() []Interaction {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var res []Interaction
+	for i, in := range c.interactions {
+		if !c.used[i] {
+			res = append(res, in)
+		}
+	}
+	return res
+}
+
+// matches 按方法、URL 和请求体匹配，查询参数顺序无关
+func (r RecordedRequest) matches(other RecordedRequest) bool {
+	if r.Method != other.Method || r.Body != other.Body {
+		return false
+	}
+	a, errA := url.Parse(r.URL)
+	b, errB := url.Parse(other.URL)
+	if errA != nil || errB != nil {
+		return r.URL == other.URL
+	}
+	return a.Scheme == b.Scheme && a.Host == b.Host && a.Path == b.Path &&
+		a.Query().Encode() == b.Query().Encode()
+}
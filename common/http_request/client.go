@@ -0,0 +1,341 @@
+package http_request
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DefaultTimeout 单次请求的默认超时时间
+const DefaultTimeout = 60 * time.Second
+
+// HTTPError 服务端返回了非 2xx 状态码
+type HTTPError struct {
+	Method string
+	URL    string
+	Status int
+	Body   []byte
+	Header http.Header
+}
+
+func (e *HTTPError) Error() string {
+	body := e.Body
+	if len(body) > 512 {
+		body = body[:512]
+	}
+	return fmt.Sprintf("%s %s: status %d: %s", e.Method, e.URL, e.Status, body)
+}
+
+// Retryable 429 和 5xx 视为可重试
+func (e *HTTPError) Retryable() bool {
+	return e.Status == http.StatusTooManyRequests || e.Status >= 500
+}
+
+// RetryPolicy 失败重试的指数退避参数
+type RetryPolicy struct {
+	MaxRetries    int           // 最多重试次数，不含首次请求
+	Initial       time.Duration // 首次重试等待时间
+	Max           time.Duration // 单次等待上限
+	Multiplier    float64       // 每次重试的放大倍数
+	MaxRetryAfter time.Duration // Retry-After 的上限，服务端要求更久时按上限等待
+}
+
+// DefaultRetryPolicy 默认重试策略：最多重试 3 次，1s 起步翻倍，最长 30s
+var DefaultRetryPolicy = RetryPolicy{
+	MaxRetries:    3,
+	Initial:       time.Second,
+	Max:           30 * time.Second,
+	Multiplier:    2,
+	MaxRetryAfter: 2 * time.Minute,
+}
+
+// delay 计算第 attempt 次重试的等待时间，使用 equal jitter
+func (p RetryPolicy) delay(attempt int) time.Duration {
+	d := float64(p.Initial)
+	for i := 0; i < attempt && d < float64(p.Max); i++ {
+		d *= p.Multiplier
+	}
+	if d > float64(p.Max) {
+		d = float64(p.Max)
+	}
+	half := d / 2
+	return time.Duration(half + rand.Float64()*half)
+}
+
+// Config 客户端配置
+type Config struct {
+	Timeout   time.Duration             // 单次请求超时，默认 DefaultTimeout
+	Retry     *RetryPolicy              // 为空时使用 DefaultRetryPolicy
+	Transport http.RoundTripper         // 为空时使用 http.DefaultTransport
+	Limiter   func(host string) Limiter // 为每个 host 创建限流器，为空时不限流
+	Log       *LogConfig                // 请求日志，为空时按 HTTP_LOG 环境变量决定是否开启
+}
+
+// Client 带超时、重试和按 host 限流的 HTTP 客户端，可并发使用
+type Client struct {
+	http       *http.Client
+	retry      RetryPolicy
+	newLimiter func(host string) Limiter
+	logger     *slog.Logger // 重试日志，请求日志未开启时为空
+
+	mu       sync.Mutex
+	limiters map[string]Limiter
+}
+
+// NewClient 按配置创建客户端
+func NewClient(cfg Config) *Client {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+	retry := DefaultRetryPolicy
+	if cfg.Retry != nil {
+		retry = *cfg.Retry
+	}
+	transport := cfg.Transport
+	logCfg := cfg.Log
+	if logCfg == nil {
+		logCfg = LogConfigFromEnv()
+	}
+	var logger *slog.Logger
+	if logCfg != nil {
+		lt := NewLoggingTransport(transport, *logCfg)
+		transport, logger = lt, lt.logger
+	}
+	return &Client{
+		http:       &http.Client{Timeout: cfg.Timeout, Transport: transport},
+		retry:      retry,
+		newLimiter: cfg.Limiter,
+		logger:     logger,
+		limiters:   make(map[string]Limiter),
+	}
+}
+
+// DefaultClient 使用默认配置的共享客户端
+var DefaultClient = NewClient(Config{})
+
+// SetHostLimiter 为指定 host 设置限流器，覆盖 Config.Limiter 的结果
+func (c *Client) SetHostLimiter(host string, l Limiter) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.limiters[host] = l
+}
+
+func (c *Client) limiterFor(host string) Limiter {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if l, ok := c.limiters[host]; ok {
+		return l
+	}
+	var l Limiter
+	if c.newLimiter != nil {
+		l = c.newLimiter(host)
+	}
+	c.limiters[host] = l
+	return l
+}
+
+// Get 发送 GET 请求
+func (c *Client) Get(ctx context.Context, rawURL string, headers, params map[string]string) ([]byte, error) {
+	return c.Do(ctx, http.MethodGet, rawURL, headers, params, nil)
+}
+
+// Post 发送 POST 请求，默认 Content-Type 为 application/json
+func (c *Client) Post(ctx context.Context, rawURL string, headers, params map[string]string, data []byte) ([]byte, error) {
+	return c.Do(ctx, http.MethodPost, rawURL, headers, params, data)
+}
+
+type retryUnsafeKey struct{}
+
+// WithRetryUnsafe 声明 ctx 上的请求可以安全地重复发送，Do 会像 GET 一样重试 POST 等非幂等方法
+// 只应用于服务端去重（如带幂等键）或重复执行无副作用的请求
+func WithRetryUnsafe(ctx context.Context) context.Context {
+	return context.WithValue(ctx, retryUnsafeKey{}, true)
+}
+
+// idempotent 判断请求是否可以重试：幂等方法，或调用方通过 WithRetryUnsafe 明确允许
+func idempotent(ctx context.Context, method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	ok, _ := ctx.Value(retryUnsafeKey{}).(bool)
+	return ok
+}
+
+// noRetryAfter 响应没有可用的 Retry-After，按退避策略等待
+const noRetryAfter time.Duration = -1
+
+// Do 发送请求并返回响应体
+// 非 2xx 返回 *HTTPError；网络错误、429 和 5xx 按重试策略重试，优先使用服务端的 Retry-After。
+// POST、PATCH 等非幂等方法默认不重试，需要时用 WithRetryUnsafe 按请求开启
+func (c *Client) Do(ctx context.Context, method, rawURL string, headers, params map[string]string, data []byte) ([]byte, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if len(params) > 0 {
+		q := u.Query()
+		for key, value := range params {
+			q.Add(key, value)
+		}
+		u.RawQuery = q.Encode()
+	}
+	limiter := c.limiterFor(u.Host)
+	retry := idempotent(ctx, method)
+
+	for attempt := 0; ; attempt++ {
+		if limiter != nil {
+			if err := limiter.Wait(ctx); err != nil {
+				return nil, err
+			}
+		}
+
+		body, wait, err := c.do(ctx, method, u.String(), headers, data)
+		if err == nil {
+			return body, nil
+		}
+		if !retry || !c.retryable(ctx, err) || attempt >= c.retry.MaxRetries {
+			return nil, err
+		}
+
+		// Retry-After: 0 表示立即重试
+		if wait == noRetryAfter {
+			wait = c.retry.delay(attempt)
+		}
+		c.logRetry(ctx, method, u, attempt, wait, err)
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, fmt.Errorf("%w (last error: %v)", ctx.Err(), err)
+		case <-timer.C:
+		}
+	}
+}
+
+// logRetry 输出一条重试日志，与请求日志使用同一个 logger
+// 不记录查询参数和响应体，网络错误只记录底层原因，避免 url.Error 带出完整 URL
+func (c *Client) logRetry(ctx context.Context, method string, u *url.URL, attempt int, wait time.Duration, err error) {
+	if c.logger == nil {
+		return
+	}
+	attrs := []slog.Attr{
+		slog.String("method", method),
+		slog.String("host", u.Host),
+		slog.String("path", u.Path),
+		slog.Int("attempt", attempt+1),
+		slog.Duration("wait", wait),
+	}
+	if id := TraceIDFrom(ctx); id != "" {
+		attrs = append(attrs, slog.String("trace_id", id))
+	}
+	var httpErr *HTTPError
+	var urlErr *url.Error
+	switch {
+	case errors.As(err, &httpErr):
+		attrs = append(attrs, slog.Int("status", httpErr.Status))
+	case errors.As(err, &urlErr):
+		attrs = append(attrs, slog.String("error", urlErr.Err.Error()))
+	default:
+		attrs = append(attrs, slog.String("error", err.Error()))
+	}
+	c.logger.LogAttrs(ctx, slog.LevelWarn, "http request retry", attrs...)
+}
+
+// do 发送一次请求，返回响应体和服务端要求的重试等待时间，没有要求时为 noRetryAfter
+func (c *Client) do(ctx context.Context, method, rawURL string, headers map[string]string, data []byte) ([]byte, time.Duration, error) {
+	var reader io.Reader
+	if data != nil {
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, reader)
+	if err != nil {
+		return nil, noRetryAfter, err
+	}
+	if method == http.MethodPost {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, noRetryAfter, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, noRetryAfter, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		httpErr := &HTTPError{
+			Method: method,
+			URL:    stripQuery(req.URL),
+			Status: resp.StatusCode,
+			Body:   body,
+			Header: resp.Header,
+		}
+		return nil, c.retryAfter(resp.Header), httpErr
+	}
+	return body, 0, nil
+}
+
+// retryable 判断错误是否值得重试，ctx 已结束时不重试
+func (c *Client) retryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.Retryable()
+	}
+	if errors.Is(err, ErrNoInteraction) {
+		return false
+	}
+	// 其余为连接、超时等网络错误
+	return true
+}
+
+// retryAfter 解析 Retry-After（秒数或 HTTP 日期），超过上限时按上限等待
+// 没有该头或无法解析时返回 noRetryAfter；0 和已过去的日期表示立即重试
+func (c *Client) retryAfter(header http.Header) time.Duration {
+	v := header.Get("Retry-After")
+	if v == "" {
+		return noRetryAfter
+	}
+
+	var d time.Duration
+	if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+		d = time.Duration(secs) * time.Second
+	} else if t, err := http.ParseTime(v); err == nil {
+		d = time.Until(t)
+	} else {
+		return noRetryAfter
+	}
+	if d < 0 {
+		return 0
+	}
+	if c.retry.MaxRetryAfter > 0 && d > c.retry.MaxRetryAfter {
+		return c.retry.MaxRetryAfter
+	}
+	return d
+}
+
+// stripQuery 去掉查询参数，避免 access_token 等出现在日志和错误里
+func stripQuery(u *url.URL) string {
+	return u.Scheme + "://" + u.Host + u.Path
+}
@@ -0,0 +1,344 @@
+package http_request
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var testRetry = RetryPolicy{
+	MaxRetries:    3,
+	Initial:       time.Millisecond,
+	Max:           5 * time.Millisecond,
+	Multiplier:    2,
+	MaxRetryAfter: 10 * time.Millisecond,
+}
+
+func TestClientRetry(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch calls.Add(1) {
+		case 1:
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+		case 2:
+			w.WriteHeader(http.StatusBadGateway)
+		default:
+			w.Write([]byte(`{"ok":true,"q":"` + r.URL.Query().Get("q") + `"}`))
+		}
+	}))
+	defer srv.Close()
+
+	c := NewClient(Config{Retry: &testRetry})
+	body, err := c.Get(context.Background(), srv.URL, nil, map[string]string{"q": "x"})
+	if err != nil {
+		t.Fatalf("请求失败: %v", err)
+	}
+	if string(body) != `{"ok":true,"q":"x"}` {
+		t.Fatalf("响应不符合预期: %s", body)
+	}
+	if calls.Load() != 3 {
+		t.Fatalf("期望请求 3 次，实际 %d", calls.Load())
+	}
+}
+
+func TestClientHTTPError(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error":"not found"}`))
+	}))
+	defer srv.Close()
+
+	c := NewClient(Config{Retry: &testRetry})
+	_, err := c.Post(context.Background(), srv.URL+"/reports", nil, map[string]string{"access_token": "secret"}, []byte(`{}`))
+
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) {
+		t.Fatalf("期望 *HTTPError，实际 %v", err)
+	}
+	if httpErr.Status != http.StatusNotFound || string(httpErr.Body) != `{"error":"not found"}` {
+		t.Fatalf("HTTPError 内容不符合预期: %+v", httpErr)
+	}
+	if calls.Load() != 1 {
+		t.Fatalf("4xx 不应重试，实际请求 %d 次", calls.Load())
+	}
+	if httpErr.URL != srv.URL+"/reports" {
+		t.Fatalf("错误中的 URL 不应包含查询参数: %s", httpErr.URL)
+	}
+}
+
+func TestClientGivesUp(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	c := NewClient(Config{Retry: &testRetry})
+	_, err := c.Get(context.Background(), srv.URL, nil, nil)
+
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) || httpErr.Status != http.StatusServiceUnavailable {
+		t.Fatalf("期望 503 HTTPError，实际 %v", err)
+	}
+	if calls.Load() != int32(testRetry.MaxRetries+1) {
+		t.Fatalf("期望请求 %d 次，实际 %d", testRetry.MaxRetries+1, calls.Load())
+	}
+}
+
+func TestTokenBucket(t *testing.T) {
+	b := NewTokenBucket(100, 2)
+	ctx := context.Background()
+
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		if err := b.Wait(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 满桶 2 个令牌立即可用，后 2 个需要按 100/s 补充
+	if elapsed := time.Since(start); elapsed < 15*time.Millisecond {
+		t.Fatalf("令牌桶没有限流，耗时 %v", elapsed)
+	}
+
+	empty := NewTokenBucket(0, 1)
+	_ = empty.Wait(ctx)
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if err := empty.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("期望等待超时，实际 %v", err)
+	}
+}
+
+func TestCassetteRecordReplay(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"page":"` + r.URL.Query().Get("page") + `","access_token":"live-secret"}`))
+	}))
+
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	rec, err := NewCassette(path, ModeRecord)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewClient(Config{Transport: rec, Retry: &testRetry})
+	headers := map[string]string{"Authorization": "Bearer live-secret"}
+	for _, page := range []string{"1", "2"} {
+		body, err := c.Get(context.Background(), srv.URL+"/items", headers, map[string]string{"page": page, "access_token": "live-secret"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(body), "live-secret") {
+			t.Fatalf("录制时调用方应拿到真实响应: %s", body)
+		}
+	}
+	if err := rec.Save(); err != nil {
+		t.Fatal(err)
+	}
+	srv.Close()
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(b), "live-secret") {
+		t.Fatalf("磁带中包含未脱敏的密钥: %s", b)
+	}
+
+	// 服务已关闭，回放不访问网络，且使用不同的密钥也能匹配
+	replay, err := NewCassette(path, ModeReplay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c = NewClient(Config{Transport: replay, Retry: &testRetry})
+	headers = map[string]string{"Authorization": "Bearer other"}
+	for _, page := range []string{"1", "2"} {
+		body, err := c.Get(context.Background(), srv.URL+"/items", headers, map[string]string{"access_token": "other", "page": page})
+		if err != nil {
+			t.Fatalf("回放失败: %v", err)
+		}
+		if !strings.Contains(string(body), `"page":"`+page+`"`) {
+			t.Fatalf("回放响应不符合预期: %s", body)
+		}
+	}
+	if len(replay.Unused()) != 0 {
+		t.Fatalf("仍有未使用的录制: %v", replay.Unused())
+	}
+
+	_, err = c.Get(context.Background(), srv.URL+"/items", nil, map[string]string{"page": "3"})
+	if !errors.Is(err, ErrNoInteraction) {
+		t.Fatalf("期望 ErrNoInteraction，实际 %v", err)
+	}
+	if _, err := NewCassette(filepath.Join(t.TempDir(), "missing.json"), ModeReplay); !errors.Is(err, ErrCassetteNotFound) {
+		t.Fatalf("期望 ErrCassetteNotFound，实际 %v", err)
+	}
+}
+
+func TestLoggingTransport(t *testing.T) {
+	resp := `{"access_token":"live-secret","refresh_token":"live-refresh","email":"a@b.com"}`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(resp))
+	}))
+	defer srv.Close()
+
+	var buf bytes.Buffer
+	c := NewClient(Config{Retry: &testRetry, Log: &LogConfig{
+		Logger:     slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})),
+		Level:      slog.LevelInfo,
+		Body:       true,
+		BodyFields: []string{"email"},
+	}})
+	ctx := WithTraceID(context.Background(), "1-acc")
+	headers := map[string]string{"Authorization": "Bearer live-secret", "Cookie": "sid=live-secret"}
+	params := map[string]string{"access_token": "live-secret", "page": "2"}
+	if _, err := c.Post(ctx, srv.URL+"/oauth/token", headers, params, []byte(`{"refresh_token":"live-refresh"}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	out := buf.String()
+	for _, secret := range []string{"live-secret", "live-refresh", "a@b.com"} {
+		if strings.Contains(out, secret) {
+			t.Fatalf("日志中包含未脱敏的内容 %q: %s", secret, out)
+		}
+	}
+	var entry map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("期望一条 JSON 日志: %v %s", err, out)
+	}
+	if entry["method"] != "POST" || entry["path"] != "/oauth/token" || entry["trace_id"] != "1-acc" ||
+		entry["status"] != float64(200) || entry["bytes"] != float64(len(resp)) || entry["query"] != "access_token=REDACTED&page=2" {
+		t.Fatalf("日志字段不符合预期: %s", out)
+	}
+}
+
+func TestLoggingTransportTruncate(t *testing.T) {
+	// 敏感值跨过 maxLoggedBody，先截断再脱敏会露出前半段
+	secret := strings.Repeat("s", 100)
+	resp := `{"pad":"` + strings.Repeat("x", maxLoggedBody-30) + `","access_token":"` + secret + `"}`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(resp))
+	}))
+	defer srv.Close()
+
+	var buf bytes.Buffer
+	c := NewClient(Config{Retry: &testRetry, Log: &LogConfig{
+		Logger: slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})),
+		Level:  slog.LevelInfo,
+		Body:   true,
+	}})
+	if _, err := c.Post(context.Background(), srv.URL, nil, nil, []byte(resp)); err != nil {
+		t.Fatal(err)
+	}
+	var entry map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("期望一条 JSON 日志: %v %s", err, buf.String())
+	}
+	for _, field := range []string{"request_body", "response_body"} {
+		body, _ := entry[field].(string)
+		if len(body) > maxLoggedBody || strings.Contains(body, "sss") {
+			t.Fatalf("%s 截断前应已脱敏: %s", field, body)
+		}
+	}
+
+	// 已被截断的 JSON 末尾的敏感值也要替换
+	if got := NewRedactor().Body(`{"a":1,"refresh_token":"live-ref`); got != `{"a":1,"refresh_token":"REDACTED` {
+		t.Fatalf("末尾未闭合的敏感值未脱敏: %s", got)
+	}
+}
+
+func TestClientRetryLog(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"access_token":"live-secret"}`))
+			return
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	var buf bytes.Buffer
+	c := NewClient(Config{Retry: &testRetry, Log: &LogConfig{
+		Logger: slog.New(slog.NewJSONHandler(&buf, nil)),
+		Level:  slog.LevelDebug,
+	}})
+	ctx := WithTraceID(context.Background(), "1-acc")
+	if _, err := c.Get(ctx, srv.URL+"/v5/ads", nil, map[string]string{"access_token": "live-secret"}); err != nil {
+		t.Fatal(err)
+	}
+
+	out := buf.String()
+	if strings.Contains(out, "live-secret") {
+		t.Fatalf("重试日志中包含未脱敏的内容: %s", out)
+	}
+	var retry map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		var entry map[string]any
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("期望 JSON 日志: %v %s", err, line)
+		}
+		if entry["msg"] == "http request retry" {
+			retry = entry
+		}
+	}
+	if retry == nil || retry["path"] != "/v5/ads" || retry["status"] != float64(429) ||
+		retry["attempt"] != float64(1) || retry["trace_id"] != "1-acc" {
+		t.Fatalf("重试日志字段不符合预期: %s", out)
+	}
+}
+
+func TestClientRetryMethods(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	// 较大的退避时间，Retry-After: 0 必须立即重试而不是按退避等待
+	slow := testRetry
+	slow.Initial, slow.Max = time.Minute, time.Minute
+	c := NewClient(Config{Retry: &slow})
+
+	// 默认不重试 POST
+	var httpErr *HTTPError
+	_, err := c.Post(context.Background(), srv.URL, nil, nil, []byte(`{}`))
+	if !errors.As(err, &httpErr) || httpErr.Status != http.StatusTooManyRequests || calls.Load() != 1 {
+		t.Fatalf("POST 默认不应重试，实际 %v，请求 %d 次", err, calls.Load())
+	}
+
+	// 调用方声明可以重试
+	calls.Store(0)
+	start := time.Now()
+	if _, err := c.Post(WithRetryUnsafe(context.Background()), srv.URL, nil, nil, []byte(`{}`)); err != nil {
+		t.Fatal(err)
+	}
+	if calls.Load() != 2 {
+		t.Fatalf("期望请求 2 次，实际 %d", calls.Load())
+	}
+
+	calls.Store(0)
+	if _, err := c.Get(context.Background(), srv.URL, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if calls.Load() != 2 || time.Since(start) > 10*time.Second {
+		t.Fatalf("Retry-After: 0 应立即重试，请求 %d 次，耗时 %v", calls.Load(), time.Since(start))
+	}
+}
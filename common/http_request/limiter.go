@@ -0,0 +1,76 @@
+package http_request
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Limiter 请求限流器，Wait 阻塞到允许发送下一个请求或 ctx 结束
+type Limiter interface {
+	Wait(ctx context.Context) error
+}
+
+// TokenBucket 令牌桶限流器，按 rate 每秒补充令牌，最多累积 burst 个
+type TokenBucket struct {
+	rate  float64
+	burst float64
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// NewTokenBucket 创建令牌桶，初始为满桶
+func NewTokenBucket(rate float64, burst int) *TokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &TokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// Wait 取走一个令牌，令牌不足时等待补充
+func (b *TokenBucket) Wait(ctx context.Context) error {
+	for {
+		wait := b.reserve()
+		if wait <= 0 {
+			return nil
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// reserve 有令牌时取走并返回 0，否则返回还需等待的时间
+func (b *TokenBucket) reserve() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0
+	}
+	if b.rate <= 0 {
+		// 速率为 0 时不再补充令牌，每秒检查一次以便响应 ctx
+		return time.Second
+	}
+	return time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+}
@@ -0,0 +1,192 @@
+package http_request
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LogEnv 控制共享客户端的请求日志：off（默认）、info、debug
+// debug 额外记录脱敏后的请求头、请求体和响应体
+const LogEnv = "HTTP_LOG"
+
+// maxLoggedBody debug 日志中请求体/响应体保留的最大字节数
+const maxLoggedBody = 2048
+
+// maxCapturedBody 响应体先按此上限完整捕获，脱敏后再截断到 maxLoggedBody，避免截断处露出半个敏感值
+const maxCapturedBody = 1 << 20
+
+// LogConfig 请求日志配置
+type LogConfig struct {
+	Logger     *slog.Logger // 为空时使用 slog.Default()
+	Level      slog.Level   // 成功请求的日志级别，失败和非 2xx 固定为 Warn
+	Body       bool         // 是否记录脱敏后的请求头、请求体和响应体
+	BodyFields []string     // 在默认敏感字段之外需要脱敏的 body 字段
+}
+
+// LogConfigFromEnv 从 HTTP_LOG 读取配置，未开启时返回 nil
+func LogConfigFromEnv() *LogConfig {
+	switch strings.ToLower(os.Getenv(LogEnv)) {
+	case "info":
+		return &LogConfig{Level: slog.LevelInfo}
+	case "debug":
+		return &LogConfig{Level: slog.LevelDebug, Body: true}
+	default:
+		return nil
+	}
+}
+
+type traceIDKey struct{}
+
+// WithTraceID 把 trace id 放入 ctx，请求日志会带上该字段
+func WithTraceID(ctx context.Context, traceID string) context.Context {
+	return context.WithValue(ctx, traceIDKey{}, traceID)
+}
+
+// TraceIDFrom 取出 ctx 中的 trace id，没有时返回空字符串
+func TraceIDFrom(ctx context.Context) string {
+	id, _ := ctx.Value(traceIDKey{}).(string)
+	return id
+}
+
+// LoggingTransport 为每次请求输出一条 slog 结构化日志的 http.RoundTripper
+// 日志包含方法、host、路径、状态码、耗时、响应字节数和 trace id，查询参数和敏感字段已脱敏
+type LoggingTransport struct {
+	next     http.RoundTripper
+	logger   *slog.Logger
+	level    slog.Level
+	body     bool
+	redactor *Redactor
+}
+
+// NewLoggingTransport 包装 next，next 为空时使用 http.DefaultTransport
+func NewLoggingTransport(next http.RoundTripper, cfg LogConfig) *LoggingTransport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	logger := cfg.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &LoggingTransport{
+		next:     next,
+		logger:   logger,
+		level:    cfg.Level,
+		body:     cfg.Body,
+		redactor: NewRedactor(cfg.BodyFields...),
+	}
+}
+
+// RoundTrip 实现 http.RoundTripper，响应的日志在响应体读完或关闭时输出
+func (t *LoggingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	attrs := []slog.Attr{
+		slog.String("method", req.Method),
+		slog.String("host", req.URL.Host),
+		slog.String("path", req.URL.Path),
+	}
+	if req.URL.RawQuery != "" {
+		attrs = append(attrs, slog.String("query", t.redactor.Values(req.URL.Query()).Encode()))
+	}
+	if id := TraceIDFrom(ctx); id != "" {
+		attrs = append(attrs, slog.String("trace_id", id))
+	}
+	if t.body {
+		attrs = append(attrs, slog.Any("request_header", t.redactor.Header(req.Header)))
+		if body := t.requestBody(req); body != "" {
+			attrs = append(attrs, slog.String("request_body", body))
+		}
+	}
+
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		attrs = append(attrs, slog.Duration("latency", time.Since(start)), slog.String("error", err.Error()))
+		t.logger.LogAttrs(ctx, slog.LevelWarn, "http request failed", attrs...)
+		return nil, err
+	}
+
+	lb := &loggedBody{ReadCloser: resp.Body, t: t, ctx: ctx, start: start, status: resp.StatusCode, attrs: attrs}
+	if t.body {
+		lb.captured = &bytes.Buffer{}
+	}
+	resp.Body = lb
+	return resp, nil
+}
+
+// requestBody 通过 GetBody 读取请求体副本，不影响实际发送
+func (t *LoggingTransport) requestBody(req *http.Request) string {
+	if req.GetBody == nil {
+		return ""
+	}
+	rc, err := req.GetBody()
+	if err != nil {
+		return ""
+	}
+	defer rc.Close()
+	b, _ := io.ReadAll(io.LimitReader(rc, maxCapturedBody))
+	return t.loggedBody(string(b))
+}
+
+// loggedBody 先对完整内容脱敏再截断，截断不会产生未脱敏的片段
+func (t *LoggingTransport) loggedBody(body string) string {
+	body = t.redactor.Body(body)
+	if len(body) > maxLoggedBody {
+		body = body[:maxLoggedBody]
+	}
+	return body
+}
+
+// loggedBody 统计响应字节数，读到 EOF 或关闭时输出一次日志
+type loggedBody struct {
+	io.ReadCloser
+	t        *LoggingTransport
+	ctx      context.Context
+	start    time.Time
+	status   int
+	attrs    []slog.Attr
+	bytes    int64
+	captured *bytes.Buffer
+	once     sync.Once
+}
+
+func (b *loggedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.bytes += int64(n)
+	if b.captured != nil && b.captured.Len() < maxCapturedBody {
+		b.captured.Write(p[:min(n, maxCapturedBody-b.captured.Len())])
+	}
+	if err == io.EOF {
+		b.log()
+	}
+	return n, err
+}
+
+func (b *loggedBody) Close() error {
+	b.log()
+	return b.ReadCloser.Close()
+}
+
+func (b *loggedBody) log() {
+	b.once.Do(func() {
+		attrs := append(b.attrs,
+			slog.Int("status", b.status),
+			slog.Duration("latency", time.Since(b.start)),
+			slog.Int64("bytes", b.bytes),
+		)
+		if b.captured != nil {
+			attrs = append(attrs, slog.String("response_body", b.t.loggedBody(b.captured.String())))
+		}
+		level := b.t.level
+		if b.status < 200 || b.status >= 300 {
+			level = slog.LevelWarn
+		}
+		b.t.logger.LogAttrs(b.ctx, level, "http request", attrs...)
+	})
+}
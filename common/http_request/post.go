@@ -4,10 +4,17 @@ import (
 	"bytes"
 	"io"
 	"net/http"
+	"time"
 )
 
+// legacyClient Get/Post 共用的客户端，只加了兜底超时，避免连接挂起时任务永远不结束
+var legacyClient = &http.Client{Timeout: 5 * time.Minute}
+
+// Post 发送 POST 请求，不检查状态码也不重试
+//
+// Deprecated: 使用 Client.Post，非 2xx 会返回 *HTTPError 并按策略重试
 func Post(url string, headers, params map[string]string, data []byte) ([]byte, error) {
-	client := &http.Client{}
+	client := legacyClient
 	req, err := http.NewRequest("POST", url, bytes.NewBuffer(data))
 	if err != nil {
 		return nil, err
@@ -45,8 +52,11 @@ func Post(url string, headers, params map[string]string, data []byte) ([]byte, e
 	return body, nil
 }
 
+// Get 发送 GET 请求，不检查状态码也不重试
+//
+// Deprecated: 使用 Client.Get，非 2xx 会返回 *HTTPError 并按策略重试
 func Get(url string, headers, params map[string]string) ([]byte, error) {
-	client := &http.Client{}
+	client := legacyClient
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
 		return nil, err
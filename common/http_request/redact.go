@@ -0,0 +1,105 @@
+package http_request
+
+import (
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// redacted 替换敏感信息的占位符
+const redacted = "REDACTED"
+
+// sensitiveHeaders 需要替换值的请求/响应头
+var sensitiveHeaders = []string{"Authorization", "Cookie", "Set-Cookie", "X-Api-Key", "X-Access-Token"}
+
+// sensitiveKeys 查询参数、表单和 JSON 字段名包含这些词时替换值，token 同时覆盖 access_token 和 refresh_token
+var sensitiveKeys = []string{"token", "secret", "password", "api_key", "apikey", "client_id", "credential"}
+
+// Redactor 对 URL、请求头和请求体脱敏，录制磁带和请求日志共用
+type Redactor struct {
+	keys []string
+	json *regexp.Regexp // 匹配 JSON 中的 "key": "value"，只处理字符串值
+	tail *regexp.Regexp // 匹配被截断在末尾、没有结束引号的 "key": "valu
+}
+
+// NewRedactor 在默认敏感字段之外追加 fields，字段名包含其中任一词（不区分大小写）即脱敏
+func NewRedactor(fields ...string) *Redactor {
+	keys := append([]string{}, sensitiveKeys...)
+	for _, f := range fields {
+		if f = strings.ToLower(strings.TrimSpace(f)); f != "" {
+			keys = append(keys, f)
+		}
+	}
+	quoted := make([]string, len(keys))
+	for i, k := range keys {
+		quoted[i] = regexp.QuoteMeta(k)
+	}
+	key := `"([A-Za-z0-9_.-]*(?i:` + strings.Join(quoted, "|") + `)[A-Za-z0-9_.-]*)"\s*:\s*"`
+	return &Redactor{
+		keys: keys,
+		json: regexp.MustCompile(key + `[^"]*"`),
+		tail: regexp.MustCompile(key + `[^"]*$`),
+	}
+}
+
+var defaultRedactor = NewRedactor()
+
+func (r *Redactor) sensitive(key string) bool {
+	key = strings.ToLower(key)
+	for _, s := range r.keys {
+		if strings.Contains(key, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// Values 替换敏感参数的值，会修改传入的 values
+func (r *Redactor) Values(values url.Values) url.Values {
+	for key := range values {
+		if r.sensitive(key) {
+			values[key] = []string{redacted}
+		}
+	}
+	return values
+}
+
+// URL 返回去掉用户信息、敏感参数已替换的 URL
+func (r *Redactor) URL(u *url.URL) string {
+	cp := *u
+	cp.User = nil
+	cp.RawQuery = r.Values(cp.Query()).Encode()
+	return cp.String()
+}
+
+// Header 返回敏感头已替换的副本，空头返回 nil
+func (r *Redactor) Header(h http.Header) http.Header {
+	if len(h) == 0 {
+		return nil
+	}
+	cp := h.Clone()
+	for _, key := range sensitiveHeaders {
+		if cp.Get(key) != "" {
+			cp.Set(key, redacted)
+		}
+	}
+	return cp
+}
+
+// Body 脱敏 JSON 字段和表单参数，其他格式原样返回
+// 被截断的 JSON 末尾没有结束引号的敏感值也会替换
+func (r *Redactor) Body(body string) string {
+	if body == "" {
+		return body
+	}
+	trimmed := strings.TrimSpace(body)
+	if strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[") {
+		body = r.json.ReplaceAllString(body, `"$1":"`+redacted+`"`)
+		return r.tail.ReplaceAllString(body, `"$1":"`+redacted)
+	}
+	if values, err := url.ParseQuery(body); err == nil && strings.Contains(body, "=") {
+		return r.Values(values).Encode()
+	}
+	return body
+}
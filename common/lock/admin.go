@@ -0,0 +1,69 @@
+package lock
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// NewAdminHandler 创建锁管理接口，供各个服务挂载到自己的路由上
+// token 不为空时要求请求头携带 Authorization: Bearer <token>
+//
+//	GET    /locks?prefix=knocommerce:   列出锁
+//	GET    /locks/metrics?prefix=...    锁数量与持有时长统计
+//	DELETE /locks/{key}                 强制释放锁
+//
+// 挂载示例：http.Handle("/admin/", http.StripPrefix("/admin", lock.NewAdminHandler(admin, token)))
+func NewAdminHandler(locker Admin, token string) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /locks", func(w http.ResponseWriter, r *http.Request) {
+		locks, err := locker.ListLocks(r.URL.Query().Get("prefix"))
+		if err != nil {
+			writeAdminJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		writeAdminJSON(w, http.StatusOK, locks)
+	})
+
+	mux.HandleFunc("GET /locks/metrics", func(w http.ResponseWriter, r *http.Request) {
+		locks, err := locker.ListLocks(r.URL.Query().Get("prefix"))
+		if err != nil {
+			writeAdminJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		writeAdminJSON(w, http.StatusOK, ComputeMetrics(locks))
+	})
+
+	mux.HandleFunc("DELETE /locks/{key...}", func(w http.ResponseWriter, r *http.Request) {
+		key := r.PathValue("key")
+		err := locker.ForceUnlock(key)
+		if errors.Is(err, ErrLockNotFound) {
+			writeAdminJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			writeAdminJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		writeAdminJSON(w, http.StatusOK, map[string]string{"key": key, "status": "unlocked"})
+	})
+
+	if token == "" {
+		return mux
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+token {
+			writeAdminJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// writeAdminJSON 输出 JSON 响应
+func writeAdminJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
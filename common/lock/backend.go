@@ -0,0 +1,86 @@
+package lock
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// 支持的锁后端
+const (
+	BackendMySQL  = "mysql"
+	BackendRedis  = "redis"
+	BackendMemory = "memory"
+)
+
+// Config 锁后端配置
+type Config struct {
+	Backend       string // mysql / redis / memory，为空时使用 mysql
+	RedisAddr     string // host:port
+	RedisPassword string
+	RedisDB       int
+}
+
+// ConfigFromEnv 从环境变量读取锁后端配置
+// LOCK_BACKEND 选择后端；Redis 连接沿用 REDIS_HOST / REDIS_PORT / REDIS_PASSWORD / REDIS_DB
+func ConfigFromEnv() Config {
+	db, _ := strconv.Atoi(os.Getenv("REDIS_DB"))
+	return Config{
+		Backend:       getEnv("LOCK_BACKEND", BackendMySQL),
+		RedisAddr:     fmt.Sprintf("%s:%s", getEnv("REDIS_HOST", "localhost"), getEnv("REDIS_PORT", "6379")),
+		RedisPassword: os.Getenv("REDIS_PASSWORD"),
+		RedisDB:       db,
+	}
+}
+
+// New 根据配置创建对应后端的分布式锁实例
+func New(cfg Config) (Locker, error) {
+	switch cfg.Backend {
+	case "", BackendMySQL:
+		return NewMySQLLocker(), nil
+	case BackendRedis:
+		client := redis.NewClient(&redis.Options{
+			Addr:         cfg.RedisAddr,
+			Password:     cfg.RedisPassword,
+			DB:           cfg.RedisDB,
+			DialTimeout:  5 * time.Second,
+			ReadTimeout:  3 * time.Second,
+			WriteTimeout: 3 * time.Second,
+		})
+		return NewRedisLocker(client), nil
+	case BackendMemory:
+		return NewMemoryLocker(), nil
+	default:
+		return nil, fmt.Errorf("unknown lock backend: %s", cfg.Backend)
+	}
+}
+
+// NewRW 根据配置创建读写锁实例，Redis 后端不支持共享/独占模式，返回错误
+func NewRW(cfg Config) (RWLocker, error) {
+	switch cfg.Backend {
+	case "", BackendMySQL:
+		return NewMySQLRWLocker(), nil
+	case BackendMemory:
+		return NewMemoryRWLocker(), nil
+	case BackendRedis:
+		return nil, fmt.Errorf("lock backend %s does not support shared/exclusive locks", cfg.Backend)
+	default:
+		return nil, fmt.Errorf("unknown lock backend: %s", cfg.Backend)
+	}
+}
+
+// NewFromEnv 根据环境变量创建分布式锁实例
+func NewFromEnv() (Locker, error) {
+	return New(ConfigFromEnv())
+}
+
+// getEnv 获取环境变量，如果不存在返回默认值
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
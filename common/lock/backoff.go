@@ -0,0 +1,80 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+)
+
+// Backoff 阻塞获取锁时的指数退避参数
+type Backoff struct {
+	Initial    time.Duration // 首次重试等待时间
+	Max        time.Duration // 单次等待上限
+	Multiplier float64       // 每次重试的放大倍数
+}
+
+// DefaultBackoff 默认退避策略：50ms 起步，每次翻倍，最长 1s
+var DefaultBackoff = Backoff{
+	Initial:    50 * time.Millisecond,
+	Max:        time.Second,
+	Multiplier: 2,
+}
+
+// delay 计算第 attempt 次重试的等待时间
+// 使用 equal jitter：在 [d/2, d] 之间随机，避免多个等待者同时重试
+func (b Backoff) delay(attempt int) time.Duration {
+	d := float64(b.Initial)
+	for i := 0; i < attempt && d < float64(b.Max); i++ {
+		d *= b.Multiplier
+	}
+	if d > float64(b.Max) {
+		d = float64(b.Max)
+	}
+	half := d / 2
+	return time.Duration(half + rand.Float64()*half)
+}
+
+// LockTimeoutError 在 Context 取消或超时前未能获取到锁
+type LockTimeoutError struct {
+	Key    string
+	Waited time.Duration
+	Err    error // context.Canceled 或 context.DeadlineExceeded
+}
+
+func (e *LockTimeoutError) Error() string {
+	return fmt.Sprintf("lock %s not acquired after %v: %v", e.Key, e.Waited.Truncate(time.Millisecond), e.Err)
+}
+
+func (e *LockTimeoutError) Unwrap() error {
+	return e.Err
+}
+
+// lockWithBackoff 反复调用 try 直到获取锁、遇到非占用错误或 ctx 结束
+func lockWithBackoff[T any](ctx context.Context, key string, try func() (T, error)) (T, error) {
+	var zero T
+	start := time.Now()
+	for attempt := 0; ; attempt++ {
+		if err := ctx.Err(); err != nil {
+			return zero, &LockTimeoutError{Key: key, Waited: time.Since(start), Err: err}
+		}
+
+		res, err := try()
+		if err == nil {
+			return res, nil
+		}
+		if !errors.Is(err, ErrLockExists) {
+			return zero, err
+		}
+
+		// 锁被占用，退避后重试
+		timer := time.NewTimer(DefaultBackoff.delay(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return zero, &LockTimeoutError{Key: key, Waited: time.Since(start), Err: ctx.Err()}
+		case <-timer.C:
+		}
+	}
+}
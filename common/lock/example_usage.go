@@ -30,7 +30,7 @@ func basicExample(locker Locker) {
 	fmt.Println("=== 基本使用示例 ===")
 
 	// 获取锁
-	err := locker.TryLock(lockKey, ownerID, lockDuration)
+	_, err := locker.TryLock(lockKey, ownerID, lockDuration)
 	if err != nil {
 		if err == ErrLockExists {
 			fmt.Println("锁已被其他进程持有")
@@ -73,7 +73,7 @@ func advancedExample(locker Locker) {
 	fmt.Println("\n=== 高级使用示例 ===")
 
 	// 阻塞式获取锁
-	err := locker.Lock(lockKey, ownerID, 5*time.Minute)
+	_, err := locker.Lock(lockKey, ownerID, 5*time.Minute)
 	if err != nil {
 		log.Printf("获取锁失败: %v", err)
 		return
@@ -113,14 +113,14 @@ func concurrencyExample(locker Locker) {
 		go func(workerID int) {
 			ownerID := fmt.Sprintf("worker-%d", workerID)
 
-			err := locker.TryLock(lockKey, ownerID, 3*time.Second)
+			_, err := locker.TryLock(lockKey, ownerID, 3*time.Second)
 			if err != nil {
 				if err == ErrLockExists {
 					fmt.Printf("Worker %d: 锁被占用，等待...\n", workerID)
 
 					// 等待并重试
 					time.Sleep(100 * time.Millisecond)
-					err = locker.Lock(lockKey, ownerID, 3*time.Second)
+					_, err = locker.Lock(lockKey, ownerID, 3*time.Second)
 					if err != nil {
 						fmt.Printf("Worker %d: 最终获取锁失败: %v\n", workerID, err)
 						return
@@ -165,7 +165,7 @@ func autoRenewExample(locker Locker) {
 	initialDuration := 10 * time.Second
 
 	// 获取初始锁
-	err := locker.TryLock(lockKey, ownerID, initialDuration)
+	_, err := locker.TryLock(lockKey, ownerID, initialDuration)
 	if err != nil {
 		log.Printf("获取锁失败: %v", err)
 		return
@@ -0,0 +1,114 @@
+package lock
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// LockToken fencing token 计数表，每个锁键一行，锁被删除后计数依然保留
+type LockToken struct {
+	LockKey   string    `gorm:"primaryKey;column:lock_key;size:255" json:"lock_key"`
+	Token     int64     `gorm:"column:token;not null" json:"token"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// TableName 指定表名
+func (LockToken) TableName() string {
+	return "platform_offline.distributed_lock_tokens"
+}
+
+// nextFencingToken 在事务内将锁键的计数加一并返回新值
+// 行锁会持有到事务结束，同一个键的分配因此是串行的
+func nextFencingToken(tx *gorm.DB, key string) (int64, error) {
+	err := tx.Exec(
+		"INSERT INTO platform_offline.distributed_lock_tokens (lock_key, token) VALUES (?, 1) ON DUPLICATE KEY UPDATE token = token + 1",
+		key,
+	).Error
+	if err != nil {
+		return 0, err
+	}
+
+	var t LockToken
+	if err := tx.Where("lock_key = ?", key).First(&t).Error; err != nil {
+		return 0, err
+	}
+	return t.Token, nil
+}
+
+// Fence 写入方持有的 fencing 凭证，只有 MySQL 后端分配的 token 可以校验
+// Check 必须在写入所在的事务中调用，且事务与锁使用同一个 MySQL 实例：
+// 校验时对计数行加锁，新的持有者分配 token 时会等待写入事务结束，校验和写入之间锁不会易主。
+// 写入的库与锁不在同一个实例时使用 Advance
+type Fence struct {
+	Key   string
+	Token int64
+
+	// Durable token 由 MySQL 后端分配，计数永久保存、严格递增。
+	// Redis 的计数会随 meta 过期重置，内存后端每个进程各自计数，这两种 token 无法在数据库中校验
+	Durable bool
+}
+
+// FenceMark 写入库中每个锁键见过的最大 token（水位），由 Advance 维护
+// 表结构：lock_key VARCHAR(255) PRIMARY KEY, token BIGINT NOT NULL, updated_at DATETIME
+type FenceMark struct {
+	LockKey   string    `gorm:"primaryKey;column:lock_key;size:255" json:"lock_key"`
+	Token     int64     `gorm:"column:token;not null" json:"token"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// Check 在事务 tx 中校验 token 是否仍是最新的，过期时返回 ErrStaleToken
+func (f Fence) Check(tx *gorm.DB) error {
+	var t LockToken
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("lock_key = ?", f.Key).
+		Take(&t).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("fence %s: no fencing token allocated, lock is not held through the mysql backend", f.Key)
+	}
+	if err != nil {
+		return fmt.Errorf("fence %s: %w", f.Key, err)
+	}
+	if f.Token < t.Token {
+		return fmt.Errorf("%w: key=%s token=%d current=%d", ErrStaleToken, f.Key, f.Token, t.Token)
+	}
+	return nil
+}
+
+// Advance 在写入库的事务 tx 中按水位校验 token，table 为 FenceMark 所在的表
+// 水位高于 token 说明新的持有者已经写入过，返回 ErrStaleToken；否则把水位推进到 token。
+// 水位行加锁到事务结束，校验和写入之间不会有其他持有者插入写入。
+// 新持有者第一次写入之前，旧持有者的写入仍会成功，只能保证新持有者写入后旧的写入被拒绝
+func (f Fence) Advance(tx *gorm.DB, table string) error {
+	if !f.Durable {
+		return fmt.Errorf("fence %s: only tokens from the mysql backend can be enforced", f.Key)
+	}
+
+	// 先插入水位为 0 的占位行，并发的第一次写入因此都会落到下面的行锁上
+	err := tx.Table(table).Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&FenceMark{LockKey: f.Key}).Error
+	if err != nil {
+		return fmt.Errorf("fence %s: %w", f.Key, err)
+	}
+
+	var m FenceMark
+	err = tx.Table(table).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("lock_key = ?", f.Key).
+		Take(&m).Error
+	switch {
+	case err != nil:
+	case f.Token < m.Token:
+		return fmt.Errorf("%w: key=%s token=%d current=%d", ErrStaleToken, f.Key, f.Token, m.Token)
+	case f.Token > m.Token:
+		err = tx.Table(table).Where("lock_key = ?", f.Key).
+			Updates(map[string]interface{}{"token": f.Token, "updated_at": time.Now()}).Error
+	}
+	if err != nil {
+		return fmt.Errorf("fence %s: %w", f.Key, err)
+	}
+	return nil
+}
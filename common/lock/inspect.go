@@ -0,0 +1,70 @@
+package lock
+
+import (
+	"strings"
+	"time"
+)
+
+// LockInfo 锁的快照信息，用于排查长时间未释放的锁
+type LockInfo struct {
+	Key        string    `json:"key"`
+	OwnerID    string    `json:"owner_id"`
+	Token      int64     `json:"token"`
+	AcquiredAt time.Time `json:"acquired_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Expired    bool      `json:"expired"`
+	AgeSeconds float64   `json:"age_seconds"`
+}
+
+// LockMetrics 一组锁的统计指标
+type LockMetrics struct {
+	Total         int     `json:"total"`
+	Expired       int     `json:"expired"`
+	MaxAgeSeconds float64 `json:"max_age_seconds"`
+	AvgAgeSeconds float64 `json:"avg_age_seconds"`
+	OldestKey     string  `json:"oldest_key"`
+}
+
+// newLockInfo 根据持有时间计算锁的年龄和过期状态
+func newLockInfo(key, ownerID string, token int64, acquiredAt, expiresAt, now time.Time) LockInfo {
+	info := LockInfo{
+		Key:        key,
+		OwnerID:    ownerID,
+		Token:      token,
+		AcquiredAt: acquiredAt,
+		ExpiresAt:  expiresAt,
+		Expired:    !expiresAt.After(now),
+	}
+	if !acquiredAt.IsZero() {
+		info.AgeSeconds = now.Sub(acquiredAt).Seconds()
+	}
+	return info
+}
+
+// ComputeMetrics 计算锁的数量、过期数量和持有时长
+func ComputeMetrics(locks []LockInfo) LockMetrics {
+	m := LockMetrics{Total: len(locks)}
+	if len(locks) == 0 {
+		return m
+	}
+
+	var sum float64
+	for _, l := range locks {
+		if l.Expired {
+			m.Expired++
+		}
+		sum += l.AgeSeconds
+		if l.AgeSeconds > m.MaxAgeSeconds || m.OldestKey == "" {
+			m.MaxAgeSeconds = l.AgeSeconds
+			m.OldestKey = l.Key
+		}
+	}
+	m.AvgAgeSeconds = sum / float64(len(locks))
+	return m
+}
+
+// escapeLike 转义 LIKE 模式中的通配符
+func escapeLike(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return r.Replace(s)
+}
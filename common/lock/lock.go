@@ -1,6 +1,7 @@
 package lock
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"strings"
@@ -16,12 +17,20 @@ CREATE TABLE `distributed_locks` (
 	`lock_key` VARCHAR(255) NOT NULL COMMENT '锁的唯一键',
 	`owner_id` VARCHAR(128) NOT NULL COMMENT '锁持有者的唯一标识',
 	`expires_at` TIMESTAMP NOT NULL COMMENT '锁的过期时间',
+	`fencing_token` BIGINT UNSIGNED NOT NULL DEFAULT 0 COMMENT '获取锁时分配的 fencing token',
 	`created_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 	`updated_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
 	PRIMARY KEY (`id`),
 	UNIQUE KEY `uk_lock_key` (`lock_key`),
 	KEY `idx_expires_at` (`expires_at`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='分布式锁表';
+
+CREATE TABLE `distributed_lock_tokens` (
+	`lock_key` VARCHAR(255) NOT NULL COMMENT '锁的唯一键',
+	`token` BIGINT UNSIGNED NOT NULL DEFAULT 0 COMMENT '最近一次分配的 fencing token',
+	`updated_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+	PRIMARY KEY (`lock_key`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='分布式锁 fencing token 计数表';
 */
 
 // DistributedLock 分布式锁模型
@@ -30,8 +39,10 @@ type DistributedLock struct {
 	LockKey   string    `gorm:"uniqueIndex:uk_lock_key;size:255;not null" json:"lock_key"`
 	OwnerID   string    `gorm:"size:128;not null" json:"owner_id"`
 	ExpiresAt time.Time `gorm:"index:idx_expires_at;not null" json:"expires_at"`
-	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
-	UpdatedAt time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+	// FencingToken 每次新获取锁时单调递增，写入方据此拒绝过期持有者的写入
+	FencingToken int64     `gorm:"column:fencing_token;not null;default:0" json:"fencing_token"`
+	CreatedAt    time.Time `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt    time.Time `gorm:"autoUpdateTime" json:"updated_at"`
 }
 
 // TableName 指定表名
@@ -41,11 +52,11 @@ func (DistributedLock) TableName() string {
 
 // Locker 分布式锁接口
 type Locker interface {
-	// Lock 获取锁，如果获取成功返回 nil，否则返回错误
-	Lock(key, ownerID string, duration time.Duration) error
+	// Lock 获取锁，如果获取成功返回 fencing token，否则返回错误
+	Lock(key, ownerID string, duration time.Duration) (int64, error)
 
-	// TryLock 尝试获取锁，不阻塞，立即返回结果
-	TryLock(key, ownerID string, duration time.Duration) error
+	// TryLock 尝试获取锁，不阻塞，立即返回结果和 fencing token
+	TryLock(key, ownerID string, duration time.Duration) (int64, error)
 
 	// Renew 续期锁，延长锁的过期时间
 	Renew(key, ownerID string, duration time.Duration) error
@@ -63,17 +74,55 @@ type Locker interface {
 	CleanExpiredLocks() (int64, error)
 }
 
+// ContextLocker 支持受 ctx 控制的阻塞加锁，三种后端都实现了该接口
+type ContextLocker interface {
+	Locker
+
+	// LockContext 阻塞获取锁，按指数退避重试，ctx 取消或超时时返回 *LockTimeoutError
+	LockContext(ctx context.Context, key, ownerID string, duration time.Duration) (int64, error)
+}
+
+// Admin 锁的运维操作，供管理接口和排查使用，业务代码只依赖 Locker
+type Admin interface {
+	// CurrentToken 获取锁最近一次分配的 fencing token，从未加锁时返回 0
+	CurrentToken(key string) (int64, error)
+
+	// ListLocks 列出键以 prefix 开头的锁（包括已过期但未清理的锁）
+	ListLocks(prefix string) ([]LockInfo, error)
+
+	// ForceUnlock 忽略持有者强制释放锁，用于清理异常退出后遗留的锁
+	ForceUnlock(key string) error
+}
+
+// LockContext 阻塞获取锁，locker 实现了 ContextLocker 时直接调用，否则按退避策略重试 TryLock
+func LockContext(ctx context.Context, locker Locker, key, ownerID string, duration time.Duration) (int64, error) {
+	if cl, ok := locker.(ContextLocker); ok {
+		return cl.LockContext(ctx, key, ownerID, duration)
+	}
+	if duration <= 0 {
+		return 0, ErrInvalidDuration
+	}
+	return lockWithBackoff(ctx, key, func() (int64, error) {
+		return locker.TryLock(key, ownerID, duration)
+	})
+}
+
 // MySQLLocker MySQL实现的分布式锁
 type MySQLLocker struct {
 	db *gorm.DB
 }
 
-// NewMySQLLocker 创建新的MySQL分布式锁实例
-func NewMySQLLocker() Locker {
+// NewMySQLLocker 创建新的MySQL分布式锁实例，使用 platform_db 连接
+func NewMySQLLocker() *MySQLLocker {
 	db := platform_db.GetDB()
 	// 自动迁移表结构
 	//db.AutoMigrate(&DistributedLock{})
 
+	return NewMySQLLockerWithDB(db)
+}
+
+// NewMySQLLockerWithDB 使用指定的数据库连接创建MySQL分布式锁实例
+func NewMySQLLockerWithDB(db *gorm.DB) *MySQLLocker {
 	return &MySQLLocker{
 		db: db,
 	}
@@ -86,31 +135,29 @@ var (
 	ErrNotLockOwner    = errors.New("not the owner of this lock")
 	ErrLockFailed      = errors.New("failed to acquire lock")
 	ErrInvalidDuration = errors.New("invalid lock duration")
+	ErrStaleToken      = errors.New("stale fencing token")
 )
 
-// Lock 获取锁（阻塞式，会重试）
-func (m *MySQLLocker) Lock(key, ownerID string, duration time.Duration) error {
+// Lock 获取锁（阻塞式，会一直重试直到成功）
+func (m *MySQLLocker) Lock(key, ownerID string, duration time.Duration) (int64, error) {
+	return m.LockContext(context.Background(), key, ownerID, duration)
+}
+
+// LockContext 获取锁（阻塞式，按退避策略重试，受 ctx 控制）
+func (m *MySQLLocker) LockContext(ctx context.Context, key, ownerID string, duration time.Duration) (int64, error) {
 	if duration <= 0 {
-		return ErrInvalidDuration
+		return 0, ErrInvalidDuration
 	}
 
-	for {
-		err := m.TryLock(key, ownerID, duration)
-		if err == nil {
-			return nil // 成功获取锁
-		}
-		if err != ErrLockExists {
-			return err // 其他错误直接返回
-		}
-		// 锁被占用，等待重试
-		time.Sleep(100 * time.Millisecond)
-	}
+	return lockWithBackoff(ctx, key, func() (int64, error) {
+		return m.TryLock(key, ownerID, duration)
+	})
 }
 
 // TryLock 尝试获取锁（非阻塞）
-func (m *MySQLLocker) TryLock(key, ownerID string, duration time.Duration) error {
+func (m *MySQLLocker) TryLock(key, ownerID string, duration time.Duration) (int64, error) {
 	if duration <= 0 {
-		return ErrInvalidDuration
+		return 0, ErrInvalidDuration
 	}
 
 	now := time.Now()
@@ -125,33 +172,61 @@ func (m *MySQLLocker) TryLock(key, ownerID string, duration time.Duration) error
 	if err == nil {
 		// 锁已存在，检查是否是同一个owner
 		if existingLock.OwnerID == ownerID && existingLock.ExpiresAt.After(now) {
-			// 是同一个owner且锁未过期，则更新过期时间
-			return m.Renew(key, ownerID, duration)
+			// 是同一个owner且锁未过期，则更新过期时间，token 保持不变
+			if err := m.Renew(key, ownerID, duration); err != nil {
+				return 0, err
+			}
+			return existingLock.FencingToken, nil
 		}
 		// 不是同一个owner或者锁已过期但还没清理
-		return ErrLockExists
+		return 0, ErrLockExists
 	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
 		// 数据库错误
-		return fmt.Errorf("database error: %w", err)
+		return 0, fmt.Errorf("database error: %w", err)
 	}
 
-	// 锁不存在，尝试创建新锁
-	lock := &DistributedLock{
-		LockKey:   key,
-		OwnerID:   ownerID,
-		ExpiresAt: expiresAt,
-	}
+	// 锁不存在，在同一事务中分配新 token 并创建新锁
+	// 创建失败时事务回滚，token 不会被白白消耗
+	var token int64
+	err = m.db.Transaction(func(tx *gorm.DB) error {
+		next, err := nextFencingToken(tx, key)
+		if err != nil {
+			return err
+		}
 
-	result := m.db.Create(lock)
-	if result.Error != nil {
-		// 检查是否是唯一键冲突（可能在并发情况下其他进程插入了同样的key）
-		if isUniqueConstraintError(result.Error) {
+		// 读写锁的其他持有者同样占用这个键，与 TryAcquire 一样在 token 行锁下检查
+		var held int64
+		err = tx.Model(&LockHolder{}).
+			Where("lock_key = ? AND owner_id <> ? AND expires_at > ?", key, ownerID, now).
+			Count(&held).Error
+		if err != nil {
+			return err
+		}
+		if held > 0 {
 			return ErrLockExists
 		}
-		return fmt.Errorf("database error: %w", result.Error)
+
+		lock := &DistributedLock{
+			LockKey:      key,
+			OwnerID:      ownerID,
+			ExpiresAt:    expiresAt,
+			FencingToken: next,
+		}
+		if err := tx.Create(lock).Error; err != nil {
+			return err
+		}
+		token = next
+		return nil
+	})
+	if err != nil {
+		// 检查是否是唯一键冲突（可能在并发情况下其他进程插入了同样的key）
+		if errors.Is(err, ErrLockExists) || isUniqueConstraintError(err) {
+			return 0, ErrLockExists
+		}
+		return 0, fmt.Errorf("database error: %w", err)
 	}
 
-	return nil
+	return token, nil
 }
 
 // Renew 续期锁
@@ -260,6 +335,55 @@ func (m *MySQLLocker) CleanExpiredLocks() (int64, error) {
 	return result.RowsAffected, nil
 }
 
+// CurrentToken 获取锁最近一次分配的 fencing token
+func (m *MySQLLocker) CurrentToken(key string) (int64, error) {
+	var t LockToken
+	err := m.db.Where("lock_key = ?", key).First(&t).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("database error: %w", err)
+	}
+	return t.Token, nil
+}
+
+// ListLocks 列出键以 prefix 开头的锁
+func (m *MySQLLocker) ListLocks(prefix string) ([]LockInfo, error) {
+	var locks []DistributedLock
+	err := m.db.
+		Where("lock_key LIKE ?", escapeLike(prefix)+"%").
+		Order("created_at").
+		Find(&locks).Error
+	if err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+
+	now := time.Now()
+	res := make([]LockInfo, 0, len(locks))
+	for _, l := range locks {
+		res = append(res, newLockInfo(l.LockKey, l.OwnerID, l.FencingToken, l.CreatedAt, l.ExpiresAt, now))
+	}
+	return res, nil
+}
+
+// ForceUnlock 强制释放锁
+func (m *MySQLLocker) ForceUnlock(key string) error {
+	result := m.db.
+		Where("lock_key = ?", key).
+		Delete(&DistributedLock{})
+
+	if result.Error != nil {
+		return fmt.Errorf("database error: %w", result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrLockNotFound
+	}
+
+	return nil
+}
+
 // isUniqueConstraintError 判断是否是唯一键约束错误
 func isUniqueConstraintError(err error) bool {
 	if err == nil {
@@ -1,13 +1,19 @@
 package lock
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
 
 	_ "github.com/go-sql-driver/mysql" // 引入 MySQL 驱动
+	"gorm.io/gorm"
 )
 
 func setupTestLocker(t *testing.T) Locker {
@@ -33,8 +39,20 @@ func setupTestLocker(t *testing.T) Locker {
 
 // --- Test Cases ---
 
-// TestLockerSuite 运行所有与 Locker 相关的测试
+// TestLockerSuite 针对 MySQL 实现运行所有与 Locker 相关的测试
 func TestLockerSuite(t *testing.T) {
+	runLockerSuite(t, setupTestLocker)
+}
+
+// TestMemoryLockerSuite 针对内存实现运行同一套测试，无需数据库
+func TestMemoryLockerSuite(t *testing.T) {
+	runLockerSuite(t, func(t *testing.T) Locker {
+		return NewMemoryLocker()
+	})
+}
+
+// runLockerSuite 运行所有与 Locker 相关的测试，setupTestLocker 为每个子测试创建干净的实例
+func runLockerSuite(t *testing.T, setupTestLocker func(t *testing.T) Locker) {
 	// 注意：下面的每个 t.Run 都是一个独立的子测试。
 	// 理想情况下，每个子测试都应该在一个干净的环境下运行。
 	// setupTestLocker 函数就是为了实现这一点。
@@ -47,13 +65,13 @@ func TestLockerSuite(t *testing.T) {
 		duration := 10 * time.Second
 
 		// 1. owner1 成功获取锁
-		err := locker.TryLock(key, owner1, duration)
+		_, err := locker.TryLock(key, owner1, duration)
 		if err != nil {
 			t.Fatalf("owner1 应该能成功获取锁，但失败了: %v", err)
 		}
 
 		// 2. owner2 尝试获取同一个锁，应该立即失败
-		err = locker.TryLock(key, owner2, duration)
+		_, err = locker.TryLock(key, owner2, duration)
 		if err == nil {
 			t.Fatal("owner2 不应该能获取已被 owner1 持有的锁")
 		}
@@ -65,7 +83,7 @@ func TestLockerSuite(t *testing.T) {
 		}
 
 		// 4. owner2 再次尝试，这次应该成功
-		err = locker.TryLock(key, owner2, duration)
+		_, err = locker.TryLock(key, owner2, duration)
 		if err != nil {
 			t.Fatalf("owner2 在锁被释放后应该能成功获取，但失败了: %v", err)
 		}
@@ -79,7 +97,7 @@ func TestLockerSuite(t *testing.T) {
 		owner2 := "owner-fake"
 		duration := 10 * time.Second
 
-		if err := locker.TryLock(key, owner1, duration); err != nil {
+		if _, err := locker.TryLock(key, owner1, duration); err != nil {
 			t.Fatalf("获取锁失败: %v", err)
 		}
 
@@ -103,7 +121,7 @@ func TestLockerSuite(t *testing.T) {
 		owner := "owner-expire"
 		duration := 1 * time.Second
 
-		if err := locker.TryLock(key, owner, duration); err != nil {
+		if _, err := locker.TryLock(key, owner, duration); err != nil {
 			t.Fatalf("获取锁失败: %v", err)
 		}
 
@@ -126,7 +144,7 @@ func TestLockerSuite(t *testing.T) {
 		owner2 := "imposter"
 		duration := 2 * time.Second
 
-		if err := locker.TryLock(key, owner1, duration); err != nil {
+		if _, err := locker.TryLock(key, owner1, duration); err != nil {
 			t.Fatalf("获取锁失败: %v", err)
 		}
 
@@ -198,7 +216,7 @@ func TestLockerSuite(t *testing.T) {
 				ownerID := fmt.Sprintf("worker-%d", id)
 				for j := 0; j < incrementsPerGoroutine; j++ {
 					// 尝试获取锁，这里使用阻塞的 Lock
-					err := locker.Lock(key, ownerID, 5*time.Second)
+					_, err := locker.Lock(key, ownerID, 5*time.Second)
 					if err != nil {
 						// 在并发测试中，我们不希望有错误发生
 						t.Errorf("Worker %d 获取锁失败: %v", id, err)
@@ -230,13 +248,13 @@ func TestLockerSuite(t *testing.T) {
 		locker := setupTestLocker(t)
 
 		// 1. 创建一个很快就会过期的锁
-		err := locker.TryLock("expired-key", "owner-expired", 1*time.Second)
+		_, err := locker.TryLock("expired-key", "owner-expired", 1*time.Second)
 		if err != nil {
 			t.Fatalf("创建 expired-key 失败: %v", err)
 		}
 
 		// 2. 创建一个不会过期的锁
-		err = locker.TryLock("active-key", "owner-active", 1*time.Minute)
+		_, err = locker.TryLock("active-key", "owner-active", 1*time.Minute)
 		if err != nil {
 			t.Fatalf("创建 active-key 失败: %v", err)
 		}
@@ -265,4 +283,422 @@ func TestLockerSuite(t *testing.T) {
 			t.Error("CleanExpiredLocks 错误地清理了未过期的锁")
 		}
 	})
+
+	t.Run("TestSession_Heartbeat", func(t *testing.T) {
+		locker := setupTestLocker(t)
+		key := "session-key"
+		owner := "owner-session"
+		ttl := 1500 * time.Millisecond
+
+		session, err := LockWithHeartbeat(context.Background(), locker, key, owner, ttl)
+		if err != nil {
+			t.Fatalf("获取心跳锁失败: %v", err)
+		}
+
+		// 等待超过一个租约周期，心跳应保证锁仍然有效
+		time.Sleep(2 * ttl)
+		if owner2, err := locker.GetOwner(key); err != nil || owner2 != owner {
+			t.Fatalf("心跳续期后锁应仍被 %s 持有，但 GetOwner() 返回 '%s' (err: %v)", owner, owner2, err)
+		}
+		if session.Context().Err() != nil {
+			t.Fatal("锁未丢失时会话 Context 不应被取消")
+		}
+
+		// 关闭会话后锁应被释放
+		if err := session.Close(); err != nil {
+			t.Fatalf("关闭会话失败: %v", err)
+		}
+		if locked, _ := locker.IsLocked(key); locked {
+			t.Fatal("关闭会话后锁应被释放")
+		}
+	})
+
+	t.Run("TestSession_LostOwnership", func(t *testing.T) {
+		locker := setupTestLocker(t)
+		key := "session-lost-key"
+		owner := "owner-session-lost"
+		ttl := 900 * time.Millisecond
+
+		session, err := LockWithHeartbeat(context.Background(), locker, key, owner, ttl)
+		if err != nil {
+			t.Fatalf("获取心跳锁失败: %v", err)
+		}
+		defer session.Close()
+
+		// 模拟锁被外部删除
+		if err := locker.Unlock(key, owner); err != nil {
+			t.Fatalf("释放锁失败: %v", err)
+		}
+
+		select {
+		case <-session.Context().Done():
+		case <-time.After(2 * ttl):
+			t.Fatal("失去所有权后会话 Context 应被取消")
+		}
+		if session.Err() == nil {
+			t.Fatal("失去所有权后 Err() 应返回错误")
+		}
+	})
+
+	t.Run("TestFencingToken_Monotonic", func(t *testing.T) {
+		locker := setupTestLocker(t)
+		key := "fencing-key"
+		duration := 10 * time.Second
+
+		token1, err := locker.TryLock(key, "owner-fence-1", duration)
+		if err != nil {
+			t.Fatalf("owner-fence-1 获取锁失败: %v", err)
+		}
+		stale := Fence{Key: key, Token: token1}
+		locker.Unlock(key, "owner-fence-1")
+
+		token2, err := locker.TryLock(key, "owner-fence-2", duration)
+		if err != nil {
+			t.Fatalf("owner-fence-2 获取锁失败: %v", err)
+		}
+		defer locker.Unlock(key, "owner-fence-2")
+
+		if token2 <= token1 {
+			t.Fatalf("fencing token 应单调递增，token1=%d token2=%d", token1, token2)
+		}
+		if current, err := locker.(Admin).CurrentToken(key); err != nil || current != token2 {
+			t.Fatalf("CurrentToken 应返回最新的 token %d，实际: %d %v", token2, current, err)
+		}
+
+		// 事务内校验只支持 MySQL 后端
+		m, ok := locker.(*MySQLLocker)
+		if !ok {
+			return
+		}
+		err = m.db.Transaction(func(tx *gorm.DB) error {
+			return Fence{Key: key, Token: token2}.Check(tx)
+		})
+		if err != nil {
+			t.Fatalf("持有最新 token 时校验不应失败: %v", err)
+		}
+		err = m.db.Transaction(func(tx *gorm.DB) error {
+			return stale.Check(tx)
+		})
+		if !errors.Is(err, ErrStaleToken) {
+			t.Fatalf("过期 token 校验应返回 ErrStaleToken，实际: %v", err)
+		}
+	})
+
+	t.Run("TestLockContext_Timeout", func(t *testing.T) {
+		locker := setupTestLocker(t)
+		key := "lock-context-key"
+		duration := 10 * time.Second
+
+		if _, err := locker.TryLock(key, "owner-holder", duration); err != nil {
+			t.Fatalf("获取锁失败: %v", err)
+		}
+		defer locker.Unlock(key, "owner-holder")
+
+		ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+		defer cancel()
+
+		startTime := time.Now()
+		_, err := LockContext(ctx, locker, key, "owner-waiter", duration)
+		elapsed := time.Since(startTime)
+
+		var timeoutErr *LockTimeoutError
+		if !errors.As(err, &timeoutErr) {
+			t.Fatalf("期望返回 *LockTimeoutError，实际: %v", err)
+		}
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("超时错误应包装 context.DeadlineExceeded，实际: %v", err)
+		}
+		if elapsed > 2*time.Second {
+			t.Errorf("LockContext 超时后应尽快返回，实际等待了 %v", elapsed)
+		}
+	})
+
+	t.Run("TestListLocksAndForceUnlock", func(t *testing.T) {
+		locker := setupTestLocker(t)
+		admin := locker.(Admin)
+		duration := 10 * time.Second
+
+		if _, err := locker.TryLock("list:1:a", "owner-list-1", duration); err != nil {
+			t.Fatalf("获取锁失败: %v", err)
+		}
+		if _, err := locker.TryLock("list:1:b", "owner-list-2", duration); err != nil {
+			t.Fatalf("获取锁失败: %v", err)
+		}
+		if _, err := locker.TryLock("other:1:a", "owner-list-3", duration); err != nil {
+			t.Fatalf("获取锁失败: %v", err)
+		}
+		defer locker.Unlock("other:1:a", "owner-list-3")
+
+		locks, err := admin.ListLocks("list:")
+		if err != nil {
+			t.Fatalf("ListLocks 失败: %v", err)
+		}
+		if len(locks) != 2 {
+			t.Fatalf("期望列出 2 个锁，实际 %d 个", len(locks))
+		}
+		if m := ComputeMetrics(locks); m.Total != 2 || m.Expired != 0 {
+			t.Errorf("锁统计不正确: %+v", m)
+		}
+
+		// 不需要持有者即可强制释放
+		if err := admin.ForceUnlock("list:1:a"); err != nil {
+			t.Fatalf("ForceUnlock 失败: %v", err)
+		}
+		if err := admin.ForceUnlock("list:1:b"); err != nil {
+			t.Fatalf("ForceUnlock 失败: %v", err)
+		}
+		if err := admin.ForceUnlock("list:1:b"); !errors.Is(err, ErrLockNotFound) {
+			t.Errorf("重复 ForceUnlock 应返回 ErrLockNotFound，实际: %v", err)
+		}
+		if locks, _ := admin.ListLocks("list:"); len(locks) != 0 {
+			t.Errorf("强制释放后不应再列出锁，实际 %d 个", len(locks))
+		}
+	})
+}
+
+func TestAdminHandler(t *testing.T) {
+	locker := NewMemoryLocker()
+	if _, err := locker.TryLock("knocommerce:1:acc", "process-1", time.Minute); err != nil {
+		t.Fatalf("获取锁失败: %v", err)
+	}
+
+	handler := NewAdminHandler(locker, "secret")
+
+	// 未携带 token
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/locks", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("未授权请求应返回 401，实际 %d", rec.Code)
+	}
+
+	// 列出锁
+	req := httptest.NewRequest(http.MethodGet, "/locks?prefix=knocommerce:", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	var locks []LockInfo
+	if err := json.Unmarshal(rec.Body.Bytes(), &locks); err != nil {
+		t.Fatalf("解析响应失败: %v, body=%s", err, rec.Body.String())
+	}
+	if len(locks) != 1 || locks[0].OwnerID != "process-1" {
+		t.Fatalf("列出的锁不正确: %+v", locks)
+	}
+
+	// 强制释放
+	req = httptest.NewRequest(http.MethodDelete, "/locks/knocommerce:1:acc", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("强制释放应返回 200，实际 %d: %s", rec.Code, rec.Body.String())
+	}
+	if locked, _ := locker.IsLocked("knocommerce:1:acc"); locked {
+		t.Fatal("强制释放后锁应不存在")
+	}
+}
+
+// TestRWLockerSuite 针对 MySQL 实现运行读写锁测试
+func TestRWLockerSuite(t *testing.T) {
+	runRWLockerSuite(t, func(t *testing.T) RWLocker {
+		return NewMySQLRWLocker()
+	})
+}
+
+// TestMemoryRWLockerSuite 针对内存实现运行读写锁测试
+func TestMemoryRWLockerSuite(t *testing.T) {
+	runRWLockerSuite(t, func(t *testing.T) RWLocker {
+		return NewMemoryRWLocker()
+	})
+}
+
+// runRWLockerSuite 运行共享/独占模式和重入相关的测试
+func runRWLockerSuite(t *testing.T, setupRWLocker func(t *testing.T) RWLocker) {
+	t.Run("TestShared_MultipleHolders", func(t *testing.T) {
+		locker := setupRWLocker(t)
+		key := "rw-shared-key"
+		duration := 10 * time.Second
+
+		// 多个只读校验任务可以同时持有共享锁
+		for _, owner := range []string{"validator-1", "validator-2", "validator-3"} {
+			if _, err := locker.TryAcquire(key, owner, ModeShared, duration); err != nil {
+				t.Fatalf("%s 获取共享锁失败: %v", owner, err)
+			}
+		}
+
+		holders, err := locker.Holders(key)
+		if err != nil {
+			t.Fatalf("Holders 失败: %v", err)
+		}
+		if len(holders) != 3 {
+			t.Fatalf("期望 3 个共享持有者，实际 %d 个", len(holders))
+		}
+
+		// 存在共享持有者时无法获取独占锁
+		if _, err := locker.TryAcquire(key, "backfill", ModeExclusive, duration); !errors.Is(err, ErrLockExists) {
+			t.Fatalf("存在共享持有者时独占获取应返回 ErrLockExists，实际: %v", err)
+		}
+
+		for _, owner := range []string{"validator-1", "validator-2", "validator-3"} {
+			if _, err := locker.Release(key, owner); err != nil {
+				t.Fatalf("%s 释放失败: %v", owner, err)
+			}
+		}
+
+		// 全部释放后可以获取独占锁
+		if _, err := locker.TryAcquire(key, "backfill", ModeExclusive, duration); err != nil {
+			t.Fatalf("共享锁全部释放后应能获取独占锁: %v", err)
+		}
+		locker.Release(key, "backfill")
+	})
+
+	t.Run("TestExclusive_BlocksShared", func(t *testing.T) {
+		locker := setupRWLocker(t)
+		key := "rw-exclusive-key"
+		duration := 10 * time.Second
+
+		if _, err := locker.TryAcquire(key, "incremental", ModeExclusive, duration); err != nil {
+			t.Fatalf("获取独占锁失败: %v", err)
+		}
+		if _, err := locker.TryAcquire(key, "validator", ModeShared, duration); !errors.Is(err, ErrLockExists) {
+			t.Fatalf("存在独占持有者时共享获取应返回 ErrLockExists，实际: %v", err)
+		}
+		if _, err := locker.TryAcquire(key, "backfill", ModeExclusive, duration); !errors.Is(err, ErrLockExists) {
+			t.Fatalf("存在独占持有者时独占获取应返回 ErrLockExists，实际: %v", err)
+		}
+		locker.Release(key, "incremental")
+	})
+
+	t.Run("TestReentrant_HoldCount", func(t *testing.T) {
+		locker := setupRWLocker(t)
+		key := "rw-reentrant-key"
+		owner := "owner-reentrant"
+		duration := 10 * time.Second
+
+		first, err := locker.TryAcquire(key, owner, ModeExclusive, duration)
+		if err != nil {
+			t.Fatalf("首次获取失败: %v", err)
+		}
+		second, err := locker.TryAcquire(key, owner, ModeExclusive, duration)
+		if err != nil {
+			t.Fatalf("同一持有者重入失败: %v", err)
+		}
+		if second.Count != 2 {
+			t.Fatalf("重入后持有计数应为 2，实际 %d", second.Count)
+		}
+		if second.Token != first.Token {
+			t.Errorf("重入不应改变 fencing token，first=%d second=%d", first.Token, second.Token)
+		}
+
+		remaining, err := locker.Release(key, owner)
+		if err != nil || remaining != 1 {
+			t.Fatalf("第一次释放后应剩余 1 次持有，实际 %d (err: %v)", remaining, err)
+		}
+		if _, err := locker.TryAcquire(key, "other", ModeShared, duration); !errors.Is(err, ErrLockExists) {
+			t.Fatalf("仍有持有计数时其他持有者不应获取成功，实际: %v", err)
+		}
+
+		remaining, err = locker.Release(key, owner)
+		if err != nil || remaining != 0 {
+			t.Fatalf("第二次释放后应完全释放，实际剩余 %d (err: %v)", remaining, err)
+		}
+		if _, err := locker.Release(key, owner); !errors.Is(err, ErrLockNotFound) {
+			t.Fatalf("完全释放后再次释放应返回 ErrLockNotFound，实际: %v", err)
+		}
+	})
+
+	t.Run("TestUpgrade_SoleSharedHolder", func(t *testing.T) {
+		locker := setupRWLocker(t)
+		key := "rw-upgrade-key"
+		duration := 10 * time.Second
+
+		shared, err := locker.TryAcquire(key, "owner-upgrade", ModeShared, duration)
+		if err != nil {
+			t.Fatalf("获取共享锁失败: %v", err)
+		}
+		upgraded, err := locker.TryAcquire(key, "owner-upgrade", ModeExclusive, duration)
+		if err != nil {
+			t.Fatalf("唯一共享持有者应能升级为独占: %v", err)
+		}
+		if upgraded.Mode != ModeExclusive || upgraded.Token <= shared.Token {
+			t.Fatalf("升级后应为独占并分配新 token，实际 %+v", upgraded)
+		}
+
+		// 有其他共享持有者时不能升级
+		if _, err := locker.TryAcquire("rw-upgrade-key-2", "a", ModeShared, duration); err != nil {
+			t.Fatalf("获取共享锁失败: %v", err)
+		}
+		if _, err := locker.TryAcquire("rw-upgrade-key-2", "b", ModeShared, duration); err != nil {
+			t.Fatalf("获取共享锁失败: %v", err)
+		}
+		if _, err := locker.TryAcquire("rw-upgrade-key-2", "a", ModeExclusive, duration); !errors.Is(err, ErrLockExists) {
+			t.Fatalf("存在其他共享持有者时升级应失败，实际: %v", err)
+		}
+
+		locker.Release(key, "owner-upgrade")
+		locker.Release(key, "owner-upgrade")
+		locker.Release("rw-upgrade-key-2", "a")
+		locker.Release("rw-upgrade-key-2", "b")
+	})
+
+	t.Run("TestHold_Expiration", func(t *testing.T) {
+		locker := setupRWLocker(t)
+		key := "rw-expire-key"
+
+		if _, err := locker.TryAcquire(key, "owner-expire", ModeExclusive, time.Second); err != nil {
+			t.Fatalf("获取独占锁失败: %v", err)
+		}
+		time.Sleep(1500 * time.Millisecond)
+
+		// 过期的持有者不再阻止其他人获取
+		if _, err := locker.TryAcquire(key, "owner-next", ModeShared, 10*time.Second); err != nil {
+			t.Fatalf("过期后应能获取锁: %v", err)
+		}
+		if err := locker.RenewHold(key, "owner-expire", time.Second); !errors.Is(err, ErrLockNotFound) {
+			t.Fatalf("过期持有者续期应返回 ErrLockNotFound，实际: %v", err)
+		}
+		locker.Release(key, "owner-next")
+	})
+
+	t.Run("TestRW_SharesKeysWithLocker", func(t *testing.T) {
+		rw := setupRWLocker(t)
+		locker := rw.(Locker)
+		key := "rw-plain-key"
+		duration := 10 * time.Second
+
+		// 通过 Locker 持有的锁对读写锁视为独占
+		if _, err := locker.TryLock(key, "incremental", duration); err != nil {
+			t.Fatalf("获取普通锁失败: %v", err)
+		}
+		if _, err := rw.TryAcquire(key, "backfill", ModeExclusive, duration); !errors.Is(err, ErrLockExists) {
+			t.Fatalf("存在普通锁时独占获取应返回 ErrLockExists，实际: %v", err)
+		}
+		if _, err := rw.TryAcquire(key, "validator", ModeShared, duration); !errors.Is(err, ErrLockExists) {
+			t.Fatalf("存在普通锁时共享获取应返回 ErrLockExists，实际: %v", err)
+		}
+		if err := locker.Unlock(key, "incremental"); err != nil {
+			t.Fatalf("释放普通锁失败: %v", err)
+		}
+
+		// 读写锁的持有者同样阻止 Locker 获取
+		if _, err := rw.TryAcquire(key, "validator", ModeShared, duration); err != nil {
+			t.Fatalf("获取共享锁失败: %v", err)
+		}
+		if _, err := locker.TryLock(key, "incremental", duration); !errors.Is(err, ErrLockExists) {
+			t.Fatalf("存在共享持有者时 TryLock 应返回 ErrLockExists，实际: %v", err)
+		}
+		rw.Release(key, "validator")
+		if _, err := locker.TryLock(key, "incremental", duration); err != nil {
+			t.Fatalf("读写锁释放后应能获取普通锁: %v", err)
+		}
+		locker.Unlock(key, "incremental")
+	})
+}
+
+func TestNewRW(t *testing.T) {
+	if _, err := NewRW(Config{Backend: BackendRedis}); err == nil {
+		t.Fatal("Redis 后端不支持读写锁，NewRW 应返回错误")
+	}
+	if _, err := NewRW(Config{Backend: BackendMemory}); err != nil {
+		t.Fatalf("内存后端应支持读写锁: %v", err)
+	}
 }
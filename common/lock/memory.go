@@ -0,0 +1,323 @@
+package lock
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// memoryLock 内存锁记录
+type memoryLock struct {
+	ownerID    string
+	acquiredAt time.Time
+	expiresAt  time.Time
+	token      int64
+}
+
+// MemoryLocker 进程内实现的锁，仅在单进程内有效，主要用于单元测试
+type MemoryLocker struct {
+	mu      sync.Mutex
+	locks   map[string]*memoryLock
+	tokens  map[string]int64
+	holders map[string]map[string]*Hold // 读写锁：key -> owner -> 持有记录
+}
+
+// NewMemoryLocker 创建新的内存锁实例
+func NewMemoryLocker() *MemoryLocker {
+	return &MemoryLocker{
+		locks:   make(map[string]*memoryLock),
+		tokens:  make(map[string]int64),
+		holders: make(map[string]map[string]*Hold),
+	}
+}
+
+// NewMemoryRWLocker 创建新的内存读写锁实例
+func NewMemoryRWLocker() *MemoryLocker {
+	return NewMemoryLocker()
+}
+
+// Lock 获取锁（阻塞式，会一直重试直到成功）
+func (m *MemoryLocker) Lock(key, ownerID string, duration time.Duration) (int64, error) {
+	return m.LockContext(context.Background(), key, ownerID, duration)
+}
+
+// LockContext 获取锁（阻塞式，按退避策略重试，受 ctx 控制）
+func (m *MemoryLocker) LockContext(ctx context.Context, key, ownerID string, duration time.Duration) (int64, error) {
+	if duration <= 0 {
+		return 0, ErrInvalidDuration
+	}
+
+	return lockWithBackoff(ctx, key, func() (int64, error) {
+		return m.TryLock(key, ownerID, duration)
+	})
+}
+
+// TryLock 尝试获取锁（非阻塞）
+func (m *MemoryLocker) TryLock(key, ownerID string, duration time.Duration) (int64, error) {
+	if duration <= 0 {
+		return 0, ErrInvalidDuration
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	if l, ok := m.locks[key]; ok {
+		if l.expiresAt.After(now) {
+			if l.ownerID == ownerID {
+				l.expiresAt = now.Add(duration)
+				return l.token, nil
+			}
+			return 0, ErrLockExists
+		}
+		// 已过期，视为不存在
+		delete(m.locks, key)
+	}
+	// 读写锁的其他持有者同样占用这个键
+	for _, h := range m.liveHolders(key, now) {
+		if h.OwnerID != ownerID {
+			return 0, ErrLockExists
+		}
+	}
+
+	m.tokens[key]++
+	token := m.tokens[key]
+	m.locks[key] = &memoryLock{
+		ownerID:    ownerID,
+		acquiredAt: now,
+		expiresAt:  now.Add(duration),
+		token:      token,
+	}
+	return token, nil
+}
+
+// Renew 续期锁
+func (m *MemoryLocker) Renew(key, ownerID string, duration time.Duration) error {
+	if duration <= 0 {
+		return ErrInvalidDuration
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	l, ok := m.locks[key]
+	if !ok {
+		return ErrLockNotFound
+	}
+	if l.ownerID != ownerID {
+		return ErrNotLockOwner
+	}
+
+	now := time.Now()
+	if !l.expiresAt.After(now) {
+		return ErrLockExpired
+	}
+	l.expiresAt = now.Add(duration)
+	return nil
+}
+
+// Unlock 释放锁
+func (m *MemoryLocker) Unlock(key, ownerID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	l, ok := m.locks[key]
+	if !ok || l.ownerID != ownerID {
+		return ErrLockNotFound
+	}
+	delete(m.locks, key)
+	return nil
+}
+
+// IsLocked 检查锁是否存在且未过期
+func (m *MemoryLocker) IsLocked(key string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	l, ok := m.locks[key]
+	return ok && l.expiresAt.After(time.Now()), nil
+}
+
+// GetOwner 获取锁的持有者
+func (m *MemoryLocker) GetOwner(key string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	l, ok := m.locks[key]
+	if !ok || !l.expiresAt.After(time.Now()) {
+		return "", ErrLockNotFound
+	}
+	return l.ownerID, nil
+}
+
+// CleanExpiredLocks 清理过期的锁
+func (m *MemoryLocker) CleanExpiredLocks() (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var count int64
+	now := time.Now()
+	for key, l := range m.locks {
+		if !l.expiresAt.After(now) {
+			delete(m.locks, key)
+			count++
+		}
+	}
+	return count, nil
+}
+
+// CurrentToken 获取锁最近一次分配的 fencing token
+func (m *MemoryLocker) CurrentToken(key string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.tokens[key], nil
+}
+
+// ListLocks 列出键以 prefix 开头的锁
+func (m *MemoryLocker) ListLocks(prefix string) ([]LockInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	res := make([]LockInfo, 0)
+	for key, l := range m.locks {
+		if strings.HasPrefix(key, prefix) {
+			res = append(res, newLockInfo(key, l.ownerID, l.token, l.acquiredAt, l.expiresAt, now))
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].AcquiredAt.Before(res[j].AcquiredAt)
+	})
+	return res, nil
+}
+
+// ForceUnlock 强制释放锁
+func (m *MemoryLocker) ForceUnlock(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.locks[key]; !ok {
+		return ErrLockNotFound
+	}
+	delete(m.locks, key)
+	return nil
+}
+
+// liveHolders 清理过期持有者并返回当前持有者，调用方需持有 m.mu
+func (m *MemoryLocker) liveHolders(key string, now time.Time) []Hold {
+	res := make([]Hold, 0, len(m.holders[key]))
+	for owner, h := range m.holders[key] {
+		if !h.ExpiresAt.After(now) {
+			delete(m.holders[key], owner)
+			continue
+		}
+		res = append(res, *h)
+	}
+	return res
+}
+
+// TryAcquire 以指定模式尝试获取锁（非阻塞）
+func (m *MemoryLocker) TryAcquire(key, ownerID string, mode LockMode, duration time.Duration) (Hold, error) {
+	if duration <= 0 {
+		return Hold{}, ErrInvalidDuration
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	// 其他 owner 通过 Locker 持有的锁视为独占
+	if l, ok := m.locks[key]; ok && l.expiresAt.After(now) && l.ownerID != ownerID {
+		return Hold{}, ErrLockExists
+	}
+	mine, err := checkAcquire(m.liveHolders(key, now), ownerID, mode)
+	if err != nil {
+		return Hold{}, err
+	}
+
+	if mine != nil {
+		h := m.holders[key][ownerID]
+		h.Count++
+		h.ExpiresAt = now.Add(duration)
+		if mode == ModeExclusive && h.Mode == ModeShared {
+			m.tokens[key]++
+			h.Mode = ModeExclusive
+			h.Token = m.tokens[key]
+		}
+		return *h, nil
+	}
+
+	if mode == ModeExclusive {
+		m.tokens[key]++
+	}
+	h := &Hold{
+		Key:       key,
+		OwnerID:   ownerID,
+		Mode:      mode,
+		Count:     1,
+		Token:     m.tokens[key],
+		ExpiresAt: now.Add(duration),
+	}
+	if m.holders[key] == nil {
+		m.holders[key] = make(map[string]*Hold)
+	}
+	m.holders[key][ownerID] = h
+	return *h, nil
+}
+
+// AcquireContext 阻塞获取锁（按退避策略重试，受 ctx 控制）
+func (m *MemoryLocker) AcquireContext(ctx context.Context, key, ownerID string, mode LockMode, duration time.Duration) (Hold, error) {
+	return lockWithBackoff(ctx, key, func() (Hold, error) {
+		return m.TryAcquire(key, ownerID, mode, duration)
+	})
+}
+
+// Release 持有计数减一，减到 0 时释放锁
+func (m *MemoryLocker) Release(key, ownerID string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	h, ok := m.holders[key][ownerID]
+	if !ok {
+		return 0, ErrLockNotFound
+	}
+	h.Count--
+	if h.Count > 0 {
+		return h.Count, nil
+	}
+	delete(m.holders[key], ownerID)
+	return 0, nil
+}
+
+// RenewHold 续期持有者的锁
+func (m *MemoryLocker) RenewHold(key, ownerID string, duration time.Duration) error {
+	if duration <= 0 {
+		return ErrInvalidDuration
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	h, ok := m.holders[key][ownerID]
+	if !ok || !h.ExpiresAt.After(now) {
+		return ErrLockNotFound
+	}
+	h.ExpiresAt = now.Add(duration)
+	return nil
+}
+
+// Holders 列出锁当前未过期的持有者
+func (m *MemoryLocker) Holders(key string) ([]Hold, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	res := m.liveHolders(key, time.Now())
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].OwnerID < res[j].OwnerID
+	})
+	return res, nil
+}
@@ -0,0 +1,275 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// redisKeyPrefix Redis 中锁键的前缀
+const redisKeyPrefix = "wm:lock:"
+
+// redisMetaTTL meta hash 的过期时间，每次获取和续期时刷新
+// 锁键闲置超过该时长后 meta 被删除，fencing token 从 1 重新计数，因此 Redis 的 token 不能用于 Fence 校验
+const redisMetaTTL = 7 * 24 * time.Hour
+
+// acquireScript 原子地获取锁并分配 fencing token
+// 锁不存在时 SET NX PX，并在 meta hash 中递增 token、记录获取时间；同一个 owner 重复获取时只续期
+// 两种情况都会把 meta 的过期时间刷新为 ARGV[4] 毫秒。返回 token，被他人持有时返回 -1
+var acquireScript = redis.NewScript(`
+if redis.call('SET', KEYS[1], ARGV[1], 'NX', 'PX', ARGV[2]) then
+	local t = redis.call('HINCRBY', KEYS[2], 'token', 1)
+	redis.call('HSET', KEYS[2], 'acquired_at', ARGV[3])
+	redis.call('PEXPIRE', KEYS[2], ARGV[4])
+	return t
+end
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	redis.call('PEXPIRE', KEYS[1], ARGV[2])
+	redis.call('PEXPIRE', KEYS[2], ARGV[4])
+	return tonumber(redis.call('HGET', KEYS[2], 'token') or '0')
+end
+return -1
+`)
+
+// renewScript 仅当持有者匹配时续期锁和 meta：1 成功，0 锁不存在，-1 持有者不匹配
+var renewScript = redis.NewScript(`
+local cur = redis.call('GET', KEYS[1])
+if cur == false then
+	return 0
+end
+if cur ~= ARGV[1] then
+	return -1
+end
+redis.call('PEXPIRE', KEYS[1], ARGV[2])
+redis.call('PEXPIRE', KEYS[2], ARGV[3])
+return 1
+`)
+
+// unlockScript compare-and-delete：仅当持有者匹配时删除，1 成功，0 未删除
+var unlockScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// RedisLocker Redis实现的分布式锁
+type RedisLocker struct {
+	client *redis.Client
+}
+
+// NewRedisLocker 使用已有的 Redis 客户端创建分布式锁实例
+func NewRedisLocker(client *redis.Client) *RedisLocker {
+	return &RedisLocker{
+		client: client,
+	}
+}
+
+// lockKey 锁在 Redis 中的键，使用 hash tag 保证与 meta 键落在同一个 slot
+func (r *RedisLocker) lockKey(key string) string {
+	return redisKeyPrefix + "{" + key + "}"
+}
+
+// metaKey 保存 fencing token 和获取时间的 hash
+// 锁释放或过期后 token 计数依然保留，闲置超过 redisMetaTTL 后随 meta 一起过期
+func (r *RedisLocker) metaKey(key string) string {
+	return redisKeyPrefix + "{" + key + "}:meta"
+}
+
+// Lock 获取锁（阻塞式，会一直重试直到成功）
+func (r *RedisLocker) Lock(key, ownerID string, duration time.Duration) (int64, error) {
+	return r.LockContext(context.Background(), key, ownerID, duration)
+}
+
+// LockContext 获取锁（阻塞式，按退避策略重试，受 ctx 控制）
+func (r *RedisLocker) LockContext(ctx context.Context, key, ownerID string, duration time.Duration) (int64, error) {
+	if duration <= 0 {
+		return 0, ErrInvalidDuration
+	}
+
+	return lockWithBackoff(ctx, key, func() (int64, error) {
+		return r.TryLock(key, ownerID, duration)
+	})
+}
+
+// TryLock 尝试获取锁（非阻塞）
+func (r *RedisLocker) TryLock(key, ownerID string, duration time.Duration) (int64, error) {
+	if duration <= 0 {
+		return 0, ErrInvalidDuration
+	}
+
+	token, err := acquireScript.Run(context.Background(), r.client,
+		[]string{r.lockKey(key), r.metaKey(key)},
+		ownerID, duration.Milliseconds(), time.Now().UnixMilli(), redisMetaTTL.Milliseconds(),
+	).Int64()
+	if err != nil {
+		return 0, fmt.Errorf("redis error: %w", err)
+	}
+	if token < 0 {
+		return 0, ErrLockExists
+	}
+	return token, nil
+}
+
+// Renew 续期锁
+func (r *RedisLocker) Renew(key, ownerID string, duration time.Duration) error {
+	if duration <= 0 {
+		return ErrInvalidDuration
+	}
+
+	res, err := renewScript.Run(context.Background(), r.client,
+		[]string{r.lockKey(key), r.metaKey(key)},
+		ownerID, duration.Milliseconds(), redisMetaTTL.Milliseconds(),
+	).Int64()
+	if err != nil {
+		return fmt.Errorf("redis error: %w", err)
+	}
+
+	switch res {
+	case 1:
+		return nil
+	case 0:
+		// Redis 过期的键会被直接删除，无法区分过期和不存在
+		return ErrLockNotFound
+	default:
+		return ErrNotLockOwner
+	}
+}
+
+// Unlock 释放锁
+func (r *RedisLocker) Unlock(key, ownerID string) error {
+	res, err := unlockScript.Run(context.Background(), r.client,
+		[]string{r.lockKey(key)},
+		ownerID,
+	).Int64()
+	if err != nil {
+		return fmt.Errorf("redis error: %w", err)
+	}
+	if res == 0 {
+		return ErrLockNotFound
+	}
+	return nil
+}
+
+// IsLocked 检查锁是否存在且未过期
+func (r *RedisLocker) IsLocked(key string) (bool, error) {
+	n, err := r.client.Exists(context.Background(), r.lockKey(key)).Result()
+	if err != nil {
+		return false, fmt.Errorf("redis error: %w", err)
+	}
+	return n > 0, nil
+}
+
+// GetOwner 获取锁的持有者
+func (r *RedisLocker) GetOwner(key string) (string, error) {
+	owner, err := r.client.Get(context.Background(), r.lockKey(key)).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return "", ErrLockNotFound
+		}
+		return "", fmt.Errorf("redis error: %w", err)
+	}
+	return owner, nil
+}
+
+// CleanExpiredLocks Redis 会自动删除过期的键，无需清理
+func (r *RedisLocker) CleanExpiredLocks() (int64, error) {
+	return 0, nil
+}
+
+// CurrentToken 获取锁最近一次分配的 fencing token
+func (r *RedisLocker) CurrentToken(key string) (int64, error) {
+	token, err := r.client.HGet(context.Background(), r.metaKey(key), "token").Int64()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("redis error: %w", err)
+	}
+	return token, nil
+}
+
+// ListLocks 列出键以 prefix 开头的锁
+// Redis 中过期的锁会被自动删除，因此结果中不会出现已过期的锁
+func (r *RedisLocker) ListLocks(prefix string) ([]LockInfo, error) {
+	ctx := context.Background()
+	pattern := redisKeyPrefix + "{" + escapeGlob(prefix) + "*"
+
+	var keys []string
+	iter := r.client.Scan(ctx, 0, pattern, 100).Iterator()
+	for iter.Next(ctx) {
+		k := iter.Val()
+		// 跳过 meta hash，只保留锁本身
+		if !strings.HasSuffix(k, "}") {
+			continue
+		}
+		keys = append(keys, strings.TrimSuffix(strings.TrimPrefix(k, redisKeyPrefix+"{"), "}"))
+	}
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("redis error: %w", err)
+	}
+
+	now := time.Now()
+	res := make([]LockInfo, 0, len(keys))
+	for _, key := range keys {
+		owner, err := r.client.Get(ctx, r.lockKey(key)).Result()
+		if errors.Is(err, redis.Nil) {
+			continue // 扫描后已被释放
+		}
+		if err != nil {
+			return nil, fmt.Errorf("redis error: %w", err)
+		}
+
+		ttl, err := r.client.PTTL(ctx, r.lockKey(key)).Result()
+		if err != nil {
+			return nil, fmt.Errorf("redis error: %w", err)
+		}
+
+		meta, err := r.client.HMGet(ctx, r.metaKey(key), "token", "acquired_at").Result()
+		if err != nil {
+			return nil, fmt.Errorf("redis error: %w", err)
+		}
+
+		var token int64
+		var acquiredAt time.Time
+		if v, ok := meta[0].(string); ok {
+			token, _ = strconv.ParseInt(v, 10, 64)
+		}
+		if v, ok := meta[1].(string); ok {
+			if ms, err := strconv.ParseInt(v, 10, 64); err == nil {
+				acquiredAt = time.UnixMilli(ms)
+			}
+		}
+
+		res = append(res, newLockInfo(key, owner, token, acquiredAt, now.Add(ttl), now))
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].AcquiredAt.Before(res[j].AcquiredAt)
+	})
+	return res, nil
+}
+
+// ForceUnlock 强制释放锁
+func (r *RedisLocker) ForceUnlock(key string) error {
+	n, err := r.client.Del(context.Background(), r.lockKey(key)).Result()
+	if err != nil {
+		return fmt.Errorf("redis error: %w", err)
+	}
+	if n == 0 {
+		return ErrLockNotFound
+	}
+	return nil
+}
+
+// escapeGlob 转义 SCAN MATCH 模式中的通配符
+func escapeGlob(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `*`, `\*`, `?`, `\?`, `[`, `\[`, `]`, `\]`)
+	return r.Replace(s)
+}
@@ -0,0 +1,181 @@
+package lock
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+)
+
+// setupRedisLocker 在 miniredis 上创建 RedisLocker，miniredis 的过期时间需要用 FastForward 推进
+func setupRedisLocker(t *testing.T) (*RedisLocker, *miniredis.Miniredis) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return NewRedisLocker(client), mr
+}
+
+func TestRedisLocker_AcquireScript(t *testing.T) {
+	locker, mr := setupRedisLocker(t)
+	key := "knocommerce:1:acc"
+
+	token1, err := locker.TryLock(key, "owner-1", 10*time.Second)
+	if err != nil || token1 != 1 {
+		t.Fatalf("第一次获取锁应返回 token 1，实际: %d %v", token1, err)
+	}
+	if _, err := locker.TryLock(key, "owner-2", 10*time.Second); !errors.Is(err, ErrLockExists) {
+		t.Fatalf("他人持有时应返回 ErrLockExists，实际: %v", err)
+	}
+
+	// 同一个 owner 重复获取只续期，token 不变
+	mr.FastForward(5 * time.Second)
+	token, err := locker.TryLock(key, "owner-1", 10*time.Second)
+	if err != nil || token != token1 {
+		t.Fatalf("同一 owner 重复获取应返回原 token %d，实际: %d %v", token1, token, err)
+	}
+	if ttl := mr.TTL(locker.lockKey(key)); ttl != 10*time.Second {
+		t.Fatalf("重复获取应刷新锁的过期时间，实际 TTL %v", ttl)
+	}
+
+	// 锁过期后由他人获取，token 递增
+	mr.FastForward(11 * time.Second)
+	token2, err := locker.TryLock(key, "owner-2", 10*time.Second)
+	if err != nil || token2 != token1+1 {
+		t.Fatalf("锁过期后新 owner 应获得 token %d，实际: %d %v", token1+1, token2, err)
+	}
+	if current, err := locker.CurrentToken(key); err != nil || current != token2 {
+		t.Fatalf("CurrentToken 应返回 %d，实际: %d %v", token2, current, err)
+	}
+
+	if _, err := locker.TryLock(key, "owner-1", 0); !errors.Is(err, ErrInvalidDuration) {
+		t.Fatalf("duration 为 0 时应返回 ErrInvalidDuration，实际: %v", err)
+	}
+}
+
+func TestRedisLocker_RenewAndUnlock(t *testing.T) {
+	locker, mr := setupRedisLocker(t)
+	key := "renew-key"
+
+	if _, err := locker.TryLock(key, "owner-1", 2*time.Second); err != nil {
+		t.Fatalf("获取锁失败: %v", err)
+	}
+	mr.FastForward(time.Second)
+	if err := locker.Renew(key, "owner-1", 5*time.Second); err != nil {
+		t.Fatalf("持有者续期失败: %v", err)
+	}
+	if ttl := mr.TTL(locker.lockKey(key)); ttl != 5*time.Second {
+		t.Fatalf("续期后 TTL 应为 5s，实际 %v", ttl)
+	}
+	if err := locker.Renew(key, "owner-2", 5*time.Second); !errors.Is(err, ErrNotLockOwner) {
+		t.Fatalf("非持有者续期应返回 ErrNotLockOwner，实际: %v", err)
+	}
+
+	// compare-and-delete：非持有者不能释放
+	if err := locker.Unlock(key, "owner-2"); !errors.Is(err, ErrLockNotFound) {
+		t.Fatalf("非持有者释放应返回 ErrLockNotFound，实际: %v", err)
+	}
+	if owner, err := locker.GetOwner(key); err != nil || owner != "owner-1" {
+		t.Fatalf("锁应仍被 owner-1 持有，实际: %q %v", owner, err)
+	}
+	if err := locker.Unlock(key, "owner-1"); err != nil {
+		t.Fatalf("持有者释放失败: %v", err)
+	}
+	if locked, _ := locker.IsLocked(key); locked {
+		t.Fatal("释放后锁应不存在")
+	}
+	if err := locker.Renew(key, "owner-1", 5*time.Second); !errors.Is(err, ErrLockNotFound) {
+		t.Fatalf("锁不存在时续期应返回 ErrLockNotFound，实际: %v", err)
+	}
+}
+
+func TestRedisLocker_MetaExpiry(t *testing.T) {
+	locker, mr := setupRedisLocker(t)
+	key := "meta-key"
+
+	if _, err := locker.TryLock(key, "owner-1", 10*time.Second); err != nil {
+		t.Fatalf("获取锁失败: %v", err)
+	}
+	if ttl := mr.TTL(locker.metaKey(key)); ttl != redisMetaTTL {
+		t.Fatalf("meta 的过期时间应为 %v，实际 %v", redisMetaTTL, ttl)
+	}
+
+	// 续期同时刷新 meta 的过期时间
+	mr.FastForward(5 * time.Second)
+	if err := locker.Renew(key, "owner-1", 10*time.Second); err != nil {
+		t.Fatalf("续期失败: %v", err)
+	}
+	if ttl := mr.TTL(locker.metaKey(key)); ttl != redisMetaTTL {
+		t.Fatalf("续期后 meta 的过期时间应刷新为 %v，实际 %v", redisMetaTTL, ttl)
+	}
+
+	// 释放后 token 计数保留，闲置超过 redisMetaTTL 后 meta 被删除
+	if err := locker.Unlock(key, "owner-1"); err != nil {
+		t.Fatalf("释放锁失败: %v", err)
+	}
+	if current, _ := locker.CurrentToken(key); current != 1 {
+		t.Fatalf("释放后 token 计数应保留，实际 %d", current)
+	}
+	mr.FastForward(redisMetaTTL)
+	if mr.Exists(locker.metaKey(key)) {
+		t.Fatal("闲置超过 redisMetaTTL 后 meta 应过期")
+	}
+}
+
+func TestRedisLocker_ListLocksAndForceUnlock(t *testing.T) {
+	locker, mr := setupRedisLocker(t)
+
+	for _, l := range []struct{ key, owner string }{
+		{"list:1:a", "owner-list-1"},
+		{"list:1:b", "owner-list-2"},
+		{"list*:1:c", "owner-list-3"},
+		{"other:1:a", "owner-list-4"},
+	} {
+		if _, err := locker.TryLock(l.key, l.owner, 10*time.Second); err != nil {
+			t.Fatalf("获取锁 %s 失败: %v", l.key, err)
+		}
+	}
+	// 已释放的锁只剩 meta，不应被列出
+	if _, err := locker.TryLock("list:1:released", "owner-list-5", 10*time.Second); err != nil {
+		t.Fatalf("获取锁失败: %v", err)
+	}
+	if err := locker.Unlock("list:1:released", "owner-list-5"); err != nil {
+		t.Fatalf("释放锁失败: %v", err)
+	}
+
+	mr.FastForward(time.Second)
+	locks, err := locker.ListLocks("list:")
+	if err != nil {
+		t.Fatalf("ListLocks 失败: %v", err)
+	}
+	byKey := make(map[string]LockInfo, len(locks))
+	for _, l := range locks {
+		byKey[l.Key] = l
+	}
+	if len(locks) != 2 || byKey["list:1:b"].OwnerID != "owner-list-2" {
+		t.Fatalf("应列出 list:1:a 和 list:1:b，实际: %+v", locks)
+	}
+	if a := byKey["list:1:a"]; a.OwnerID != "owner-list-1" || a.Token != 1 || a.Expired || a.AcquiredAt.IsZero() {
+		t.Fatalf("锁信息不正确: %+v", a)
+	}
+
+	// 前缀中的通配符按字面匹配
+	if locks, err := locker.ListLocks("list*"); err != nil || len(locks) != 1 || locks[0].Key != "list*:1:c" {
+		t.Fatalf("通配符应被转义，实际: %+v %v", locks, err)
+	}
+
+	// 不需要持有者即可强制释放，token 计数保留
+	if err := locker.ForceUnlock("list:1:a"); err != nil {
+		t.Fatalf("ForceUnlock 失败: %v", err)
+	}
+	if err := locker.ForceUnlock("list:1:a"); !errors.Is(err, ErrLockNotFound) {
+		t.Fatalf("重复 ForceUnlock 应返回 ErrLockNotFound，实际: %v", err)
+	}
+	if token, err := locker.TryLock("list:1:a", "owner-list-6", 10*time.Second); err != nil || token != 2 {
+		t.Fatalf("强制释放后重新获取应得到 token 2，实际: %d %v", token, err)
+	}
+	if locks, _ := locker.ListLocks("list:"); len(locks) != 2 {
+		t.Fatalf("期望列出 2 个锁，实际 %d 个", len(locks))
+	}
+}
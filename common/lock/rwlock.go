@@ -0,0 +1,309 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+/*
+CREATE TABLE `distributed_lock_holders` (
+	`id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+	`lock_key` VARCHAR(255) NOT NULL COMMENT '锁的唯一键',
+	`owner_id` VARCHAR(128) NOT NULL COMMENT '锁持有者的唯一标识',
+	`mode` VARCHAR(16) NOT NULL COMMENT 'shared / exclusive',
+	`hold_count` INT NOT NULL DEFAULT 1 COMMENT '同一持有者的重入次数',
+	`fencing_token` BIGINT UNSIGNED NOT NULL DEFAULT 0 COMMENT '获取锁时的 fencing token',
+	`expires_at` TIMESTAMP(3) NOT NULL COMMENT '锁的过期时间',
+	`created_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	`updated_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+	PRIMARY KEY (`id`),
+	UNIQUE KEY `uk_lock_owner` (`lock_key`, `owner_id`),
+	KEY `idx_expires_at` (`expires_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='分布式读写锁持有者表';
+*/
+
+// LockMode 锁模式
+type LockMode string
+
+const (
+	ModeExclusive LockMode = "exclusive" // 独占：同一时刻只有一个持有者
+	ModeShared    LockMode = "shared"    // 共享：可有多个持有者，但与独占互斥
+)
+
+var ErrInvalidMode = errors.New("invalid lock mode")
+
+// Hold 某个持有者对锁的持有情况
+type Hold struct {
+	Key       string    `json:"key"`
+	OwnerID   string    `json:"owner_id"`
+	Mode      LockMode  `json:"mode"`
+	Count     int       `json:"count"`
+	Token     int64     `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// RWLocker 支持共享/独占模式和重入的分布式锁，MySQL 和内存后端实现了该接口，Redis 后端不支持
+// 与同一后端的 Locker 共用键空间：其他 owner 通过 Locker 持有的锁视为独占，
+// 存在读写锁的其他持有者时 Locker 也无法获取，因此同一个键可以混用两套接口
+type RWLocker interface {
+	// TryAcquire 以指定模式尝试获取锁（非阻塞）
+	// 同一持有者重复获取时持有计数加一；唯一的共享持有者可以升级为独占
+	TryAcquire(key, ownerID string, mode LockMode, duration time.Duration) (Hold, error)
+
+	// AcquireContext 阻塞获取锁，按指数退避重试，ctx 取消或超时时返回 *LockTimeoutError
+	AcquireContext(ctx context.Context, key, ownerID string, mode LockMode, duration time.Duration) (Hold, error)
+
+	// Release 持有计数减一，减到 0 时释放锁，返回剩余计数
+	Release(key, ownerID string) (int, error)
+
+	// RenewHold 续期持有者的锁
+	RenewHold(key, ownerID string, duration time.Duration) error
+
+	// Holders 列出锁当前未过期的持有者
+	Holders(key string) ([]Hold, error)
+}
+
+// LockHolder 读写锁持有者模型
+type LockHolder struct {
+	ID           int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	LockKey      string    `gorm:"uniqueIndex:uk_lock_owner;size:255;not null" json:"lock_key"`
+	OwnerID      string    `gorm:"uniqueIndex:uk_lock_owner;size:128;not null" json:"owner_id"`
+	Mode         string    `gorm:"size:16;not null" json:"mode"`
+	HoldCount    int       `gorm:"column:hold_count;not null" json:"hold_count"`
+	FencingToken int64     `gorm:"column:fencing_token;not null" json:"fencing_token"`
+	ExpiresAt    time.Time `gorm:"index:idx_expires_at;not null" json:"expires_at"`
+	CreatedAt    time.Time `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt    time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// TableName 指定表名
+func (LockHolder) TableName() string {
+	return "platform_offline.distributed_lock_holders"
+}
+
+func (h LockHolder) toHold() Hold {
+	return Hold{
+		Key:       h.LockKey,
+		OwnerID:   h.OwnerID,
+		Mode:      LockMode(h.Mode),
+		Count:     h.HoldCount,
+		Token:     h.FencingToken,
+		ExpiresAt: h.ExpiresAt,
+	}
+}
+
+// NewMySQLRWLocker 创建新的MySQL读写锁实例，使用 platform_db 连接
+func NewMySQLRWLocker() *MySQLLocker {
+	return NewMySQLLocker()
+}
+
+// checkAcquire 根据当前未过期的持有者判断 ownerID 能否以 mode 获取锁
+// 返回 ownerID 已有的持有记录（没有则为 nil）
+func checkAcquire(holders []Hold, ownerID string, mode LockMode) (*Hold, error) {
+	if mode != ModeShared && mode != ModeExclusive {
+		return nil, ErrInvalidMode
+	}
+
+	var mine *Hold
+	for i := range holders {
+		h := &holders[i]
+		if h.OwnerID == ownerID {
+			mine = h
+			continue
+		}
+		// 独占需要没有其他持有者；共享只要求其他持有者都不是独占
+		if mode == ModeExclusive || h.Mode == ModeExclusive {
+			return nil, ErrLockExists
+		}
+	}
+	return mine, nil
+}
+
+// TryAcquire 以指定模式尝试获取锁（非阻塞）
+func (m *MySQLLocker) TryAcquire(key, ownerID string, mode LockMode, duration time.Duration) (Hold, error) {
+	if duration <= 0 {
+		return Hold{}, ErrInvalidDuration
+	}
+
+	var hold Hold
+	err := m.db.Transaction(func(tx *gorm.DB) error {
+		// 以 token 计数行作为同一个键的互斥点，串行化持有者的变更
+		if err := tx.Exec(
+			"INSERT IGNORE INTO platform_offline.distributed_lock_tokens (lock_key, token) VALUES (?, 0)",
+			key,
+		).Error; err != nil {
+			return err
+		}
+		var t LockToken
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("lock_key = ?", key).First(&t).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		if err := tx.Where("lock_key = ? AND expires_at < ?", key, now).Delete(&LockHolder{}).Error; err != nil {
+			return err
+		}
+
+		// 其他 owner 通过 Locker 持有的锁视为独占
+		var plain int64
+		err := tx.Model(&DistributedLock{}).
+			Where("lock_key = ? AND owner_id <> ? AND expires_at > ?", key, ownerID, now).
+			Count(&plain).Error
+		if err != nil {
+			return err
+		}
+		if plain > 0 {
+			return ErrLockExists
+		}
+
+		var rows []LockHolder
+		if err := tx.Where("lock_key = ?", key).Find(&rows).Error; err != nil {
+			return err
+		}
+		holders := make([]Hold, 0, len(rows))
+		for _, r := range rows {
+			holders = append(holders, r.toHold())
+		}
+
+		mine, err := checkAcquire(holders, ownerID, mode)
+		if err != nil {
+			return err
+		}
+
+		expiresAt := now.Add(duration).Truncate(time.Millisecond)
+		if mine != nil {
+			// 重入：计数加一；共享升级为独占时分配新 token
+			updates := map[string]interface{}{
+				"hold_count": mine.Count + 1,
+				"expires_at": expiresAt,
+			}
+			hold = *mine
+			hold.Count++
+			hold.ExpiresAt = expiresAt
+			if mode == ModeExclusive && mine.Mode == ModeShared {
+				next, err := nextFencingToken(tx, key)
+				if err != nil {
+					return err
+				}
+				updates["mode"] = string(ModeExclusive)
+				updates["fencing_token"] = next
+				hold.Mode = ModeExclusive
+				hold.Token = next
+			}
+			return tx.Model(&LockHolder{}).
+				Where("lock_key = ? AND owner_id = ?", key, ownerID).
+				Updates(updates).Error
+		}
+
+		// 新持有者：独占分配新 token，共享沿用当前 token
+		token := t.Token
+		if mode == ModeExclusive {
+			next, err := nextFencingToken(tx, key)
+			if err != nil {
+				return err
+			}
+			token = next
+		}
+		row := LockHolder{
+			LockKey:      key,
+			OwnerID:      ownerID,
+			Mode:         string(mode),
+			HoldCount:    1,
+			FencingToken: token,
+			ExpiresAt:    expiresAt,
+		}
+		if err := tx.Create(&row).Error; err != nil {
+			return err
+		}
+		hold = row.toHold()
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, ErrLockExists) || errors.Is(err, ErrInvalidMode) {
+			return Hold{}, err
+		}
+		return Hold{}, fmt.Errorf("database error: %w", err)
+	}
+	return hold, nil
+}
+
+// AcquireContext 阻塞获取锁（按退避策略重试，受 ctx 控制）
+func (m *MySQLLocker) AcquireContext(ctx context.Context, key, ownerID string, mode LockMode, duration time.Duration) (Hold, error) {
+	return lockWithBackoff(ctx, key, func() (Hold, error) {
+		return m.TryAcquire(key, ownerID, mode, duration)
+	})
+}
+
+// Release 持有计数减一，减到 0 时释放锁
+func (m *MySQLLocker) Release(key, ownerID string) (int, error) {
+	remaining := 0
+	err := m.db.Transaction(func(tx *gorm.DB) error {
+		var row LockHolder
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("lock_key = ? AND owner_id = ?", key, ownerID).
+			First(&row).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrLockNotFound
+			}
+			return err
+		}
+
+		if row.HoldCount > 1 {
+			remaining = row.HoldCount - 1
+			return tx.Model(&LockHolder{}).
+				Where("id = ?", row.ID).
+				Update("hold_count", remaining).Error
+		}
+		return tx.Delete(&LockHolder{}, row.ID).Error
+	})
+	if err != nil {
+		if errors.Is(err, ErrLockNotFound) {
+			return 0, err
+		}
+		return 0, fmt.Errorf("database error: %w", err)
+	}
+	return remaining, nil
+}
+
+// RenewHold 续期持有者的锁
+func (m *MySQLLocker) RenewHold(key, ownerID string, duration time.Duration) error {
+	if duration <= 0 {
+		return ErrInvalidDuration
+	}
+
+	now := time.Now()
+	result := m.db.Model(&LockHolder{}).
+		Where("lock_key = ? AND owner_id = ? AND expires_at > ?", key, ownerID, now).
+		Update("expires_at", now.Add(duration).Truncate(time.Millisecond))
+	if result.Error != nil {
+		return fmt.Errorf("database error: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrLockNotFound
+	}
+	return nil
+}
+
+// Holders 列出锁当前未过期的持有者
+func (m *MySQLLocker) Holders(key string) ([]Hold, error) {
+	var rows []LockHolder
+	err := m.db.
+		Where("lock_key = ? AND expires_at > ?", key, time.Now().Truncate(time.Millisecond)).
+		Order("created_at").
+		Find(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+
+	res := make([]Hold, 0, len(rows))
+	for _, r := range rows {
+		res = append(res, r.toHold())
+	}
+	return res, nil
+}
@@ -0,0 +1,187 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+// DefaultLeaseTTL 心跳模式下默认的租约时长，持有者每 TTL/3 续期一次
+const DefaultLeaseTTL = time.Minute
+
+// Session 带心跳续期的锁会话
+// 获取锁后在后台定期调用 Renew 续期，续期失败或失去所有权时取消 Context
+type Session struct {
+	locker  Locker
+	key     string
+	ownerID string
+	ttl     time.Duration
+	token   int64
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	stop   chan struct{}
+	done   chan struct{}
+
+	mu        sync.Mutex
+	err       error
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// LockWithHeartbeat 尝试获取锁（非阻塞），成功后启动后台心跳续期
+// ttl 为每次续期的租约时长，<= 0 时使用 DefaultLeaseTTL
+func LockWithHeartbeat(ctx context.Context, locker Locker, key, ownerID string, ttl time.Duration) (*Session, error) {
+	if ttl <= 0 {
+		ttl = DefaultLeaseTTL
+	}
+
+	token, err := locker.TryLock(key, ownerID, ttl)
+	if err != nil {
+		return nil, err
+	}
+
+	return startSession(ctx, locker, key, ownerID, ttl, token), nil
+}
+
+// WaitLockWithHeartbeat 阻塞获取锁（最多等待 wait），成功后启动后台心跳续期
+// 等待超时返回 *LockTimeoutError；会话的 Context 派生自 ctx，不受 wait 影响
+func WaitLockWithHeartbeat(ctx context.Context, locker Locker, key, ownerID string, ttl, wait time.Duration) (*Session, error) {
+	if ttl <= 0 {
+		ttl = DefaultLeaseTTL
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, wait)
+	defer cancel()
+
+	token, err := LockContext(waitCtx, locker, key, ownerID, ttl)
+	if err != nil {
+		return nil, err
+	}
+
+	return startSession(ctx, locker, key, ownerID, ttl, token), nil
+}
+
+// startSession 在锁已经获取成功后启动心跳协程
+func startSession(ctx context.Context, locker Locker, key, ownerID string, ttl time.Duration, token int64) *Session {
+	sctx, cancel := context.WithCancel(ctx)
+	s := &Session{
+		locker:  locker,
+		key:     key,
+		ownerID: ownerID,
+		ttl:     ttl,
+		token:   token,
+		ctx:     sctx,
+		cancel:  cancel,
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go s.heartbeat()
+	return s
+}
+
+// Context 返回会话的 Context，锁丢失或会话关闭时会被取消
+func (s *Session) Context() context.Context {
+	return s.ctx
+}
+
+// Key 返回锁的键
+func (s *Session) Key() string {
+	return s.key
+}
+
+// OwnerID 返回锁持有者
+func (s *Session) OwnerID() string {
+	return s.ownerID
+}
+
+// Token 返回获取锁时分配的 fencing token
+func (s *Session) Token() int64 {
+	return s.token
+}
+
+// Fence 返回用于写入校验的 fencing 凭证，只有 MySQL 后端的锁可以在写入事务中校验
+func (s *Session) Fence() Fence {
+	_, durable := s.locker.(*MySQLLocker)
+	return Fence{Key: s.key, Token: s.token, Durable: durable}
+}
+
+// Err 返回导致会话失效的续期错误，会话正常时返回 nil
+func (s *Session) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// Close 停止心跳并释放锁，可重复调用
+func (s *Session) Close() error {
+	s.closeOnce.Do(func() {
+		close(s.stop)
+		<-s.done
+		s.cancel()
+
+		// 锁已经丢失时不再尝试释放，避免误删其他持有者的锁
+		if s.Err() != nil {
+			return
+		}
+		if err := s.locker.Unlock(s.key, s.ownerID); err != nil && !errors.Is(err, ErrLockNotFound) {
+			s.closeErr = err
+		}
+	})
+	return s.closeErr
+}
+
+// heartbeat 后台续期循环
+func (s *Session) heartbeat() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(s.ttl / 3)
+	defer ticker.Stop()
+
+	lastRenew := time.Now()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+			err := s.locker.Renew(s.key, s.ownerID, s.ttl)
+			if err == nil {
+				lastRenew = time.Now()
+				continue
+			}
+
+			// 所有权已丢失，立即失效
+			if isOwnershipLost(err) {
+				s.fail(err)
+				return
+			}
+
+			// 临时错误（如数据库抖动），在租约过期前继续重试
+			log.Printf("[lock] 续期失败，稍后重试 key=%s owner=%s: %v", s.key, s.ownerID, err)
+			if time.Since(lastRenew) >= s.ttl {
+				s.fail(err)
+				return
+			}
+		}
+	}
+}
+
+// fail 记录错误并取消会话 Context
+func (s *Session) fail(err error) {
+	log.Printf("[lock] 锁已丢失 key=%s owner=%s: %v", s.key, s.ownerID, err)
+	s.mu.Lock()
+	s.err = err
+	s.mu.Unlock()
+	s.cancel()
+}
+
+// isOwnershipLost 判断续期错误是否表示锁已不再属于当前持有者
+func isOwnershipLost(err error) bool {
+	return errors.Is(err, ErrLockNotFound) ||
+		errors.Is(err, ErrNotLockOwner) ||
+		errors.Is(err, ErrLockExpired)
+}
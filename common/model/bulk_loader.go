@@ -0,0 +1,312 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"sync"
+	"time"
+)
+
+// BulkLoader 默认值
+const (
+	DefaultBulkMaxBytes      = 4 << 20 // 4MB
+	DefaultBulkFlushInterval = 5 * time.Second
+)
+
+// ErrLoaderClosed Close 之后继续写入
+var ErrLoaderClosed = errors.New("bulk loader closed")
+
+// FlushResult 一次 flush 的结果
+type FlushResult struct {
+	Seq     int           // 第几次 flush，从 1 开始
+	Rows    int           // 本批行数
+	Bytes   int           // 本批字节数，按 BulkLoaderOptions.Size 计算
+	Reason  string        // 触发原因：rows、bytes、interval、close
+	Elapsed time.Duration // flush 函数的耗时
+	Err     error
+}
+
+// BulkStats 累计结果
+type BulkStats struct {
+	Rows    int   // 成功写入的行数
+	Bytes   int64 // 成功写入的字节数
+	Flushes int   // flush 次数
+	Failed  int   // 失败的 flush 次数
+	Dropped int   // 失败的 flush 中丢弃的行数
+}
+
+func (s BulkStats) String() string {
+	return fmt.Sprintf("写入 %d 行 %d 字节, flush %d 次, 失败 %d 次, 丢弃 %d 行", s.Rows, s.Bytes, s.Flushes, s.Failed, s.Dropped)
+}
+
+// BulkLoaderOptions 批量写入配置，任一阈值达到即 flush
+type BulkLoaderOptions[T any] struct {
+	MaxRows       int           // 每批最多行数，为 0 时使用 DefaultRawBatchSize
+	MaxBytes      int           // 每批最多字节数，为 0 时使用 DefaultBulkMaxBytes；需要设置 Size 才生效
+	FlushInterval time.Duration // 第一行进入缓冲后最多等待的时间，为 0 时使用 DefaultBulkFlushInterval，小于 0 时不按时间 flush
+	Buffer        int           // 等待 flush 的队列长度，队列满时 Add 阻塞，为 0 时与 MaxRows 相同
+
+	Size func(row T) int // 行的字节数，为空时不按字节数 flush
+
+	// ContinueOnError 为 false 时第一次 flush 失败后停止，之后的 Add 返回该错误；
+	// 为 true 时丢弃失败的批次继续写入（之后不再执行 checkpoint），错误在 Close 时汇总返回
+	ContinueOnError bool
+
+	// OnFlush 每次 flush 后在写入的 goroutine 中调用，可用于记录日志和监控
+	OnFlush func(FlushResult)
+}
+
+// BulkLoader 内存有界的流式批量写入器
+// 生产者通过 Add、LoadSeq 或 LoadChan 逐行写入，后台 goroutine 按行数、字节数或时间分批调用 flush 函数。
+// flush 期间队列满了 Add 会阻塞，因此内存中最多只有一批加一个队列的数据。
+// flush 函数不能持有传入的切片，返回后切片会被复用
+type BulkLoader[T any] struct {
+	flush func([]T) error
+	opts  BulkLoaderOptions[T]
+
+	in   chan bulkOp[T]
+	done chan struct{}
+
+	closeOnce sync.Once
+	closed    chan struct{}
+
+	// 以下字段只在后台 goroutine 中修改，done 关闭后可以读取
+	batch       []T
+	bytes       int
+	seq         int
+	checkpoints []func()
+	tainted     bool // 有 flush 失败过，之后不再执行任何 checkpoint
+	stats       BulkStats
+	errs        []error
+	fatal       error
+}
+
+// bulkOp 队列中的一项：一行数据或一个 checkpoint
+type bulkOp[T any] struct {
+	row        T
+	checkpoint func()
+}
+
+// NewBulkLoader 创建写入器并启动后台 goroutine，用完必须调用 Close
+func NewBulkLoader[T any](flush func([]T) error, opts BulkLoaderOptions[T]) *BulkLoader[T] {
+	if opts.MaxRows <= 0 {
+		opts.MaxRows = DefaultRawBatchSize
+	}
+	if opts.MaxBytes <= 0 {
+		opts.MaxBytes = DefaultBulkMaxBytes
+	}
+	if opts.FlushInterval == 0 {
+		opts.FlushInterval = DefaultBulkFlushInterval
+	}
+	if opts.Buffer <= 0 {
+		opts.Buffer = opts.MaxRows
+	}
+	l := &BulkLoader[T]{
+		flush:  flush,
+		opts:   opts,
+		in:     make(chan bulkOp[T], opts.Buffer),
+		done:   make(chan struct{}),
+		closed: make(chan struct{}),
+		batch:  make([]T, 0, opts.MaxRows),
+	}
+	go l.run()
+	return l
+}
+
+// Add 写入一行，队列满时阻塞直到有空间、ctx 取消或写入器停止
+func (l *BulkLoader[T]) Add(ctx context.Context, row T) error {
+	return l.send(ctx, bulkOp[T]{row: row})
+}
+
+// Checkpoint 在之前 Add 的所有行都成功写入后调用 fn，用于保存同步进度
+// 任何一批 flush 失败后，之后的所有 checkpoint 都不会被调用：
+// 进度是单调推进的，跳过失败的行继续保存进度会让这些行永远不会被重新同步
+func (l *BulkLoader[T]) Checkpoint(ctx context.Context, fn func()) error {
+	return l.send(ctx, bulkOp[T]{checkpoint: fn})
+}
+
+func (l *BulkLoader[T]) send(ctx context.Context, op bulkOp[T]) error {
+	select {
+	case <-l.closed:
+		return ErrLoaderClosed
+	default:
+	}
+	select {
+	case <-l.done:
+		return l.fatal
+	default:
+	}
+	select {
+	case l.in <- op:
+		return nil
+	case <-l.done:
+		return l.fatal
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// LoadSeq 把迭代器中的所有行写入，出错时停止迭代
+func (l *BulkLoader[T]) LoadSeq(ctx context.Context, seq iter.Seq[T]) error {
+	for row := range seq {
+		if err := l.Add(ctx, row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadChan 把 channel 中的所有行写入，直到 channel 关闭
+func (l *BulkLoader[T]) LoadChan(ctx context.Context, ch <-chan T) error {
+	for {
+		select {
+		case row, ok := <-ch:
+			if !ok {
+				return nil
+			}
+			if err := l.Add(ctx, row); err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Close 写入剩余的数据并等待后台 goroutine 退出，返回累计结果和所有 flush 错误
+// 调用 Close 后不能再调用 Add
+func (l *BulkLoader[T]) Close() (BulkStats, error) {
+	l.closeOnce.Do(func() {
+		close(l.closed)
+		close(l.in)
+	})
+	<-l.done
+	return l.stats, errors.Join(l.errs...)
+}
+
+func (l *BulkLoader[T]) run() {
+	defer close(l.done)
+
+	var timer *time.Timer
+	var timeout <-chan time.Time
+	stopTimer := func() {
+		if timer != nil {
+			timer.Stop()
+		}
+		timeout = nil
+	}
+	defer stopTimer()
+
+	for {
+		select {
+		case op, ok := <-l.in:
+			if !ok {
+				l.doFlush("close")
+				return
+			}
+			if op.checkpoint != nil {
+				l.addCheckpoint(op.checkpoint)
+				continue
+			}
+
+			l.batch = append(l.batch, op.row)
+			if l.opts.Size != nil {
+				l.bytes += l.opts.Size(op.row)
+			}
+			if len(l.batch) == 1 && l.opts.FlushInterval > 0 {
+				timer = time.NewTimer(l.opts.FlushInterval)
+				timeout = timer.C
+			}
+
+			reason := ""
+			switch {
+			case len(l.batch) >= l.opts.MaxRows:
+				reason = "rows"
+			case l.opts.Size != nil && l.bytes >= l.opts.MaxBytes:
+				reason = "bytes"
+			default:
+				continue
+			}
+			stopTimer()
+			if !l.doFlush(reason) {
+				return
+			}
+		case <-timeout:
+			timeout = nil
+			if !l.doFlush("interval") {
+				return
+			}
+		}
+	}
+}
+
+// addCheckpoint 缓冲为空时立即执行，否则等下一次 flush 成功后执行
+func (l *BulkLoader[T]) addCheckpoint(fn func()) {
+	if l.tainted {
+		// 之前有行写入失败，保存之后的进度会跳过这些行
+		return
+	}
+	if len(l.batch) == 0 {
+		fn()
+		return
+	}
+	l.checkpoints = append(l.checkpoints, fn)
+}
+
+// doFlush 写入当前缓冲，返回 false 表示需要停止
+func (l *BulkLoader[T]) doFlush(reason string) bool {
+	if len(l.batch) == 0 {
+		return true
+	}
+	l.seq++
+	res := FlushResult{Seq: l.seq, Rows: len(l.batch), Bytes: l.bytes, Reason: reason}
+	start := time.Now()
+	res.Err = l.flush(l.batch)
+	res.Elapsed = time.Since(start)
+
+	l.stats.Flushes++
+	checkpoints := l.checkpoints
+	l.checkpoints = nil
+	if res.Err == nil {
+		l.stats.Rows += res.Rows
+		l.stats.Bytes += int64(res.Bytes)
+		for _, fn := range checkpoints {
+			fn()
+		}
+	} else {
+		res.Err = fmt.Errorf("flush #%d (%d rows): %w", res.Seq, res.Rows, res.Err)
+		l.stats.Failed++
+		l.stats.Dropped += res.Rows
+		l.errs = append(l.errs, res.Err)
+		l.tainted = true
+	}
+
+	clear(l.batch)
+	l.batch = l.batch[:0]
+	l.bytes = 0
+	if l.opts.OnFlush != nil {
+		l.opts.OnFlush(res)
+	}
+
+	if res.Err != nil && !l.opts.ContinueOnError {
+		l.fatal = res.Err
+		return false
+	}
+	return true
+}
+
+// RawFlush 用 RawWriter 写入嵌入了 AirbyteRawData 的表结构，作为 BulkLoader 的 flush 函数
+func RawFlush[T RawRecord](w *RawWriter) func([]T) error {
+	return func(rows []T) error {
+		_, err := WriteRaw(w, rows)
+		return err
+	}
+}
+
+// RawSize 原始数据行的大致字节数，作为 BulkLoaderOptions.Size
+func RawSize[T RawRecord](row T) int {
+	r := row.Raw()
+	return len(r.AirbyteData) + len(r.AirbyteMeta) + len(r.AirbyteRawId)
+}
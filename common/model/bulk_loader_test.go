@@ -0,0 +1,163 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestBulkLoaderThresholds(t *testing.T) {
+	var batches [][]int
+	var reasons []string
+	l := NewBulkLoader(func(rows []int) error {
+		batches = append(batches, slices.Clone(rows))
+		return nil
+	}, BulkLoaderOptions[int]{
+		MaxRows:       3,
+		MaxBytes:      10,
+		FlushInterval: -1,
+		Size:          func(row int) int { return row },
+		OnFlush:       func(r FlushResult) { reasons = append(reasons, r.Reason) },
+	})
+	ctx := context.Background()
+	if err := l.LoadSeq(ctx, slices.Values([]int{1, 1, 1, 6, 6, 1})); err != nil {
+		t.Fatal(err)
+	}
+	stats, err := l.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]int{{1, 1, 1}, {6, 6}, {1}}
+	if !slices.EqualFunc(batches, want, slices.Equal) || !slices.Equal(reasons, []string{"rows", "bytes", "close"}) {
+		t.Fatalf("分批不符合预期: %v %v", batches, reasons)
+	}
+	if stats != (BulkStats{Rows: 6, Bytes: 16, Flushes: 3}) {
+		t.Fatalf("统计不符合预期: %+v", stats)
+	}
+	if err := l.Add(ctx, 1); !errors.Is(err, ErrLoaderClosed) {
+		t.Fatalf("Close 之后 Add 应当失败: %v", err)
+	}
+}
+
+func TestBulkLoaderInterval(t *testing.T) {
+	flushed := make(chan FlushResult, 1)
+	l := NewBulkLoader(func([]int) error { return nil }, BulkLoaderOptions[int]{
+		FlushInterval: 10 * time.Millisecond,
+		OnFlush:       func(r FlushResult) { flushed <- r },
+	})
+	defer l.Close()
+
+	ch := make(chan int, 1)
+	ch <- 1
+	close(ch)
+	if err := l.LoadChan(context.Background(), ch); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case r := <-flushed:
+		if r.Reason != "interval" || r.Rows != 1 {
+			t.Fatalf("flush 结果不符合预期: %+v", r)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("没有按时间 flush")
+	}
+}
+
+func TestBulkLoaderBackpressure(t *testing.T) {
+	release := make(chan struct{})
+	l := NewBulkLoader(func([]int) error {
+		<-release
+		return nil
+	}, BulkLoaderOptions[int]{MaxRows: 1, Buffer: 1, FlushInterval: -1})
+
+	// 第一行在 flush 中阻塞，第二行占满队列，第三行等待
+	ctx := context.Background()
+	l.Add(ctx, 1)
+	l.Add(ctx, 2)
+	timeout, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if err := l.Add(timeout, 3); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("队列满时 Add 应当阻塞: %v", err)
+	}
+
+	close(release)
+	stats, err := l.Close()
+	if err != nil || stats.Rows != 2 {
+		t.Fatalf("Close 结果不符合预期: %+v %v", stats, err)
+	}
+}
+
+func TestBulkLoaderErrors(t *testing.T) {
+	boom := errors.New("boom")
+	ctx := context.Background()
+
+	// 默认第一次失败后停止
+	l := NewBulkLoader(func([]int) error { return boom }, BulkLoaderOptions[int]{MaxRows: 1, FlushInterval: -1})
+	l.Add(ctx, 1)
+	var err error
+	for i := 0; i < 100 && err == nil; i++ {
+		err = l.Add(ctx, 2)
+	}
+	if !errors.Is(err, boom) {
+		t.Fatalf("失败后 Add 应当返回 flush 错误: %v", err)
+	}
+	if _, err := l.Close(); !errors.Is(err, boom) {
+		t.Fatalf("Close 应当返回 flush 错误: %v", err)
+	}
+
+	// ContinueOnError：失败的批次丢弃，失败之后的 checkpoint 都不执行
+	var saved []string
+	l = NewBulkLoader(func(rows []int) error {
+		if slices.Contains(rows, -1) {
+			return boom
+		}
+		return nil
+	}, BulkLoaderOptions[int]{MaxRows: 2, FlushInterval: -1, ContinueOnError: true})
+	checkpoint := func(name string) {
+		if err := l.Checkpoint(ctx, func() { saved = append(saved, name) }); err != nil {
+			t.Fatal(err)
+		}
+	}
+	l.Add(ctx, 1)
+	checkpoint("a") // 与 2 一起写入
+	l.Add(ctx, 2)
+	l.Add(ctx, 3)
+	l.Add(ctx, -1) // 失败
+	l.Add(ctx, 4)
+	checkpoint("b") // 3 已丢弃
+	l.Add(ctx, 5)
+	checkpoint("c") // 之前有批次失败，仍然不保存
+	stats, err := l.Close()
+	if !errors.Is(err, boom) {
+		t.Fatalf("Close 应当返回 flush 错误: %v", err)
+	}
+	if !slices.Equal(saved, []string{"a"}) {
+		t.Fatalf("checkpoint 不符合预期: %v", saved)
+	}
+	if stats != (BulkStats{Rows: 4, Flushes: 3, Failed: 1, Dropped: 2}) {
+		t.Fatalf("统计不符合预期: %+v", stats)
+	}
+}
+
+func TestBulkLoaderRaw(t *testing.T) {
+	gdb := sqliteRawDB(t)
+	w := NewRawWriter(RawWriterOptions{DB: gdb, Generation: 1})
+	l := NewBulkLoader(RawFlush[rawTestItem](w), BulkLoaderOptions[rawTestItem]{
+		MaxRows: 2,
+		Size:    RawSize[rawTestItem],
+	})
+	ctx := context.Background()
+	for _, id := range []string{"a", "b", "c"} {
+		if err := l.Add(ctx, item(1, id, `{}`)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if c := w.Counts()[testRawTable]; c.Inserted != 3 {
+		t.Fatalf("写入结果不符合预期: %+v", c)
+	}
+}
@@ -0,0 +1,208 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrFullRefreshDone FullRefresh 已经 Commit 或 Abort
+var ErrFullRefreshDone = errors.New("full refresh already finished")
+
+// FullRefreshOptions 全量刷新的配置
+type FullRefreshOptions struct {
+	// HardDeleteAfter 墓碑行保留的时长，超过后在 Commit 时物理删除；为 0 时只标记不删除
+	HardDeleteAfter time.Duration
+	// AllowEmpty 为 false 时，没有写入任何行的 Commit 不标记墓碑，避免接口异常返回空列表时把数据全部标记删除
+	AllowEmpty bool
+	// KeyPrefix 刷新的范围：只处理 _airbyte_raw_id 以此开头的行，写入的行也必须以此开头。
+	// 同一租户在一张表中有多个账户（或多个 stream）的数据时必须设置，否则会把其他账户的行标记为墓碑
+	KeyPrefix string
+}
+
+// RefreshResult Commit 的结果
+type RefreshResult struct {
+	Tombstoned int // 本次新标记墓碑的行
+	Deleted    int // 超过保留时长被物理删除的行
+}
+
+func (r RefreshResult) String() string {
+	return fmt.Sprintf("标记删除 %d, 物理删除 %d", r.Tombstoned, r.Deleted)
+}
+
+// FullRefresh 一个租户在一张表上（可以用 KeyPrefix 缩小范围）的全量刷新
+// 通过 Write 写入上游完整列表中的所有行，Commit 时把范围内没有出现过的行标记为墓碑：
+// _airbyte_meta 中 tombstone 为 true，deleted_at 为标记时间；再次出现的行写入时会清除标记。
+// 只有确认拿到了完整列表才能 Commit，拉取失败时调用 Abort 或直接丢弃
+type FullRefresh struct {
+	ctx      context.Context
+	w        *RawWriter
+	table    string
+	tenantId int64
+	opts     FullRefreshOptions
+
+	mu   sync.Mutex
+	seen map[string]struct{}
+	done bool
+}
+
+// BeginFullRefresh 开始 tenantId 在 table 上的全量刷新
+func (w *RawWriter) BeginFullRefresh(table string, tenantId int64, opts FullRefreshOptions) *FullRefresh {
+	return w.BeginFullRefreshContext(context.Background(), table, tenantId, opts)
+}
+
+// BeginFullRefreshContext 同 BeginFullRefresh，Write 和 Commit 中的数据库操作受 ctx 控制
+func (w *RawWriter) BeginFullRefreshContext(ctx context.Context, table string, tenantId int64, opts FullRefreshOptions) *FullRefresh {
+	return &FullRefresh{
+		ctx:      ctx,
+		w:        w,
+		table:    table,
+		tenantId: tenantId,
+		opts:     opts,
+		seen:     make(map[string]struct{}),
+	}
+}
+
+// Write 写入完整列表中的一部分，可以多次调用；行的租户必须与 BeginFullRefresh 一致
+func (f *FullRefresh) Write(rows []AirbyteRawData) (WriteCounts, error) {
+	f.mu.Lock()
+	if f.done {
+		f.mu.Unlock()
+		return WriteCounts{}, ErrFullRefreshDone
+	}
+	for _, r := range rows {
+		if r.TenantId != f.tenantId {
+			f.mu.Unlock()
+			return WriteCounts{}, fmt.Errorf("full refresh %s: tenant %d, got row of tenant %d", f.table, f.tenantId, r.TenantId)
+		}
+		if !strings.HasPrefix(r.AirbyteRawId, f.opts.KeyPrefix) {
+			f.mu.Unlock()
+			return WriteCounts{}, fmt.Errorf("full refresh %s: row %q is outside scope %q", f.table, r.AirbyteRawId, f.opts.KeyPrefix)
+		}
+	}
+	for _, r := range rows {
+		f.seen[r.AirbyteRawId] = struct{}{}
+	}
+	f.mu.Unlock()
+
+	return f.w.WriteContext(f.ctx, f.table, rows)
+}
+
+// Abort 放弃本次刷新，不标记任何墓碑
+func (f *FullRefresh) Abort() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.done = true
+}
+
+// Commit 标记没有出现的行，并物理删除超过保留时长的墓碑行
+func (f *FullRefresh) Commit() (RefreshResult, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var res RefreshResult
+	if f.done {
+		return res, ErrFullRefreshDone
+	}
+	f.done = true
+	if len(f.seen) == 0 && !f.opts.AllowEmpty {
+		return res, nil
+	}
+
+	// 只读取键和 _airbyte_meta，全量刷新的数据量（问卷、广告等）一般不大
+	var rows []AirbyteRawData
+	err := f.scope(f.w.getDB().WithContext(f.ctx)).
+		Select("wm_tenant_id", "_airbyte_raw_id", "_airbyte_meta").
+		Find(&rows).Error
+	if err != nil {
+		return res, fmt.Errorf("full refresh %s: %w", f.table, err)
+	}
+
+	now := f.w.now()
+	nowStr := now.Format(AirbyteTimeFormat)
+	var tombstones []AirbyteRawData
+	var expired []string
+	for _, r := range rows {
+		if _, ok := f.seen[r.AirbyteRawId]; ok {
+			continue
+		}
+		meta := ParseRawMeta(r.AirbyteMeta)
+		if !meta.Tombstone {
+			meta.Tombstone = true
+			meta.DeletedAt = nowStr
+			meta.SyncId = f.w.generation
+			r.AirbyteMeta = meta.String()
+			tombstones = append(tombstones, r)
+			continue
+		}
+		if f.opts.HardDeleteAfter > 0 {
+			// deleted_at 没有时区，按写入时的本地时间解析
+			deletedAt, err := time.ParseInLocation(AirbyteTimeFormat, meta.DeletedAt, now.Location())
+			if err == nil && now.Sub(deletedAt) >= f.opts.HardDeleteAfter {
+				expired = append(expired, r.AirbyteRawId)
+			}
+		}
+	}
+
+	// 每批一条 UPDATE，各行的 _airbyte_meta 不同，用 CASE 按 _airbyte_raw_id 取值
+	for start := 0; start < len(tombstones); start += f.w.batchSize {
+		batch := tombstones[start:min(start+f.w.batchSize, len(tombstones))]
+		ids := make([]string, len(batch))
+		var cases strings.Builder
+		args := make([]interface{}, 0, 2*len(batch))
+		cases.WriteString("CASE _airbyte_raw_id")
+		for i, r := range batch {
+			ids[i] = r.AirbyteRawId
+			cases.WriteString(" WHEN ? THEN ?")
+			args = append(args, r.AirbyteRawId, r.AirbyteMeta)
+		}
+		cases.WriteString(" END")
+		err = f.w.fenced(f.w.getDB().WithContext(f.ctx), func(tx *gorm.DB) error {
+			return f.scope(tx).
+				Where("_airbyte_raw_id IN ?", ids).
+				Updates(map[string]interface{}{
+					"_airbyte_meta":          gorm.Expr(cases.String(), args...),
+					"_airbyte_generation_id": f.w.generation,
+					"_airbyte_loaded_at":     nowStr,
+				}).Error
+		})
+		if err != nil {
+			return res, fmt.Errorf("full refresh %s: tombstone: %w", f.table, err)
+		}
+		res.Tombstoned += len(batch)
+	}
+
+	for start := 0; start < len(expired); start += f.w.batchSize {
+		batch := expired[start:min(start+f.w.batchSize, len(expired))]
+		err = f.w.fenced(f.w.getDB().WithContext(f.ctx), func(tx *gorm.DB) error {
+			return f.scope(tx).
+				Where("_airbyte_raw_id IN ?", batch).
+				Delete(&AirbyteRawData{}).Error
+		})
+		if err != nil {
+			return res, fmt.Errorf("full refresh %s: delete: %w", f.table, err)
+		}
+		res.Deleted += len(batch)
+	}
+	return res, nil
+}
+
+// scope 返回 db 上限定在本次刷新范围内的查询，SELECT、UPDATE、DELETE 共用
+func (f *FullRefresh) scope(db *gorm.DB) *gorm.DB {
+	q := db.Table(f.table).Where("wm_tenant_id = ?", f.tenantId)
+	if f.opts.KeyPrefix != "" {
+		q = q.Where("_airbyte_raw_id LIKE ? ESCAPE '!'", likePrefix(f.opts.KeyPrefix))
+	}
+	return q
+}
+
+// likePrefix 转义 LIKE 的通配符，返回匹配以 prefix 开头的模式
+func likePrefix(prefix string) string {
+	r := strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+	return r.Replace(prefix) + "%"
+}
@@ -0,0 +1,130 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFullRefresh(t *testing.T) {
+	gdb := sqliteRawDB(t)
+	day := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	writer := func(gen int64, now time.Time) *RawWriter {
+		w := NewRawWriter(RawWriterOptions{DB: gdb, Generation: gen})
+		w.now = func() time.Time { return now }
+		return w
+	}
+	metaOf := func(tenantId int64, id string) (RawMeta, bool) {
+		var rows []AirbyteRawData
+		gdb.Table(testRawTable).Where("wm_tenant_id = ? AND _airbyte_raw_id = ?", tenantId, id).Find(&rows)
+		if len(rows) == 0 {
+			return RawMeta{}, false
+		}
+		return ParseRawMeta(rows[0].AirbyteMeta), true
+	}
+	opts := FullRefreshOptions{HardDeleteAfter: 48 * time.Hour}
+
+	// 第一次全量：a、b、c，另一个租户的 x 不受影响
+	r := writer(1, day).BeginFullRefresh(testRawTable, 1, opts)
+	if _, err := r.Write([]AirbyteRawData{item(1, "a", `{}`).Raw(), item(1, "b", `{}`).Raw(), item(1, "c", `{}`).Raw()}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Write([]AirbyteRawData{item(2, "x", `{}`).Raw()}); err == nil {
+		t.Fatal("其他租户的行应当报错")
+	}
+	if _, err := writer(1, day).Write(testRawTable, []AirbyteRawData{item(2, "x", `{}`).Raw()}); err != nil {
+		t.Fatal(err)
+	}
+	if res, err := r.Commit(); err != nil || res != (RefreshResult{}) {
+		t.Fatalf("第一次 Commit 不符合预期: %+v %v", res, err)
+	}
+
+	// 第二次全量：b 被删除
+	r = writer(2, day.Add(time.Hour)).BeginFullRefresh(testRawTable, 1, opts)
+	if _, err := r.Write([]AirbyteRawData{item(1, "a", `{}`).Raw(), item(1, "c", `{}`).Raw()}); err != nil {
+		t.Fatal(err)
+	}
+	if res, err := r.Commit(); err != nil || res != (RefreshResult{Tombstoned: 1}) {
+		t.Fatalf("第二次 Commit 不符合预期: %+v %v", res, err)
+	}
+	if m, _ := metaOf(1, "b"); !m.Tombstone || m.DeletedAt != "2026-01-01 01:00:00" || m.SyncId != 2 {
+		t.Fatalf("b 应当被标记为墓碑: %+v", m)
+	}
+	if m, _ := metaOf(2, "x"); m.Tombstone {
+		t.Fatalf("其他租户的行不应被标记: %+v", m)
+	}
+	if _, err := r.Commit(); err != ErrFullRefreshDone {
+		t.Fatalf("重复 Commit 应当返回 ErrFullRefreshDone: %v", err)
+	}
+
+	// 空列表默认不标记
+	if res, err := writer(3, day.Add(2*time.Hour)).BeginFullRefresh(testRawTable, 1, opts).Commit(); err != nil || res != (RefreshResult{}) {
+		t.Fatalf("空列表不应标记墓碑: %+v %v", res, err)
+	}
+
+	// 第三次全量：b 重新出现，内容不变也要清除标记；c 被删除
+	w := writer(4, day.Add(3*time.Hour))
+	r = w.BeginFullRefresh(testRawTable, 1, opts)
+	c, err := r.Write([]AirbyteRawData{item(1, "a", `{}`).Raw(), item(1, "b", `{}`).Raw()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c != (WriteCounts{Updated: 1, Unchanged: 1}) {
+		t.Fatalf("墓碑行再次出现应计为更新: %+v", c)
+	}
+	if res, err := r.Commit(); err != nil || res != (RefreshResult{Tombstoned: 1}) {
+		t.Fatalf("第三次 Commit 不符合预期: %+v %v", res, err)
+	}
+	if m, _ := metaOf(1, "b"); m.Tombstone || m.DeletedAt != "" {
+		t.Fatalf("b 的墓碑标记应当被清除: %+v", m)
+	}
+
+	// 超过保留时长后 c 被物理删除，中止的刷新不做任何处理
+	r = writer(5, day.Add(60*time.Hour)).BeginFullRefresh(testRawTable, 1, opts)
+	r.Write([]AirbyteRawData{item(1, "a", `{}`).Raw()})
+	r.Abort()
+	if _, ok := metaOf(1, "c"); !ok {
+		t.Fatal("中止的刷新不应删除数据")
+	}
+	r = writer(6, day.Add(60*time.Hour)).BeginFullRefresh(testRawTable, 1, opts)
+	r.Write([]AirbyteRawData{item(1, "a", `{}`).Raw()})
+	if res, err := r.Commit(); err != nil || res != (RefreshResult{Tombstoned: 1, Deleted: 1}) {
+		t.Fatalf("第四次 Commit 不符合预期: %+v %v", res, err)
+	}
+	if _, ok := metaOf(1, "c"); ok {
+		t.Fatal("c 应当被物理删除")
+	}
+	if m, _ := metaOf(1, "b"); !m.Tombstone {
+		t.Fatalf("b 应当被重新标记: %+v", m)
+	}
+}
+
+func TestFullRefreshKeyPrefix(t *testing.T) {
+	gdb := sqliteRawDB(t)
+	w := NewRawWriter(RawWriterOptions{DB: gdb, Generation: 1})
+	// 同一租户两个账户，_airbyte_raw_id 分别以 acc_1| 和 acc2| 开头；accX1| 用来确认 _ 被转义
+	if _, err := w.Write(testRawTable, []AirbyteRawData{
+		item(1, "acc_1|a", `{}`).Raw(), item(1, "acc_1|b", `{}`).Raw(),
+		item(1, "acc2|a", `{}`).Raw(), item(1, "accX1|a", `{}`).Raw(),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	r := w.BeginFullRefresh(testRawTable, 1, FullRefreshOptions{KeyPrefix: "acc_1|"})
+	if _, err := r.Write([]AirbyteRawData{item(1, "acc2|a", `{}`).Raw()}); err == nil {
+		t.Fatal("范围外的行应当报错")
+	}
+	if _, err := r.Write([]AirbyteRawData{item(1, "acc_1|a", `{}`).Raw()}); err != nil {
+		t.Fatal(err)
+	}
+	if res, err := r.Commit(); err != nil || res != (RefreshResult{Tombstoned: 1}) {
+		t.Fatalf("只应标记 acc_1|b: %+v %v", res, err)
+	}
+
+	var rows []AirbyteRawData
+	gdb.Table(testRawTable).Where("wm_tenant_id = ?", 1).Find(&rows)
+	for _, row := range rows {
+		if want := row.AirbyteRawId == "acc_1|b"; ParseRawMeta(row.AirbyteMeta).Tombstone != want {
+			t.Fatalf("%s 的墓碑标记应为 %v", row.AirbyteRawId, want)
+		}
+	}
+}
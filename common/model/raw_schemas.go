@@ -0,0 +1,48 @@
+package model
+
+// 下游（alter-data-v2、alert-v2）查询的原始数据表的 schema
+// 这些表由 Airbyte 写入，接口返回的指标都是字符串，这里只声明下游用到的字段，生成 SQL 时按 raw tag 转换类型
+
+// TiktokGmvMaxMetrics raw_tiktok_marketing_gmv_max_metrics 的 _airbyte_data
+type TiktokGmvMaxMetrics struct {
+	CampaignId   string `json:"campaign_id"`
+	AdvertiserId string `json:"advertiser_id"`
+	StatTimeDay  string `json:"stat_time_day" raw:"stat_date,date"`
+	Metrics      struct {
+		Cost         string `json:"cost" raw:"ad_spend,double"`
+		GrossRevenue string `json:"gross_revenue" raw:"gross_revenue,double"`
+		Orders       string `json:"orders" raw:"orders,bigint"`
+	} `json:"metrics"`
+}
+
+// ApplovinAdsMetrics raw_applovin_ads_ads_metrics 的 _airbyte_data，2025-06-20 之前的数据
+type ApplovinAdsMetrics struct {
+	Day                string `json:"day" raw:"stat_date,date"`
+	AccountId          string `json:"account_id"`
+	CampaignIdExternal string `json:"campaign_id_external" raw:"campaign_id"`
+	AdId               string `json:"ad_id"`
+	Cost               string `json:"cost" raw:"ad_spend,double"`
+	Impressions        string `json:"impressions" raw:",bigint"`
+	Clicks             string `json:"clicks" raw:",bigint"`
+	Chka7d             string `json:"chka_7d" raw:"ads_orders,bigint"`
+	ChkaUsd7d          string `json:"chka_usd_7d" raw:"ads_sales,double"`
+}
+
+// ApplovinAdsV2Metrics raw_applovin_ads_v2_ads_metrics 的 _airbyte_data
+type ApplovinAdsV2Metrics struct {
+	Day           string `json:"day" raw:"stat_date,date"`
+	AccountId     string `json:"account_id"`
+	CampaignId    string `json:"campaign_id"`
+	CreativeSetId string `json:"creative_set_id" raw:"ad_id"`
+	Cost          string `json:"cost" raw:"ad_spend,double"`
+	Impressions   string `json:"impressions" raw:",bigint"`
+	Clicks        string `json:"clicks" raw:",bigint"`
+	Chka7d        string `json:"chka_7d" raw:"ads_orders,bigint"`
+	ChkaUsd7d     string `json:"chka_usd_7d" raw:"ads_sales,double"`
+}
+
+var (
+	TiktokGmvMaxMetricsSchema  = RegisterSchema[TiktokGmvMaxMetrics]("raw_tiktok_marketing_gmv_max_metrics")
+	ApplovinAdsMetricsSchema   = RegisterSchema[ApplovinAdsMetrics]("raw_applovin_ads_ads_metrics")
+	ApplovinAdsV2MetricsSchema = RegisterSchema[ApplovinAdsV2Metrics]("raw_applovin_ads_v2_ads_metrics")
+)
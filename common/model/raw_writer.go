@@ -0,0 +1,371 @@
+package model
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+	"wm-func/common/db/airbyte_db"
+	"wm-func/common/lock"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// AirbyteTimeFormat _airbyte_extracted_at、_airbyte_loaded_at 的格式，与已有的写入方一致使用本地时间
+const AirbyteTimeFormat = "2006-01-02 15:04:05"
+
+// DefaultRawBatchSize RawWriter 每批处理的行数
+const DefaultRawBatchSize = 500
+
+// FenceMarkTable Airbyte 库中的 fencing token 水位表，RawWriterOptions.Fence 不为空时使用
+const FenceMarkTable = "airbyte_destination_v2.raw_fence_marks"
+
+// MetaChange _airbyte_meta.changes 中的一项，格式与 Airbyte 一致
+type MetaChange struct {
+	Field  string `json:"field"`
+	Change string `json:"change"`
+	Reason string `json:"reason"`
+}
+
+// RawMeta RawWriter 写入的 _airbyte_meta
+type RawMeta struct {
+	Changes  []MetaChange `json:"changes"`
+	SyncId   int64        `json:"sync_id"`             // 写入该行的 generation id
+	DataHash string       `json:"data_hash,omitempty"` // _airbyte_data 的 sha256，用于判断内容是否变化
+
+	// Tombstone 全量刷新时上游已不存在的行，DeletedAt 为标记时间（本地时间，AirbyteTimeFormat）
+	Tombstone bool   `json:"tombstone,omitempty"`
+	DeletedAt string `json:"deleted_at,omitempty"`
+}
+
+// ParseRawMeta 解析 _airbyte_meta，格式不正确时返回空的 RawMeta
+func ParseRawMeta(s string) RawMeta {
+	var m RawMeta
+	_ = json.Unmarshal([]byte(s), &m)
+	return m
+}
+
+func (m RawMeta) String() string {
+	if m.Changes == nil {
+		m.Changes = []MetaChange{}
+	}
+	b, _ := json.Marshal(m)
+	return string(b)
+}
+
+// HashAirbyteData 计算 _airbyte_data 的哈希，合法 JSON 先去掉空白，避免格式差异被当成变化
+func HashAirbyteData(data []byte) string {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, data); err == nil {
+		data = buf.Bytes()
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// RawRecord 嵌入了 AirbyteRawData 的表结构，如 pinterest 的 RawPinterestAds
+type RawRecord interface {
+	AirbyteTable
+	Raw() AirbyteRawData
+}
+
+// Raw 返回原始数据，嵌入 AirbyteRawData 的结构体因此实现 RawRecord
+func (r AirbyteRawData) Raw() AirbyteRawData {
+	return r
+}
+
+// WriteCounts 写入结果
+type WriteCounts struct {
+	Inserted  int // 新增的行
+	Updated   int // 内容变化后更新的行
+	Unchanged int // 内容没有变化而跳过的行
+}
+
+func (c *WriteCounts) add(o WriteCounts) {
+	c.Inserted += o.Inserted
+	c.Updated += o.Updated
+	c.Unchanged += o.Unchanged
+}
+
+func (c WriteCounts) String() string {
+	return fmt.Sprintf("新增 %d, 更新 %d, 未变化 %d", c.Inserted, c.Updated, c.Unchanged)
+}
+
+// RawWriterOptions RawWriter 配置
+type RawWriterOptions struct {
+	DB         *gorm.DB // 为空时在第一次写入时使用 airbyte_db.GetDB()
+	Generation int64    // 本次运行的 generation id，为 0 时使用创建时的 Unix 时间戳
+	BatchSize  int      // 每批处理的行数，为 0 时使用 DefaultRawBatchSize
+
+	// Schemas 写入前按表名查找 schema 校验 _airbyte_data，漂移记录在 _airbyte_meta.changes 中；
+	// 为空时使用 DefaultSchemas，没有注册 schema 的表不校验
+	Schemas *SchemaRegistry
+
+	// Fence 不为空时，每批写入和全量刷新的墓碑更新都在事务中先用 lock.Fence.Advance 校验，
+	// 锁的新持有者写入过之后，旧持有者的写入返回 lock.ErrStaleToken。
+	// 只有 MySQL 后端的锁可以校验，Redis 和内存后端的 Fence 会导致写入失败
+	Fence lock.Fence
+}
+
+// RawWriter 幂等的 Airbyte 原始数据写入器，一次运行创建一个，可并发使用
+// 按 (wm_tenant_id, _airbyte_raw_id) 比较 _airbyte_data 的哈希，内容没有变化的行不写入；
+// 写入的行统一填充 generation id、_airbyte_meta 和加载时间，下游可以据此识别真正的变化
+type RawWriter struct {
+	db         *gorm.DB
+	dbOnce     sync.Once
+	generation int64
+	batchSize  int
+	schemas    *SchemaRegistry
+	fence      lock.Fence
+	fenceTable string
+	now        func() time.Time
+
+	mu     sync.Mutex
+	counts map[string]WriteCounts
+}
+
+// NewRawWriter 创建写入器
+func NewRawWriter(opts RawWriterOptions) *RawWriter {
+	if opts.Generation == 0 {
+		opts.Generation = time.Now().Unix()
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultRawBatchSize
+	}
+	if opts.Schemas == nil {
+		opts.Schemas = DefaultSchemas
+	}
+	return &RawWriter{
+		db:         opts.DB,
+		generation: opts.Generation,
+		batchSize:  opts.BatchSize,
+		schemas:    opts.Schemas,
+		fence:      opts.Fence,
+		fenceTable: FenceMarkTable,
+		now:        time.Now,
+		counts:     make(map[string]WriteCounts),
+	}
+}
+
+// Generation 返回本次运行的 generation id
+func (w *RawWriter) Generation() int64 {
+	return w.generation
+}
+
+// Counts 返回每张表累计的写入结果
+func (w *RawWriter) Counts() map[string]WriteCounts {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	res := make(map[string]WriteCounts, len(w.counts))
+	for table, c := range w.counts {
+		res[table] = c
+	}
+	return res
+}
+
+func (w *RawWriter) getDB() *gorm.DB {
+	w.dbOnce.Do(func() {
+		if w.db == nil {
+			w.db = airbyte_db.GetDB()
+		}
+	})
+	return w.db
+}
+
+// fenced 执行写入 fn；配置了 Fence 时在同一个事务中先校验并推进水位
+func (w *RawWriter) fenced(db *gorm.DB, fn func(tx *gorm.DB) error) error {
+	if w.fence.Key == "" {
+		return fn(db)
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := w.fence.Advance(tx, w.fenceTable); err != nil {
+			return err
+		}
+		return fn(tx)
+	})
+}
+
+// WriteRaw 写入嵌入了 AirbyteRawData 的表结构，表名取自 TableName
+func WriteRaw[T RawRecord](w *RawWriter, rows []T) (WriteCounts, error) {
+	if len(rows) == 0 {
+		return WriteCounts{}, nil
+	}
+	raws := make([]AirbyteRawData, len(rows))
+	for i, r := range rows {
+		raws[i] = r.Raw()
+	}
+	return w.Write(rows[0].TableName(), raws)
+}
+
+// Write 分批写入 table，返回本次调用的写入结果；出错时返回出错前已完成的部分
+func (w *RawWriter) Write(table string, rows []AirbyteRawData) (WriteCounts, error) {
+	return w.WriteContext(context.Background(), table, rows)
+}
+
+// WriteContext 同 Write，数据库操作受 ctx 控制
+func (w *RawWriter) WriteContext(ctx context.Context, table string, rows []AirbyteRawData) (WriteCounts, error) {
+	var total WriteCounts
+	for start := 0; start < len(rows); start += w.batchSize {
+		end := min(start+w.batchSize, len(rows))
+		var c WriteCounts
+		err := w.fenced(w.getDB().WithContext(ctx), func(tx *gorm.DB) error {
+			var err error
+			c, err = w.writeBatch(tx, table, rows[start:end])
+			return err
+		})
+		total.add(c)
+		w.record(table, c)
+		if err != nil {
+			return total, fmt.Errorf("write %s: %w", table, err)
+		}
+	}
+	return total, nil
+}
+
+func (w *RawWriter) record(table string, c WriteCounts) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	total := w.counts[table]
+	total.add(c)
+	w.counts[table] = total
+}
+
+// rawKey 原始数据表的唯一键
+type rawKey struct {
+	TenantId     int64
+	AirbyteRawId string
+}
+
+func (w *RawWriter) writeBatch(db *gorm.DB, table string, rows []AirbyteRawData) (WriteCounts, error) {
+	var counts WriteCounts
+	// 与已有的写入方一致，使用本地时间
+	now := w.now().Format(AirbyteTimeFormat)
+	schema, _ := w.schemas.Lookup(table)
+
+	// 同一批内重复的键以最后一条为准，被覆盖的行计为未变化
+	prepared := make(map[rawKey]AirbyteRawData, len(rows))
+	hashes := make(map[rawKey]string, len(rows))
+	var order []rawKey
+	for _, r := range rows {
+		key := rawKey{r.TenantId, r.AirbyteRawId}
+		if _, ok := prepared[key]; !ok {
+			order = append(order, key)
+		} else {
+			counts.Unchanged++
+		}
+		hashes[key] = HashAirbyteData(r.AirbyteData)
+		meta := ParseRawMeta(r.AirbyteMeta)
+		if schema != nil {
+			schema.flagDrift(r.AirbyteData, &meta)
+		}
+		meta.SyncId = w.generation
+		meta.DataHash = hashes[key]
+		meta.Tombstone, meta.DeletedAt = false, ""
+		r.AirbyteMeta = meta.String()
+		r.AirbyteGenerationId = w.generation
+		r.AirbyteLoadedAt = now
+		if r.AirbyteExtractedAt == "" {
+			r.AirbyteExtractedAt = now
+		}
+		prepared[key] = r
+	}
+
+	existing, err := w.existingHashes(db, table, order)
+	if err != nil {
+		return counts, err
+	}
+
+	var toWrite []AirbyteRawData
+	for _, key := range order {
+		old, found := existing[key]
+		switch {
+		case !found:
+			counts.Inserted++
+		case old.hash == hashes[key] && !old.tombstone:
+			counts.Unchanged++
+			continue
+		default:
+			counts.Updated++
+		}
+		toWrite = append(toWrite, prepared[key])
+	}
+	if len(toWrite) == 0 {
+		return counts, nil
+	}
+
+	err = db.Table(table).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "wm_tenant_id"}, {Name: "_airbyte_raw_id"}},
+		UpdateAll: true,
+	}).Create(&toWrite).Error
+	if err != nil {
+		return WriteCounts{Unchanged: counts.Unchanged}, err
+	}
+	return counts, nil
+}
+
+// existingRow 已存在行的状态，墓碑行再次出现时即使内容没有变化也要改写以清除标记
+type existingRow struct {
+	hash      string
+	tombstone bool
+}
+
+// existingHashes 查询已存在行的数据哈希和墓碑标记
+// 旧数据的 _airbyte_meta 中没有 data_hash，此时读取 _airbyte_data 计算
+func (w *RawWriter) existingHashes(db *gorm.DB, table string, keys []rawKey) (map[rawKey]existingRow, error) {
+	res := make(map[rawKey]existingRow, len(keys))
+	if len(keys) == 0 {
+		return res, nil
+	}
+
+	var found []AirbyteRawData
+	err := db.Table(table).
+		Select("wm_tenant_id", "_airbyte_raw_id", "_airbyte_meta").
+		Where("(wm_tenant_id, _airbyte_raw_id) IN ?", keyPairs(keys)).
+		Find(&found).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var legacy []rawKey
+	for _, r := range found {
+		key := rawKey{r.TenantId, r.AirbyteRawId}
+		meta := ParseRawMeta(r.AirbyteMeta)
+		res[key] = existingRow{hash: meta.DataHash, tombstone: meta.Tombstone}
+		if meta.DataHash == "" {
+			legacy = append(legacy, key)
+		}
+	}
+	if len(legacy) == 0 {
+		return res, nil
+	}
+
+	found = nil
+	err = db.Table(table).
+		Select("wm_tenant_id", "_airbyte_raw_id", "_airbyte_data").
+		Where("(wm_tenant_id, _airbyte_raw_id) IN ?", keyPairs(legacy)).
+		Find(&found).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range found {
+		key := rawKey{r.TenantId, r.AirbyteRawId}
+		row := res[key]
+		row.hash = HashAirbyteData(r.AirbyteData)
+		res[key] = row
+	}
+	return res, nil
+}
+
+func keyPairs(keys []rawKey) [][]interface{} {
+	pairs := make([][]interface{}, len(keys))
+	for i, k := range keys {
+		pairs[i] = []interface{}{k.TenantId, k.AirbyteRawId}
+	}
+	return pairs
+}
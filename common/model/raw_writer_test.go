@@ -0,0 +1,159 @@
+package model
+
+import (
+	"errors"
+	"testing"
+	"time"
+	"wm-func/common/db"
+	"wm-func/common/lock"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+const testRawTable = "raw_test_items"
+
+// sqliteRawDB 内存 SQLite 上的原始数据表，upsert 使用 SQLite 驱动原生的 ON CONFLICT
+func sqliteRawDB(t *testing.T) *gorm.DB {
+	gdb, err := db.Open(db.Config{
+		Dialector:    sqlite.Open(":memory:"),
+		MaxOpenConns: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if conn, err := gdb.DB(); err == nil {
+			conn.Close()
+		}
+	})
+
+	err = gdb.Exec(`CREATE TABLE ` + testRawTable + ` (
+		wm_tenant_id INTEGER,
+		_airbyte_raw_id TEXT,
+		_airbyte_data TEXT,
+		_airbyte_extracted_at TEXT,
+		_airbyte_loaded_at TEXT,
+		_airbyte_meta TEXT,
+		_airbyte_generation_id INTEGER,
+		UNIQUE (wm_tenant_id, _airbyte_raw_id)
+	)`).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+	return gdb
+}
+
+type rawTestItem struct {
+	AirbyteRawData
+}
+
+func (rawTestItem) TableName() string { return testRawTable }
+
+func item(tenantId int64, id, data string) rawTestItem {
+	return rawTestItem{AirbyteRawData{TenantId: tenantId, AirbyteRawId: id, AirbyteData: []byte(data), AirbyteMeta: `{}`}}
+}
+
+func TestRawWriter(t *testing.T) {
+	gdb := sqliteRawDB(t)
+	// 没有 data_hash 的旧数据，内容与第一次写入相同
+	err := gdb.Exec(`INSERT INTO ` + testRawTable + ` VALUES (1, 'legacy', '{"a": 1}', '', '', '{}', 0)`).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := NewRawWriter(RawWriterOptions{DB: gdb, Generation: 100, BatchSize: 2})
+	rows := []rawTestItem{
+		item(1, "a", `{"v":1}`),
+		item(1, "b", `{"v":2}`),
+		item(2, "a", `{"v":1}`),
+		item(1, "legacy", `{"a":1}`),
+	}
+	c, err := WriteRaw(w, rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c != (WriteCounts{Inserted: 3, Unchanged: 1}) {
+		t.Fatalf("第一次写入结果不符合预期: %+v", c)
+	}
+
+	var got AirbyteRawData
+	gdb.Table(testRawTable).Where("wm_tenant_id = 1 AND _airbyte_raw_id = 'a'").Take(&got)
+	meta := ParseRawMeta(got.AirbyteMeta)
+	if got.AirbyteGenerationId != 100 || meta.SyncId != 100 || meta.DataHash != HashAirbyteData([]byte(`{"v":1}`)) || meta.Changes == nil {
+		t.Fatalf("generation 或 _airbyte_meta 不符合预期: %+v %s", got, got.AirbyteMeta)
+	}
+
+	// 第二次运行：一条内容变化，一条只有空白差异，一条新增，同批重复的键以最后一条为准
+	w2 := NewRawWriter(RawWriterOptions{DB: gdb, Generation: 200})
+	w2.now = func() time.Time { return time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC) }
+	c, err = WriteRaw(w2, []rawTestItem{
+		item(1, "a", `{"v":10}`),
+		item(1, "b", `{ "v": 2 }`),
+		item(1, "c", `{"v":3}`),
+		item(1, "c", `{"v":4}`),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c != (WriteCounts{Inserted: 1, Updated: 1, Unchanged: 2}) {
+		t.Fatalf("第二次写入结果不符合预期: %+v", c)
+	}
+	if counts := w2.Counts()[testRawTable]; counts != c {
+		t.Fatalf("按表累计的结果不符合预期: %+v", counts)
+	}
+
+	var b, cRow AirbyteRawData
+	gdb.Table(testRawTable).Where("wm_tenant_id = 1 AND _airbyte_raw_id = 'b'").Take(&b)
+	gdb.Table(testRawTable).Where("wm_tenant_id = 1 AND _airbyte_raw_id = 'c'").Take(&cRow)
+	if b.AirbyteGenerationId != 100 {
+		t.Fatalf("未变化的行不应被改写: %+v", b)
+	}
+	if string(cRow.AirbyteData) != `{"v":4}` || cRow.AirbyteGenerationId != 200 || cRow.AirbyteLoadedAt != "2026-01-02 03:04:05" {
+		t.Fatalf("新增行不符合预期: %+v", cRow)
+	}
+}
+
+func TestRawWriterFence(t *testing.T) {
+	gdb := sqliteRawDB(t)
+	err := gdb.Exec(`CREATE TABLE raw_fence_marks (lock_key TEXT PRIMARY KEY, token INTEGER NOT NULL, updated_at DATETIME)`).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+	fencedWriter := func(fence lock.Fence) *RawWriter {
+		w := NewRawWriter(RawWriterOptions{DB: gdb, Fence: fence})
+		w.fenceTable = "raw_fence_marks"
+		return w
+	}
+
+	old := fencedWriter(lock.Fence{Key: "k", Token: 1, Durable: true})
+	if _, err := WriteRaw(old, []rawTestItem{item(1, "a", `{"v":1}`)}); err != nil {
+		t.Fatalf("第一个持有者写入失败: %v", err)
+	}
+
+	// 新的持有者写入后水位推进，旧持有者的写入和墓碑标记都被拒绝
+	cur := fencedWriter(lock.Fence{Key: "k", Token: 2, Durable: true})
+	if _, err := WriteRaw(cur, []rawTestItem{item(1, "b", `{"v":2}`)}); err != nil {
+		t.Fatalf("新的持有者写入失败: %v", err)
+	}
+	if _, err := WriteRaw(old, []rawTestItem{item(1, "a", `{"v":10}`)}); !errors.Is(err, lock.ErrStaleToken) {
+		t.Fatalf("旧持有者写入应返回 ErrStaleToken，实际: %v", err)
+	}
+	f := old.BeginFullRefresh(testRawTable, 1, FullRefreshOptions{})
+	f.seen["a"] = struct{}{}
+	if _, err := f.Commit(); !errors.Is(err, lock.ErrStaleToken) {
+		t.Fatalf("旧持有者标记墓碑应返回 ErrStaleToken，实际: %v", err)
+	}
+
+	var a AirbyteRawData
+	gdb.Table(testRawTable).Where("_airbyte_raw_id = 'a'").Take(&a)
+	if string(a.AirbyteData) != `{"v":1}` || ParseRawMeta(a.AirbyteMeta).Tombstone {
+		t.Fatalf("被拒绝的写入不应生效: %+v", a)
+	}
+
+	// Redis、内存后端的 token 无法校验，直接拒绝写入
+	redis := fencedWriter(lock.Fence{Key: "k", Token: 3})
+	if _, err := WriteRaw(redis, []rawTestItem{item(1, "c", `{"v":3}`)}); err == nil {
+		t.Fatal("非 MySQL 后端的 fence 应拒绝写入")
+	}
+}
@@ -0,0 +1,455 @@
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FieldType _airbyte_data 中字段的 JSON 类型
+type FieldType string
+
+const (
+	TypeString  FieldType = "string"
+	TypeInteger FieldType = "integer"
+	TypeNumber  FieldType = "number"
+	TypeBoolean FieldType = "boolean"
+	TypeObject  FieldType = "object"
+	TypeArray   FieldType = "array"
+	TypeAny     FieldType = "any" // interface{}，不检查类型
+)
+
+// _airbyte_meta.changes 中的 schema 漂移
+const (
+	ChangeAdded       = "ADDED"        // 数据中有 schema 没有的字段
+	ChangeMissing     = "MISSING"      // 缺少必填字段
+	ChangeTypeChanged = "TYPE_CHANGED" // 字段类型与 schema 不一致
+	ChangeInvalid     = "INVALID"      // _airbyte_data 不是合法的 JSON 对象，字段为 $
+	ReasonSchemaDrift = "SOURCE_SCHEMA_DRIFT"
+)
+
+// Field schema 中的一个字段
+type Field struct {
+	Path     []string // 从 _airbyte_data 根开始的键
+	Type     FieldType
+	Required bool    // 没有 omitempty 且不是指针、切片、map 的字段
+	Column   string  // 生成 SQL 时的列名，默认为路径转小写后以 _ 连接
+	SQLType  string  // 生成 SQL 时转换的类型，默认按 Type 推断；date 表示先转 varchar 再取日期
+	Fields   []Field // Type 为 object 时的子字段，为空表示不检查子字段（如 map）
+}
+
+// JSONPath 返回 MySQL/StarRocks 的 JSON 路径，如 $.metrics.cost、$."segments.date"
+func (f Field) JSONPath() string {
+	var b strings.Builder
+	b.WriteString("$")
+	for _, k := range f.Path {
+		b.WriteString(".")
+		if plainKey.MatchString(k) {
+			b.WriteString(k)
+		} else {
+			b.WriteString(strconv.Quote(k))
+		}
+	}
+	return b.String()
+}
+
+var plainKey = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Schema 原始数据表 _airbyte_data 的结构，由 Go 结构体的 json tag 推导
+//
+// 结构体字段可以用 raw tag 调整生成的 SQL：raw:"列名,类型"，如 raw:"ad_spend,double"、raw:",date"；
+// raw:"-" 表示不生成该列，但仍然参与校验
+type Schema struct {
+	Table  string // 不带库名的表名，如 raw_pinterest_ad_analytics
+	Type   reflect.Type
+	Fields []Field
+
+	columns []Field // 生成 SQL 的叶子字段，按结构体字段顺序
+}
+
+// NewSchema 由 T 的 json tag 推导 table 的 schema
+func NewSchema[T any](table string) *Schema {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("schema %s: %s is not a struct", table, t))
+	}
+	s := &Schema{Table: bareTable(table), Type: t}
+	s.Fields = structFields(t, nil, &s.columns)
+	return s
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// structFields 按 encoding/json 的规则展开结构体字段，匿名嵌入且没有 json 名的结构体平铺到上一层
+func structFields(t reflect.Type, parent []string, columns *[]Field) []Field {
+	var fields []Field
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		name, opts, _ := strings.Cut(sf.Tag.Get("json"), ",")
+		if name == "-" && opts == "" {
+			continue
+		}
+		ft := sf.Type
+		if sf.Anonymous && name == "" {
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				fields = append(fields, structFields(ft, parent, columns)...)
+				continue
+			}
+		}
+		if !sf.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+
+		f := Field{
+			Path:     append(append([]string{}, parent...), name),
+			Required: !strings.Contains(","+opts+",", ",omitempty,"),
+		}
+		if ft.Kind() == reflect.Pointer {
+			f.Required = false
+			ft = ft.Elem()
+		}
+		f.Type = jsonType(ft)
+		if f.Type == TypeArray || (f.Type == TypeObject && ft.Kind() == reflect.Map) {
+			f.Required = false
+		}
+		if f.Type == TypeObject && ft.Kind() == reflect.Struct {
+			f.Fields = structFields(ft, f.Path, columns)
+		}
+
+		column, sqlType, _ := strings.Cut(sf.Tag.Get("raw"), ",")
+		f.Column, f.SQLType = column, sqlType
+		if f.Column == "" {
+			f.Column = defaultColumn(f.Path)
+		}
+		if f.SQLType == "" {
+			f.SQLType = defaultSQLType(f.Type)
+		}
+		if column != "-" && f.Fields == nil {
+			*columns = append(*columns, f)
+		}
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+func jsonType(t reflect.Type) FieldType {
+	if t == timeType {
+		return TypeString
+	}
+	if t == reflect.TypeOf(json.Number("")) {
+		return TypeNumber
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return TypeString
+	case reflect.Bool:
+		return TypeBoolean
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return TypeInteger
+	case reflect.Float32, reflect.Float64:
+		return TypeNumber
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return TypeString // []byte 序列化为 base64 字符串
+		}
+		return TypeArray
+	case reflect.Array:
+		return TypeArray
+	case reflect.Struct, reflect.Map:
+		return TypeObject
+	default:
+		return TypeAny
+	}
+}
+
+func defaultColumn(path []string) string {
+	var parts []string
+	for _, k := range path {
+		parts = append(parts, strings.ToLower(nonWord.ReplaceAllString(k, "_")))
+	}
+	return strings.Join(parts, "_")
+}
+
+var nonWord = regexp.MustCompile(`\W+`)
+
+func defaultSQLType(t FieldType) string {
+	switch t {
+	case TypeInteger:
+		return "bigint"
+	case TypeNumber:
+		return "double"
+	case TypeBoolean:
+		return "boolean"
+	case TypeString:
+		return "varchar"
+	default:
+		return "json"
+	}
+}
+
+// bareTable 去掉库名，RawWriter 使用的表名通常带有 airbyte_destination_v2.
+func bareTable(table string) string {
+	if i := strings.LastIndexByte(table, '.'); i >= 0 {
+		return table[i+1:]
+	}
+	return table
+}
+
+// Validate 按 schema 检查 _airbyte_data，返回漂移的字段，不是合法 JSON 对象时返回错误
+// null 被视为缺失值，不算类型变化
+func (s *Schema) Validate(data []byte) ([]MetaChange, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v map[string]interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, fmt.Errorf("schema %s: invalid _airbyte_data: %w", s.Table, err)
+	}
+	var changes []MetaChange
+	validateObject(s.Fields, nil, v, &changes)
+	return changes, nil
+}
+
+func validateObject(fields []Field, parent []string, obj map[string]interface{}, changes *[]MetaChange) {
+	known := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		key := f.Path[len(f.Path)-1]
+		known[key] = true
+		v, ok := obj[key]
+		if !ok {
+			if f.Required {
+				*changes = append(*changes, MetaChange{Field: f.JSONPath(), Change: ChangeMissing, Reason: ReasonSchemaDrift})
+			}
+			continue
+		}
+		if v == nil {
+			continue
+		}
+		if got := valueType(v); !typeMatches(f.Type, got) {
+			*changes = append(*changes, MetaChange{
+				Field:  f.JSONPath(),
+				Change: ChangeTypeChanged,
+				Reason: fmt.Sprintf("%s: expected %s, got %s", ReasonSchemaDrift, f.Type, got),
+			})
+			continue
+		}
+		if child, ok := v.(map[string]interface{}); ok && f.Fields != nil {
+			validateObject(f.Fields, f.Path, child, changes)
+		}
+	}
+
+	var added []string
+	for key := range obj {
+		if !known[key] {
+			added = append(added, key)
+		}
+	}
+	sort.Strings(added)
+	for _, key := range added {
+		f := Field{Path: append(append([]string{}, parent...), key)}
+		*changes = append(*changes, MetaChange{Field: f.JSONPath(), Change: ChangeAdded, Reason: ReasonSchemaDrift})
+	}
+}
+
+func valueType(v interface{}) FieldType {
+	switch v := v.(type) {
+	case string:
+		return TypeString
+	case bool:
+		return TypeBoolean
+	case json.Number:
+		if _, err := v.Int64(); err == nil {
+			return TypeInteger
+		}
+		return TypeNumber
+	case map[string]interface{}:
+		return TypeObject
+	case []interface{}:
+		return TypeArray
+	default:
+		return TypeAny
+	}
+}
+
+// typeMatches 整数可以出现在 number 字段中
+func typeMatches(want, got FieldType) bool {
+	return want == TypeAny || want == got || (want == TypeNumber && got == TypeInteger)
+}
+
+// JSONSchema 返回 draft-07 的 JSON Schema
+func (s *Schema) JSONSchema() ([]byte, error) {
+	doc := objectSchema(s.Fields)
+	doc["$schema"] = "http://json-schema.org/draft-07/schema#"
+	doc["title"] = s.Table
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+func objectSchema(fields []Field) map[string]interface{} {
+	props := make(map[string]interface{}, len(fields))
+	var required []string
+	for _, f := range fields {
+		key := f.Path[len(f.Path)-1]
+		var p map[string]interface{}
+		switch {
+		case f.Type == TypeObject && f.Fields != nil:
+			p = objectSchema(f.Fields)
+			p["type"] = []string{string(TypeObject), "null"}
+		case f.Type == TypeAny:
+			p = map[string]interface{}{}
+		default:
+			p = map[string]interface{}{"type": []string{string(f.Type), "null"}}
+		}
+		props[key] = p
+		if f.Required {
+			required = append(required, key)
+		}
+	}
+	doc := map[string]interface{}{"type": "object", "properties": props}
+	if len(required) > 0 {
+		doc["required"] = required
+	}
+	return doc
+}
+
+// Columns 返回生成 SQL 的列名，按结构体字段顺序
+func (s *Schema) Columns() []string {
+	res := make([]string, len(s.columns))
+	for i, f := range s.columns {
+		res[i] = f.Column
+	}
+	return res
+}
+
+// Extract 返回列的类型化取值表达式，如 cast(json_extract(_airbyte_data, '$.cost') as double)
+// column 不存在时 panic，用于在包初始化时拼接 SQL
+func (s *Schema) Extract(column string) string {
+	for _, f := range s.columns {
+		if f.Column == column {
+			return extractExpr(f)
+		}
+	}
+	panic(fmt.Sprintf("schema %s: unknown column %q", s.Table, column))
+}
+
+func extractExpr(f Field) string {
+	raw := fmt.Sprintf("json_extract(_airbyte_data, '%s')", f.JSONPath())
+	switch f.SQLType {
+	case "json":
+		return raw
+	case "date":
+		return fmt.Sprintf("date(cast(%s as varchar))", raw)
+	default:
+		return fmt.Sprintf("cast(%s as %s)", raw, f.SQLType)
+	}
+}
+
+// Select 返回 "表达式 as 列名" 的列表，以 sep 连接；不指定列时返回所有列
+func (s *Schema) Select(sep string, columns ...string) string {
+	if len(columns) == 0 {
+		columns = s.Columns()
+	}
+	exprs := make([]string, len(columns))
+	for i, c := range columns {
+		exprs[i] = s.Extract(c) + " as " + c
+	}
+	return strings.Join(exprs, sep)
+}
+
+// ViewSQL 返回 database 中该表的类型化视图，视图名为表名加 _typed
+func (s *Schema) ViewSQL(database string) string {
+	return fmt.Sprintf("create or replace view %s.%s_typed as\nselect\n  wm_tenant_id,\n  _airbyte_raw_id,\n  _airbyte_extracted_at,\n  %s\nfrom %s.%s",
+		database, s.Table, s.Select(",\n  "), database, s.Table)
+}
+
+// SchemaRegistry 表名到 schema 的映射，可并发使用
+type SchemaRegistry struct {
+	mu      sync.RWMutex
+	schemas map[string]*Schema
+}
+
+// NewSchemaRegistry 创建空的注册表
+func NewSchemaRegistry() *SchemaRegistry {
+	return &SchemaRegistry{schemas: make(map[string]*Schema)}
+}
+
+// Add 注册 schema，同名的表会被替换
+func (r *SchemaRegistry) Add(s *Schema) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.schemas[s.Table] = s
+}
+
+// Lookup 按表名查找 schema，表名可以带库名
+func (r *SchemaRegistry) Lookup(table string) (*Schema, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	s, ok := r.schemas[bareTable(table)]
+	return s, ok
+}
+
+// All 返回所有 schema，按表名排序
+func (r *SchemaRegistry) All() []*Schema {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	res := make([]*Schema, 0, len(r.schemas))
+	for _, s := range r.schemas {
+		res = append(res, s)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Table < res[j].Table })
+	return res
+}
+
+// DefaultSchemas 默认注册表，RawWriter 未指定注册表时使用
+var DefaultSchemas = NewSchemaRegistry()
+
+// RegisterSchema 由 T 推导 table 的 schema 并注册到 DefaultSchemas
+func RegisterSchema[T any](table string) *Schema {
+	s := NewSchema[T](table)
+	DefaultSchemas.Add(s)
+	return s
+}
+
+// LookupSchema 在 DefaultSchemas 中查找 schema，不存在时 panic，用于在包初始化时拼接 SQL
+func LookupSchema(table string) *Schema {
+	s, ok := DefaultSchemas.Lookup(table)
+	if !ok {
+		panic(fmt.Sprintf("schema for %s is not registered", table))
+	}
+	return s
+}
+
+// flagDrift 校验 _airbyte_data，把漂移写入 meta.Changes，替换之前记录的漂移
+// 不是合法 JSON 对象时记录一条 INVALID，行照常写入，不影响同一批的其他行
+func (s *Schema) flagDrift(data []byte, meta *RawMeta) {
+	drift, err := s.Validate(data)
+	if err != nil {
+		drift = []MetaChange{{
+			Field:  Field{}.JSONPath(),
+			Change: ChangeInvalid,
+			Reason: fmt.Sprintf("%s: %v", ReasonSchemaDrift, err),
+		}}
+	}
+	changes := meta.Changes[:0:0]
+	for _, c := range meta.Changes {
+		if !strings.HasPrefix(c.Reason, ReasonSchemaDrift) {
+			changes = append(changes, c)
+		}
+	}
+	meta.Changes = append(changes, drift...)
+}
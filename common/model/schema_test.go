@@ -0,0 +1,148 @@
+package model
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type schemaTestItem struct {
+	Id      string  `json:"id"`
+	Date    string  `json:"date" raw:"stat_date,date"`
+	Spend   float64 `json:"spend" raw:"ad_spend"`
+	Note    *string `json:"note"`
+	Segment string  `json:"segments.date,omitempty"`
+	Metrics struct {
+		Clicks int64 `json:"clicks"`
+	} `json:"metrics"`
+	Tags []string `json:"tags" raw:"-"`
+}
+
+func TestSchemaValidate(t *testing.T) {
+	s := NewSchema[schemaTestItem]("airbyte_destination_v2.raw_schema_test")
+	if s.Table != "raw_schema_test" {
+		t.Fatalf("表名应当去掉库名: %s", s.Table)
+	}
+
+	changes, err := s.Validate([]byte(`{"id":"1","date":"2026-01-01","spend":3,"note":null,"metrics":{"clicks":1}}`))
+	if err != nil || len(changes) != 0 {
+		t.Fatalf("符合 schema 的数据不应有漂移: %+v %v", changes, err)
+	}
+
+	changes, err = s.Validate([]byte(`{"id":1,"spend":"3.5","metrics":{"clicks":1.5,"cost":2},"extra":true}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []MetaChange{
+		{Field: "$.id", Change: ChangeTypeChanged, Reason: ReasonSchemaDrift + ": expected string, got integer"},
+		{Field: "$.date", Change: ChangeMissing, Reason: ReasonSchemaDrift},
+		{Field: "$.spend", Change: ChangeTypeChanged, Reason: ReasonSchemaDrift + ": expected number, got string"},
+		{Field: "$.metrics.clicks", Change: ChangeTypeChanged, Reason: ReasonSchemaDrift + ": expected integer, got number"},
+		{Field: "$.metrics.cost", Change: ChangeAdded, Reason: ReasonSchemaDrift},
+		{Field: "$.extra", Change: ChangeAdded, Reason: ReasonSchemaDrift},
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Fatalf("漂移不符合预期:\n%+v\n%+v", changes, want)
+	}
+
+	if _, err := s.Validate([]byte(`[1]`)); err == nil {
+		t.Fatal("不是 JSON 对象时应当返回错误")
+	}
+}
+
+func TestSchemaJSONSchema(t *testing.T) {
+	b, err := NewSchema[schemaTestItem]("raw_schema_test").JSONSchema()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		Required   []string `json:"required"`
+		Properties map[string]struct {
+			Type       []string `json:"type"`
+			Properties map[string]interface{}
+		} `json:"properties"`
+	}
+	if err := json.Unmarshal(b, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(doc.Required, []string{"id", "date", "spend", "metrics"}) {
+		t.Fatalf("required 不符合预期: %v", doc.Required)
+	}
+	if got := doc.Properties["spend"].Type; !reflect.DeepEqual(got, []string{"number", "null"}) {
+		t.Fatalf("spend 类型不符合预期: %v", got)
+	}
+	if _, ok := doc.Properties["metrics"].Properties["clicks"]; !ok {
+		t.Fatalf("缺少嵌套字段: %s", b)
+	}
+}
+
+func TestSchemaSQL(t *testing.T) {
+	s := NewSchema[schemaTestItem]("raw_schema_test")
+	if got := s.Columns(); !reflect.DeepEqual(got, []string{"id", "stat_date", "ad_spend", "note", "segments_date", "metrics_clicks"}) {
+		t.Fatalf("列不符合预期: %v", got)
+	}
+	if got := s.Extract("segments_date"); got != `cast(json_extract(_airbyte_data, '$."segments.date"') as varchar)` {
+		t.Fatalf("带点的键应当加引号: %s", got)
+	}
+	if got := s.Select(", ", "stat_date", "metrics_clicks"); got != "date(cast(json_extract(_airbyte_data, '$.date') as varchar)) as stat_date, "+
+		"cast(json_extract(_airbyte_data, '$.metrics.clicks') as bigint) as metrics_clicks" {
+		t.Fatalf("select 不符合预期: %s", got)
+	}
+	if view := s.ViewSQL("airbyte_destination_v2"); !strings.HasPrefix(view, "create or replace view airbyte_destination_v2.raw_schema_test_typed as") ||
+		!strings.HasSuffix(view, "from airbyte_destination_v2.raw_schema_test") {
+		t.Fatalf("视图不符合预期: %s", view)
+	}
+
+	// 与 alter-data-v2 中手写的表达式一致
+	if got := TiktokGmvMaxMetricsSchema.Select(",\n"); got != strings.Join([]string{
+		"cast(json_extract(_airbyte_data, '$.campaign_id') as varchar) as campaign_id",
+		"cast(json_extract(_airbyte_data, '$.advertiser_id') as varchar) as advertiser_id",
+		"date(cast(json_extract(_airbyte_data, '$.stat_time_day') as varchar)) as stat_date",
+		"cast(json_extract(_airbyte_data, '$.metrics.cost') as double) as ad_spend",
+		"cast(json_extract(_airbyte_data, '$.metrics.gross_revenue') as double) as gross_revenue",
+		"cast(json_extract(_airbyte_data, '$.metrics.orders') as bigint) as orders",
+	}, ",\n") {
+		t.Fatalf("gmv max 的 select 不符合预期:\n%s", got)
+	}
+}
+
+func TestRawWriterSchemaDrift(t *testing.T) {
+	gdb := sqliteRawDB(t)
+	schemas := NewSchemaRegistry()
+	schemas.Add(NewSchema[struct {
+		V int64 `json:"v"`
+	}](testRawTable))
+	w := NewRawWriter(RawWriterOptions{DB: gdb, Generation: 1, Schemas: schemas})
+
+	bad := item(1, "a", `{"v":"1","w":2}`)
+	bad.AirbyteMeta = `{"changes":[{"field":"x","change":"NULLED","reason":"DESTINATION_SERIALIZATION_ERROR"}]}`
+	if _, err := WriteRaw(w, []rawTestItem{bad, item(1, "b", `{"v":1}`)}); err != nil {
+		t.Fatal(err)
+	}
+	var got AirbyteRawData
+	gdb.Table(testRawTable).Where("_airbyte_raw_id = 'a'").Take(&got)
+	changes := ParseRawMeta(got.AirbyteMeta).Changes
+	if len(changes) != 3 || changes[0].Change != "NULLED" || changes[1].Change != ChangeTypeChanged || changes[2].Change != ChangeAdded {
+		t.Fatalf("漂移没有记录到 _airbyte_meta: %s", got.AirbyteMeta)
+	}
+	gdb.Table(testRawTable).Where("_airbyte_raw_id = 'b'").Take(&got)
+	if changes := ParseRawMeta(got.AirbyteMeta).Changes; len(changes) != 0 {
+		t.Fatalf("没有漂移的行不应记录: %s", got.AirbyteMeta)
+	}
+
+	// 不合法的 JSON 记录到 _airbyte_meta，不影响同一批的其他行
+	if _, err := WriteRaw(w, []rawTestItem{item(1, "c", `not json`), item(1, "d", `{"v":2}`)}); err != nil {
+		t.Fatal(err)
+	}
+	gdb.Table(testRawTable).Where("_airbyte_raw_id = 'c'").Take(&got)
+	changes = ParseRawMeta(got.AirbyteMeta).Changes
+	if len(changes) != 1 || changes[0].Change != ChangeInvalid || changes[0].Field != "$" {
+		t.Fatalf("不合法的 JSON 应记录为 INVALID: %s", got.AirbyteMeta)
+	}
+	var n int64
+	gdb.Table(testRawTable).Where("_airbyte_raw_id = 'd'").Count(&n)
+	if n != 1 {
+		t.Fatal("同一批的其他行应当正常写入")
+	}
+}
@@ -0,0 +1,146 @@
+// Package paginate 提供统一的分页迭代器
+// 每种分页方式只需提供“按游标取一页”的函数，最大页数、翻页间隔和游标持久化由迭代器统一处理
+package paginate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	// Done 没有更多数据
+	Done = errors.New("no more pages")
+	// ErrMaxPages 达到最大页数，可能还有未获取的数据
+	ErrMaxPages = errors.New("max pages reached")
+)
+
+// Page 一页数据和获取下一页需要的游标
+type Page[T any] struct {
+	Items []T
+	Next  string // 下一页的游标（页码、URL、bookmark 或 endCursor），为空表示没有下一页
+}
+
+// Fetcher 按游标获取一页数据
+type Fetcher[T any] func(ctx context.Context, cursor string) (Page[T], error)
+
+// Options 迭代器配置
+type Options struct {
+	MaxPages int           // 最多获取的页数，0 表示不限制
+	Delay    time.Duration // 两次翻页之间的等待时间
+	Resume   string        // 从保存的游标继续，为空时从第一页开始
+
+	// Checkpoint 在调用方处理完一页、准备获取下一页时调用，参数为下一页的游标；
+	// 最后一页处理完、Next 返回 Done 之前以空游标调用一次，表示已全部完成。
+	// 返回错误时停止迭代；用于把游标持久化，任务中断后可以通过 Resume 继续
+	Checkpoint func(cursor string) error
+}
+
+// Iterator 分页迭代器，不能并发使用
+type Iterator[T any] struct {
+	fetch  Fetcher[T]
+	opts   Options
+	cursor string
+	pages  int
+	done   bool
+	last   bool // 最后一页已交给调用方，还没有保存游标
+}
+
+// New 创建迭代器，start 为第一页的游标
+func New[T any](start string, fetch Fetcher[T], opts Options) *Iterator[T] {
+	if opts.Resume != "" {
+		start = opts.Resume
+	}
+	return &Iterator[T]{fetch: fetch, opts: opts, cursor: start}
+}
+
+// Next 获取下一页数据
+// 没有更多数据时返回 Done；达到 MaxPages 且还有下一页时返回 ErrMaxPages
+func (it *Iterator[T]) Next(ctx context.Context) ([]T, error) {
+	if it.done {
+		if it.last {
+			// 最后一页也已处理完，保存空游标
+			it.last = false
+			if err := it.checkpoint(); err != nil {
+				return nil, err
+			}
+		}
+		return nil, Done
+	}
+
+	if it.pages > 0 {
+		// 上一页已被调用方处理完，保存游标后再翻页
+		if err := it.checkpoint(); err != nil {
+			return nil, err
+		}
+		if it.opts.MaxPages > 0 && it.pages >= it.opts.MaxPages {
+			it.done = true
+			return nil, fmt.Errorf("%w (%d)", ErrMaxPages, it.opts.MaxPages)
+		}
+		if err := sleep(ctx, it.opts.Delay); err != nil {
+			return nil, err
+		}
+	}
+
+	page, err := it.fetch(ctx, it.cursor)
+	if err != nil {
+		return nil, err
+	}
+	it.pages++
+	if page.Next == "" {
+		it.done = true
+		it.last = true
+	}
+	it.cursor = page.Next
+	return page.Items, nil
+}
+
+func (it *Iterator[T]) checkpoint() error {
+	if it.opts.Checkpoint == nil {
+		return nil
+	}
+	if err := it.opts.Checkpoint(it.cursor); err != nil {
+		return fmt.Errorf("checkpoint: %w", err)
+	}
+	return nil
+}
+
+// Cursor 返回下一页的游标，最后一页之后为空
+func (it *Iterator[T]) Cursor() string {
+	return it.cursor
+}
+
+// Pages 返回已获取的页数
+func (it *Iterator[T]) Pages() int {
+	return it.pages
+}
+
+// All 获取所有页的数据；出错时返回已获取的数据和错误
+func All[T any](ctx context.Context, it *Iterator[T]) ([]T, error) {
+	var res []T
+	for {
+		items, err := it.Next(ctx)
+		if errors.Is(err, Done) {
+			return res, nil
+		}
+		if err != nil {
+			return res, err
+		}
+		res = append(res, items...)
+	}
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
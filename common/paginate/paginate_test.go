@@ -0,0 +1,132 @@
+package paginate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+// numbers 返回 [from, to) 的整数
+func numbers(from, to int) []int {
+	var res []int
+	for i := from; i < to; i++ {
+		res = append(res, i)
+	}
+	return res
+}
+
+func TestPageNumber(t *testing.T) {
+	data := numbers(0, 7)
+	fetch := func(total int) func(ctx context.Context, page, pageSize int) ([]int, int, error) {
+		return func(ctx context.Context, page, pageSize int) ([]int, int, error) {
+			start := (page - 1) * pageSize
+			if start >= len(data) {
+				return nil, total, nil
+			}
+			end := min(start+pageSize, len(data))
+			return data[start:end], total, nil
+		}
+	}
+
+	for _, total := range []int{len(data), -1} {
+		got, err := All(context.Background(), PageNumber(3, fetch(total), Options{}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, data) {
+			t.Fatalf("total=%d 结果不符合预期: %v", total, got)
+		}
+	}
+}
+
+func TestNextURLAndBookmark(t *testing.T) {
+	pages := map[string][]int{"": {1, 2}, "b1": {3}, "b2": {4, 5}}
+	nexts := map[string]string{"": "b1", "b1": "b2", "b2": ""}
+
+	it := Bookmark(func(ctx context.Context, bookmark string) ([]int, string, error) {
+		return pages[bookmark], nexts[bookmark], nil
+	}, Options{})
+	got, err := All(context.Background(), it)
+	if err != nil || !reflect.DeepEqual(got, []int{1, 2, 3, 4, 5}) {
+		t.Fatalf("Bookmark 结果不符合预期: %v %v", got, err)
+	}
+
+	it = NextURL("https://api.example.com/items?page=1", func(ctx context.Context, url string) ([]int, string, error) {
+		n, _ := strconv.Atoi(url[len(url)-1:])
+		if n == 3 {
+			return []int{n}, "", nil
+		}
+		return []int{n}, fmt.Sprintf("https://api.example.com/items?page=%d", n+1), nil
+	}, Options{})
+	got, err = All(context.Background(), it)
+	if err != nil || !reflect.DeepEqual(got, []int{1, 2, 3}) {
+		t.Fatalf("NextURL 结果不符合预期: %v %v", got, err)
+	}
+}
+
+func TestEndCursorCheckpointAndResume(t *testing.T) {
+	fetch := func(ctx context.Context, after string) ([]int, PageInfo, error) {
+		n := 0
+		if after != "" {
+			n, _ = strconv.Atoi(after)
+		}
+		return []int{n}, PageInfo{HasNextPage: n < 4, EndCursor: strconv.Itoa(n + 1)}, nil
+	}
+
+	// 处理完两页后中断，保存的游标指向第三页
+	var saved string
+	it := EndCursor(fetch, Options{Checkpoint: func(cursor string) error {
+		saved = cursor
+		return nil
+	}})
+	for i := 0; i < 2; i++ {
+		if _, err := it.Next(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := it.Next(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if saved != "2" {
+		t.Fatalf("期望保存游标 2，实际 %q", saved)
+	}
+	// 最后一页处理完后保存空游标
+	if _, err := All(context.Background(), it); err != nil {
+		t.Fatal(err)
+	}
+	if saved != "" {
+		t.Fatalf("全部完成后期望保存空游标，实际 %q", saved)
+	}
+	saved = "2"
+
+	got, err := All(context.Background(), EndCursor(fetch, Options{Resume: saved}))
+	if err != nil || !reflect.DeepEqual(got, []int{2, 3, 4}) {
+		t.Fatalf("从游标继续的结果不符合预期: %v %v", got, err)
+	}
+
+	stop := errors.New("stop")
+	_, err = All(context.Background(), EndCursor(fetch, Options{Checkpoint: func(string) error { return stop }}))
+	if !errors.Is(err, stop) {
+		t.Fatalf("Checkpoint 出错时应停止迭代，实际 %v", err)
+	}
+}
+
+func TestMaxPages(t *testing.T) {
+	it := Bookmark(func(ctx context.Context, bookmark string) ([]int, string, error) {
+		return []int{len(bookmark)}, bookmark + "x", nil
+	}, Options{MaxPages: 3})
+
+	got, err := All(context.Background(), it)
+	if !errors.Is(err, ErrMaxPages) {
+		t.Fatalf("期望 ErrMaxPages，实际 %v", err)
+	}
+	if !reflect.DeepEqual(got, []int{0, 1, 2}) || it.Pages() != 3 {
+		t.Fatalf("达到最大页数前的数据不符合预期: %v", got)
+	}
+	if _, err := it.Next(context.Background()); !errors.Is(err, Done) {
+		t.Fatalf("达到最大页数后应返回 Done，实际 %v", err)
+	}
+}
@@ -0,0 +1,83 @@
+package paginate
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+)
+
+// PageNumber 页码分页（如 Knocommerce 的 page/pageSize + total），游标为页码，从 1 开始
+// fetch 返回当前页数据和总条数，总条数未知时返回负数，此时以不满一页作为结束
+func PageNumber[T any](pageSize int, fetch func(ctx context.Context, page, pageSize int) ([]T, int, error), opts Options) *Iterator[T] {
+	fetched := 0
+	return New("1", func(ctx context.Context, cursor string) (Page[T], error) {
+		page, err := strconv.Atoi(cursor)
+		if err != nil {
+			return Page[T]{}, fmt.Errorf("invalid page cursor %q: %w", cursor, err)
+		}
+		if fetched == 0 && page > 1 {
+			// 从保存的页码继续时，之前的页按满页计算
+			fetched = (page - 1) * pageSize
+		}
+
+		items, total, err := fetch(ctx, page, pageSize)
+		if err != nil {
+			return Page[T]{}, err
+		}
+		fetched += len(items)
+
+		next := strconv.Itoa(page + 1)
+		switch {
+		case len(items) == 0:
+			next = ""
+		case total >= 0 && fetched >= total:
+			next = ""
+		case total < 0 && len(items) < pageSize:
+			next = ""
+		}
+		return Page[T]{Items: items, Next: next}, nil
+	}, opts)
+}
+
+// NextURL 按响应中的下一页地址分页（如 Fairing 的 next、Meta 的 paging.next），游标为完整 URL
+func NextURL[T any](firstURL string, fetch func(ctx context.Context, url string) ([]T, string, error), opts Options) *Iterator[T] {
+	return New(firstURL, func(ctx context.Context, cursor string) (Page[T], error) {
+		items, next, err := fetch(ctx, cursor)
+		if err != nil {
+			return Page[T]{}, err
+		}
+		return Page[T]{Items: items, Next: next}, nil
+	}, opts)
+}
+
+// Bookmark 按 bookmark 分页（如 Pinterest），第一页的 bookmark 为空
+func Bookmark[T any](fetch func(ctx context.Context, bookmark string) ([]T, string, error), opts Options) *Iterator[T] {
+	return New("", func(ctx context.Context, cursor string) (Page[T], error) {
+		items, next, err := fetch(ctx, cursor)
+		if err != nil {
+			return Page[T]{}, err
+		}
+		return Page[T]{Items: items, Next: next}, nil
+	}, opts)
+}
+
+// PageInfo GraphQL 连接的分页信息
+type PageInfo struct {
+	HasNextPage bool   `json:"hasNextPage"`
+	EndCursor   string `json:"endCursor"`
+}
+
+// EndCursor GraphQL 游标分页（如 Shopify 的 pageInfo.endCursor），第一页的 after 为空
+func EndCursor[T any](fetch func(ctx context.Context, after string) ([]T, PageInfo, error), opts Options) *Iterator[T] {
+	return New("", func(ctx context.Context, cursor string) (Page[T], error) {
+		items, info, err := fetch(ctx, cursor)
+		if err != nil {
+			return Page[T]{}, err
+		}
+		next := ""
+		if info.HasNextPage {
+			next = info.EndCursor
+		}
+		return Page[T]{Items: items, Next: next}, nil
+	}, opts)
+}
@@ -0,0 +1,281 @@
+package t_pool
+
+import (
+	"context"
+	"sync"
+)
+
+// keyedTask 带键的任务
+type keyedTask struct {
+	label   string
+	run     func(ctx context.Context) error
+	dropped func() // Shutdown 丢弃未开始的任务时调用，可以为空
+}
+
+// KeyedPool 按键限制并发的协程池
+// 任务携带一个键（如租户、平台或账户），同一个键同时运行的任务数不超过该键的上限；
+// 多个键之间轮流调度，避免任务很多的键把其他键饿死
+type KeyedPool struct {
+	workerCount int
+	keyLimit    int            // 每个键默认的并发上限
+	limits      map[string]int // 单独设置的键并发上限
+
+	ctx    context.Context // 传给任务的 context，Shutdown 超时时取消
+	cancel context.CancelFunc
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	queues  map[string][]keyedTask // 每个键等待执行的任务
+	keys    []string               // 有等待任务的键，按加入顺序轮转
+	next    int                    // 下一次调度从 keys 的哪个位置开始
+	running map[string]int         // 每个键正在执行的任务数
+	pending sync.WaitGroup
+	errs    []TaskError
+	closed  bool
+}
+
+// NewKeyedPool 创建按键限流的协程池，keyLimit 为每个键默认的最大并发数
+func NewKeyedPool(workerCount, keyLimit int) *KeyedPool {
+	return NewKeyedPoolWithContext(context.Background(), workerCount, keyLimit)
+}
+
+// NewKeyedPoolWithContext 创建按键限流的协程池，任务收到的 context 派生自 ctx
+func NewKeyedPoolWithContext(ctx context.Context, workerCount, keyLimit int) *KeyedPool {
+	if keyLimit <= 0 {
+		keyLimit = workerCount
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	p := &KeyedPool{
+		workerCount: workerCount,
+		keyLimit:    keyLimit,
+		limits:      make(map[string]int),
+		ctx:         ctx,
+		cancel:      cancel,
+		queues:      make(map[string][]keyedTask),
+		running:     make(map[string]int),
+	}
+	p.cond = sync.NewCond(&p.mu)
+	return p
+}
+
+// SetKeyLimit 单独设置某个键的并发上限
+func (p *KeyedPool) SetKeyLimit(key string, limit int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.limits[key] = limit
+	p.cond.Broadcast()
+}
+
+// Run 启动协程池
+func (p *KeyedPool) Run() {
+	for i := 0; i < p.workerCount; i++ {
+		go p.worker()
+	}
+}
+
+// AddTask 添加任务到 key 的队列，不会阻塞
+func (p *KeyedPool) AddTask(key string, task func()) {
+	p.AddTaskE(key, key, func(context.Context) error {
+		task()
+		return nil
+	})
+}
+
+// AddTaskE 添加返回错误的任务到 key 的队列，label 用于在错误中标识任务
+// 协程池已关闭时任务不会执行，记录为 ErrPoolShutdown
+func (p *KeyedPool) AddTaskE(key, label string, task func(ctx context.Context) error) {
+	p.add(key, keyedTask{label: label, run: task})
+}
+
+// add 把任务放入 key 的队列，协程池已关闭时记录 ErrPoolShutdown 并返回 false
+func (p *KeyedPool) add(key string, task keyedTask) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		p.errs = append(p.errs, TaskError{Label: task.label, Err: ErrPoolShutdown})
+		return false
+	}
+	if _, ok := p.queues[key]; !ok {
+		p.keys = append(p.keys, key)
+	}
+	p.queues[key] = append(p.queues[key], task)
+	p.pending.Add(1)
+	p.cond.Signal()
+	return true
+}
+
+// KeyedGroup 共享 KeyedPool 中的一组任务，可以只等待这一组完成
+// 用于整个运行共用一个按键限流的协程池，各个调用方分别等待自己提交的任务
+type KeyedGroup struct {
+	pool *KeyedPool
+	wg   sync.WaitGroup
+	mu   sync.Mutex
+	errs []TaskError
+}
+
+// Group 创建一组任务
+func (p *KeyedPool) Group() *KeyedGroup {
+	return &KeyedGroup{pool: p}
+}
+
+// AddTaskE 添加任务到协程池中 key 的队列，错误同时记录在组和协程池中
+func (g *KeyedGroup) AddTaskE(key, label string, task func(ctx context.Context) error) {
+	skip := func() {
+		g.record(TaskError{Label: label, Err: ErrPoolShutdown})
+		g.wg.Done()
+	}
+	g.wg.Add(1)
+	ok := g.pool.add(key, keyedTask{
+		label: label,
+		run: func(ctx context.Context) (err error) {
+			defer g.wg.Done()
+			defer func() {
+				if err != nil {
+					g.record(TaskError{Label: label, Err: err})
+				}
+			}()
+			return runSafely(ctx, label, task)
+		},
+		dropped: skip,
+	})
+	if !ok {
+		skip()
+	}
+}
+
+// Wait 等待本组的任务完成，返回失败任务的错误（包括 panic）
+func (g *KeyedGroup) Wait() []TaskError {
+	g.wg.Wait()
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]TaskError(nil), g.errs...)
+}
+
+func (g *KeyedGroup) record(e TaskError) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.errs = append(g.errs, e)
+}
+
+// Wait 等待所有已添加的任务完成，返回失败任务的错误（包括 panic）
+func (p *KeyedPool) Wait() []TaskError {
+	p.pending.Wait()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]TaskError(nil), p.errs...)
+}
+
+// Close 不再接受新任务，已排队的任务执行完后 worker 退出
+func (p *KeyedPool) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+	p.cond.Broadcast()
+}
+
+// Shutdown 优雅关闭：不再接受新任务，尚未开始的任务记录为 ErrPoolShutdown，等待正在执行的任务结束
+// ctx 结束时仍未完成则取消任务的 context 并返回 ctx.Err()
+func (p *KeyedPool) Shutdown(ctx context.Context) error {
+	p.mu.Lock()
+	p.closed = true
+	for _, key := range p.keys {
+		for _, task := range p.queues[key] {
+			p.errs = append(p.errs, TaskError{Label: task.label, Err: ErrPoolShutdown})
+			if task.dropped != nil {
+				task.dropped()
+			}
+			p.pending.Done()
+		}
+		delete(p.queues, key)
+	}
+	p.keys = nil
+	p.next = 0
+	p.cond.Broadcast()
+	p.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		p.pending.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		p.cancel()
+		return ctx.Err()
+	}
+}
+
+func (p *KeyedPool) worker() {
+	for {
+		p.mu.Lock()
+		key, task, ok := p.take()
+		for !ok {
+			if p.closed && len(p.keys) == 0 {
+				p.mu.Unlock()
+				return
+			}
+			p.cond.Wait()
+			key, task, ok = p.take()
+		}
+		p.running[key]++
+		p.mu.Unlock()
+
+		err := runSafely(p.ctx, task.label, task.run)
+
+		p.mu.Lock()
+		p.running[key]--
+		if p.running[key] == 0 {
+			delete(p.running, key)
+		}
+		if err != nil {
+			p.errs = append(p.errs, TaskError{Label: task.label, Err: err})
+		}
+		// 该键腾出了并发额度，唤醒等待的 worker
+		p.cond.Broadcast()
+		p.mu.Unlock()
+		p.pending.Done()
+	}
+}
+
+// take 从 next 开始轮询，取出第一个未达到并发上限的键的任务，调用方需持有 mu
+func (p *KeyedPool) take() (string, keyedTask, bool) {
+	for i := 0; i < len(p.keys); i++ {
+		idx := (p.next + i) % len(p.keys)
+		key := p.keys[idx]
+		if p.running[key] >= p.limitOf(key) {
+			continue
+		}
+
+		queue := p.queues[key]
+		task := queue[0]
+		if len(queue) == 1 {
+			delete(p.queues, key)
+			p.keys = append(p.keys[:idx], p.keys[idx+1:]...)
+			// 删除后 idx 已指向下一个键
+			p.next = idx
+		} else {
+			p.queues[key] = queue[1:]
+			p.next = idx + 1
+		}
+		if len(p.keys) > 0 {
+			p.next %= len(p.keys)
+		} else {
+			p.next = 0
+		}
+		return key, task, true
+	}
+	return "", keyedTask{}, false
+}
+
+func (p *KeyedPool) limitOf(key string) int {
+	if limit, ok := p.limits[key]; ok && limit > 0 {
+		return limit
+	}
+	return p.keyLimit
+}
@@ -1,22 +1,87 @@
 package t_pool
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log"
+	"runtime/debug"
 	"sync"
+	"time"
 )
 
+var (
+	// ErrTaskPanic 任务执行时发生 panic
+	ErrTaskPanic = errors.New("task panicked")
+	// ErrPoolShutdown 协程池已关闭，任务没有执行
+	ErrPoolShutdown = errors.New("worker pool shut down")
+)
+
+// TaskError 单个任务的执行错误
+type TaskError struct {
+	Label string // 任务标识，通常为租户或 trace ID
+	Err   error
+}
+
+func (e TaskError) Error() string {
+	if e.Label == "" {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("[%s] %v", e.Label, e.Err)
+}
+
+func (e TaskError) Unwrap() error {
+	return e.Err
+}
+
+// Options 协程池配置
+type Options struct {
+	Workers          int           // worker 数量
+	QueueSize        int           // 任务队列容量，0 表示无缓冲，AddTask 会等到有空闲 worker
+	ProgressInterval time.Duration // 大于 0 时按该间隔打印进度日志
+	Name             string        // 进度日志的前缀，如平台名
+	Unit             string        // 进度日志中任务的单位，如 "accounts"，默认 "tasks"
+}
+
 // WorkerPool 协程池结构
 type WorkerPool struct {
 	workerCount int
 	tasks       chan func() // 任务队列
 	wg          sync.WaitGroup
+	opts        Options
+
+	ctx    context.Context // 传给任务的 context，Shutdown 超时时取消
+	cancel context.CancelFunc
+
+	mu        sync.Mutex
+	errs      []TaskError
+	shutdown  bool // 为 true 时不再执行新任务
+	closeOnce sync.Once
+	counters  counters
 }
 
 // NewWorkerPool 创建一个新的协程池
 func NewWorkerPool(workerCount int) *WorkerPool {
+	return NewWorkerPoolWithContext(context.Background(), workerCount)
+}
+
+// NewWorkerPoolWithContext 创建协程池，任务收到的 context 派生自 ctx
+func NewWorkerPoolWithContext(ctx context.Context, workerCount int) *WorkerPool {
+	return NewWorkerPoolWithOptions(ctx, Options{Workers: workerCount})
+}
+
+// NewWorkerPoolWithOptions 按配置创建协程池，任务收到的 context 派生自 ctx
+func NewWorkerPoolWithOptions(ctx context.Context, opts Options) *WorkerPool {
+	if opts.Unit == "" {
+		opts.Unit = "tasks"
+	}
+	ctx, cancel := context.WithCancel(ctx)
 	return &WorkerPool{
-		workerCount: workerCount,
-		tasks:       make(chan func()),
+		workerCount: opts.Workers,
+		tasks:       make(chan func(), opts.QueueSize),
+		opts:        opts,
+		ctx:         ctx,
+		cancel:      cancel,
 	}
 }
 
@@ -30,23 +95,147 @@ func (wp *WorkerPool) Run() {
 			}
 		}(i + 1)
 	}
+	if wp.opts.ProgressInterval > 0 {
+		go wp.logProgress(wp.opts.ProgressInterval)
+	}
 }
 
-// AddTask 添加任务到任务队列
+// AddTask 添加任务到任务队列，任务中的 panic 会被恢复并记录到 Wait 的结果中
 func (wp *WorkerPool) AddTask(task func()) {
+	wp.AddTaskE("", func(context.Context) error {
+		task()
+		return nil
+	})
+}
+
+// AddTaskE 添加返回错误的任务，label 用于在错误中标识任务（如租户或 trace ID）
+// 协程池已 Shutdown 时任务不会执行，记录为 ErrPoolShutdown
+func (wp *WorkerPool) AddTaskE(label string, task func(ctx context.Context) error) {
+	wp.mu.Lock()
+	if wp.shutdown {
+		wp.errs = append(wp.errs, TaskError{Label: label, Err: ErrPoolShutdown})
+		wp.counters.submitted++
+		wp.counters.failed++
+		wp.mu.Unlock()
+		return
+	}
 	wp.wg.Add(1)
-	wp.tasks <- func() {
-		defer wp.wg.Done()
+	wp.counters.submitted++
+	wp.mu.Unlock()
+
+	wp.tasks <- wp.wrap(label, task)
+}
+
+// TryAddTask 尝试添加任务，队列已满或协程池已关闭时立即返回 false
+func (wp *WorkerPool) TryAddTask(task func()) bool {
+	return wp.TryAddTaskE("", func(context.Context) error {
 		task()
+		return nil
+	})
+}
+
+// TryAddTaskE 尝试添加返回错误的任务，队列已满或协程池已关闭时立即返回 false
+func (wp *WorkerPool) TryAddTaskE(label string, task func(ctx context.Context) error) bool {
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+	if wp.shutdown {
+		return false
+	}
+
+	wp.wg.Add(1)
+	select {
+	case wp.tasks <- wp.wrap(label, task):
+		wp.counters.submitted++
+		return true
+	default:
+		wp.wg.Done()
+		return false
 	}
 }
 
-// Wait 等待所有任务完成
-func (wp *WorkerPool) Wait() {
+// wrap 把任务包装成队列中的函数，执行结束时通知 Wait
+func (wp *WorkerPool) wrap(label string, task func(ctx context.Context) error) func() {
+	return func() {
+		defer wp.wg.Done()
+		wp.runTask(label, task)
+	}
+}
+
+// runTask 执行单个任务，把返回的错误和 panic 记录下来
+func (wp *WorkerPool) runTask(label string, task func(ctx context.Context) error) {
+	wp.mu.Lock()
+	if wp.shutdown {
+		wp.errs = append(wp.errs, TaskError{Label: label, Err: ErrPoolShutdown})
+		wp.counters.failed++
+		wp.mu.Unlock()
+		return
+	}
+	wp.counters.running++
+	wp.mu.Unlock()
+
+	start := time.Now()
+	err := runSafely(wp.ctx, label, task)
+	elapsed := time.Since(start)
+
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+	wp.counters.running--
+	wp.counters.observe(TaskLatency{Label: label, Duration: elapsed, Failed: err != nil})
+	if err != nil {
+		wp.errs = append(wp.errs, TaskError{Label: label, Err: err})
+		wp.counters.failed++
+		return
+	}
+	wp.counters.done++
+}
+
+// runSafely 执行任务，把 panic 转换为 ErrTaskPanic 错误
+func runSafely(ctx context.Context, label string, task func(ctx context.Context) error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("[%s] task panic: %v\n%s", label, r, debug.Stack())
+			err = fmt.Errorf("%w: %v", ErrTaskPanic, r)
+		}
+	}()
+	return task(ctx)
+}
+
+// Wait 等待所有任务完成，返回失败任务的错误（包括 panic）
+func (wp *WorkerPool) Wait() []TaskError {
 	wp.wg.Wait()
+
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+	return append([]TaskError(nil), wp.errs...)
+}
+
+// Shutdown 优雅关闭：不再执行尚未开始的任务，等待正在执行的任务结束
+// ctx 结束时仍未完成则取消任务的 context 并返回 ctx.Err()
+func (wp *WorkerPool) Shutdown(ctx context.Context) error {
+	wp.mu.Lock()
+	wp.shutdown = true
+	wp.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		wp.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		wp.Close()
+		return nil
+	case <-ctx.Done():
+		wp.cancel()
+		return ctx.Err()
+	}
 }
 
-// Close 关闭任务队列
+// Close 关闭任务队列，可重复调用
 func (wp *WorkerPool) Close() {
-	close(wp.tasks)
+	wp.closeOnce.Do(func() {
+		close(wp.tasks)
+		wp.cancel()
+	})
 }
@@ -0,0 +1,339 @@
+package t_pool
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWorkerPoolCollectsErrors(t *testing.T) {
+	pool := NewWorkerPool(2)
+	pool.Run()
+	defer pool.Close()
+
+	var ran atomic.Int32
+	pool.AddTask(func() { ran.Add(1) })
+	pool.AddTask(func() { panic("boom") })
+	pool.AddTaskE("tenant-1", func(ctx context.Context) error {
+		return errors.New("api error")
+	})
+	pool.AddTaskE("tenant-2", func(ctx context.Context) error {
+		ran.Add(1)
+		return nil
+	})
+
+	errs := pool.Wait()
+	if ran.Load() != 2 {
+		t.Fatalf("期望 2 个任务成功执行，实际 %d", ran.Load())
+	}
+	if len(errs) != 2 {
+		t.Fatalf("期望 2 个错误，实际 %d: %v", len(errs), errs)
+	}
+
+	var panicked, labeled bool
+	for _, e := range errs {
+		if errors.Is(e, ErrTaskPanic) {
+			panicked = true
+		}
+		if e.Label == "tenant-1" && e.Err.Error() == "api error" {
+			labeled = true
+		}
+	}
+	if !panicked || !labeled {
+		t.Fatalf("错误内容不符合预期: %v", errs)
+	}
+}
+
+func TestWorkerPoolShutdown(t *testing.T) {
+	pool := NewWorkerPool(1)
+	pool.Run()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	pool.AddTaskE("running", func(ctx context.Context) error {
+		close(started)
+		<-release
+		return nil
+	})
+	<-started
+
+	// 等待中的任务在 Shutdown 后不应执行
+	go pool.AddTaskE("queued", func(ctx context.Context) error {
+		t.Error("Shutdown 后不应执行排队的任务")
+		return nil
+	})
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		close(release)
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := pool.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown 失败: %v", err)
+	}
+
+	pool.AddTaskE("late", func(ctx context.Context) error {
+		t.Error("Shutdown 后添加的任务不应执行")
+		return nil
+	})
+	for _, e := range pool.Wait() {
+		if !errors.Is(e, ErrPoolShutdown) {
+			t.Fatalf("期望 ErrPoolShutdown，实际 %v", e)
+		}
+	}
+}
+
+func TestWorkerPoolShutdownTimeout(t *testing.T) {
+	pool := NewWorkerPool(1)
+	pool.Run()
+
+	started := make(chan struct{})
+	pool.AddTaskE("slow", func(ctx context.Context) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := pool.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("期望超时，实际 %v", err)
+	}
+
+	// 超时后任务 context 被取消，任务随之结束
+	errs := pool.Wait()
+	if len(errs) != 1 || !errors.Is(errs[0], context.Canceled) {
+		t.Fatalf("期望任务被取消，实际 %v", errs)
+	}
+}
+
+func TestKeyedPoolFairness(t *testing.T) {
+	// 单个 worker 时执行顺序完全由调度决定
+	pool := NewKeyedPool(1, 1)
+	defer pool.Close()
+
+	var mu sync.Mutex
+	running := map[string]int{}
+	var order []string
+	task := func(key string) func(context.Context) error {
+		return func(context.Context) error {
+			mu.Lock()
+			running[key]++
+			if running[key] > 1 {
+				mu.Unlock()
+				return errors.New("超过键的并发上限")
+			}
+			order = append(order, key)
+			mu.Unlock()
+
+			time.Sleep(5 * time.Millisecond)
+
+			mu.Lock()
+			running[key]--
+			mu.Unlock()
+			return nil
+		}
+	}
+
+	// 大租户先入队 10 个任务，小租户后入队 1 个
+	for i := 0; i < 10; i++ {
+		pool.AddTaskE("big", "big", task("big"))
+	}
+	pool.AddTaskE("small", "small", task("small"))
+	pool.Run()
+
+	if errs := pool.Wait(); len(errs) != 0 {
+		t.Fatalf("任务失败: %v", errs)
+	}
+	if len(order) != 11 {
+		t.Fatalf("期望执行 11 个任务，实际 %d", len(order))
+	}
+	// 轮转调度下小租户不需要等大租户的任务全部完成
+	for i, key := range order {
+		if key == "small" {
+			if i != 1 {
+				t.Fatalf("小租户任务被饿死，执行顺序: %v", order)
+			}
+			return
+		}
+	}
+	t.Fatalf("小租户任务没有执行: %v", order)
+}
+
+func TestKeyedPoolSetKeyLimit(t *testing.T) {
+	pool := NewKeyedPool(4, 1)
+	pool.SetKeyLimit("platform", 3)
+	pool.Run()
+	defer pool.Close()
+
+	var cur, peak atomic.Int32
+	for i := 0; i < 9; i++ {
+		pool.AddTask("platform", func() {
+			n := cur.Add(1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			cur.Add(-1)
+		})
+	}
+	pool.AddTask("panic", func() { panic("boom") })
+
+	errs := pool.Wait()
+	if peak.Load() > 3 {
+		t.Fatalf("并发数 %d 超过键的上限 3", peak.Load())
+	}
+	if len(errs) != 1 || !errors.Is(errs[0], ErrTaskPanic) || errs[0].Label != "panic" {
+		t.Fatalf("期望一个 panic 错误，实际 %v", errs)
+	}
+}
+
+func TestKeyedGroup(t *testing.T) {
+	pool := NewKeyedPool(2, 1)
+	pool.Run()
+
+	// 两组任务共用一个协程池，各自只等待自己的任务
+	slow := make(chan struct{})
+	other := pool.Group()
+	other.AddTaskE("acc-2", "slow", func(context.Context) error {
+		<-slow
+		return nil
+	})
+
+	g := pool.Group()
+	var done atomic.Int32
+	for i := 0; i < 3; i++ {
+		g.AddTaskE("acc-1", "ok", func(context.Context) error {
+			done.Add(1)
+			return nil
+		})
+	}
+	g.AddTaskE("acc-1", "panic", func(context.Context) error { panic("boom") })
+
+	errs := g.Wait()
+	if done.Load() != 3 {
+		t.Fatalf("组内任务应全部完成，实际 %d", done.Load())
+	}
+	if len(errs) != 1 || !errors.Is(errs[0], ErrTaskPanic) || errs[0].Label != "panic" {
+		t.Fatalf("组内应只有一个 panic 错误，实际 %v", errs)
+	}
+
+	close(slow)
+	if errs := other.Wait(); len(errs) != 0 {
+		t.Fatalf("另一组不应有错误: %v", errs)
+	}
+	pool.Close()
+
+	closed := pool.Group()
+	closed.AddTaskE("acc-1", "late", func(context.Context) error { return nil })
+	if errs := closed.Wait(); len(errs) != 1 || !errors.Is(errs[0], ErrPoolShutdown) {
+		t.Fatalf("协程池关闭后提交的任务应返回 ErrPoolShutdown，实际 %v", errs)
+	}
+}
+
+func TestKeyedPoolShutdown(t *testing.T) {
+	pool := NewKeyedPool(1, 1)
+	pool.Run()
+
+	started := make(chan struct{})
+	pool.AddTaskE("big", "running", func(ctx context.Context) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	<-started
+
+	// 排队中的任务不再执行，组的 Wait 也不会被卡住
+	g := pool.Group()
+	g.AddTaskE("small", "queued", func(context.Context) error {
+		t.Error("Shutdown 后不应执行排队的任务")
+		return nil
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := pool.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("运行中的任务未结束时 Shutdown 应超时，实际 %v", err)
+	}
+	if errs := g.Wait(); len(errs) != 1 || !errors.Is(errs[0], ErrPoolShutdown) {
+		t.Fatalf("排队的任务应记录为 ErrPoolShutdown，实际 %v", errs)
+	}
+
+	// 超时后任务的 context 被取消，运行中的任务随之结束
+	errs := pool.Wait()
+	if len(errs) != 2 {
+		t.Fatalf("期望 2 个错误，实际 %v", errs)
+	}
+	for _, e := range errs {
+		if e.Label == "running" && !errors.Is(e, context.Canceled) {
+			t.Fatalf("运行中的任务应收到取消，实际 %v", e)
+		}
+	}
+}
+
+func TestWorkerPoolTryAddTaskAndStats(t *testing.T) {
+	pool := NewWorkerPoolWithOptions(context.Background(), Options{Workers: 1, QueueSize: 1})
+	pool.Run()
+	defer pool.Close()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	pool.AddTaskE("blocking", func(ctx context.Context) error {
+		close(started)
+		<-release
+		return nil
+	})
+	<-started
+
+	// worker 忙、队列容量为 1：第一个进入队列，第二个被拒绝
+	if !pool.TryAddTaskE("queued", func(ctx context.Context) error { return errors.New("failed") }) {
+		t.Fatal("队列未满时 TryAddTaskE 应成功")
+	}
+	if pool.TryAddTask(func() {}) {
+		t.Fatal("队列已满时 TryAddTask 应返回 false")
+	}
+
+	s := pool.Stats()
+	if s.Submitted != 2 || s.Running != 1 || s.Queued != 1 {
+		t.Fatalf("运行中快照不符合预期: %+v", s)
+	}
+
+	close(release)
+	pool.Wait()
+
+	s = pool.Stats()
+	if s.Done != 1 || s.Failed != 1 || s.Queued != 0 || s.Running != 0 || len(s.Latencies) != 2 {
+		t.Fatalf("结束后快照不符合预期: %+v", s)
+	}
+	if s.MaxLatency < s.AvgLatency {
+		t.Fatalf("耗时统计不符合预期: avg=%v max=%v", s.AvgLatency, s.MaxLatency)
+	}
+}
+
+func TestStatsLatencyWindow(t *testing.T) {
+	var c counters
+	n := maxRecentLatencies + 10
+	for i := 1; i <= n; i++ {
+		c.observe(TaskLatency{Label: strconv.Itoa(i), Duration: time.Duration(i) * time.Millisecond})
+	}
+	recent := c.recentLatencies()
+	if len(recent) != maxRecentLatencies || len(c.recent) != maxRecentLatencies {
+		t.Fatalf("最近耗时应限制在 %d 条，实际 %d", maxRecentLatencies, len(c.recent))
+	}
+	if recent[0].Label != "11" || recent[len(recent)-1].Label != strconv.Itoa(n) {
+		t.Fatalf("应按结束顺序保留最近的任务: %s ... %s", recent[0].Label, recent[len(recent)-1].Label)
+	}
+	// 平均和最大耗时按全部任务统计
+	if c.latencyMax != time.Duration(n)*time.Millisecond || c.latencySum/time.Duration(c.finished) != time.Duration(n+1)*time.Millisecond/2 {
+		t.Fatalf("耗时统计不符合预期: max=%v avg=%v", c.latencyMax, c.latencySum/time.Duration(c.finished))
+	}
+}
@@ -0,0 +1,124 @@
+package t_pool
+
+import (
+	"log"
+	"time"
+)
+
+// maxRecentLatencies Stats.Latencies 保留的最近任务数，平均和最大耗时仍按全部任务统计
+const maxRecentLatencies = 256
+
+// TaskLatency 单个已结束任务的耗时
+type TaskLatency struct {
+	Label    string
+	Duration time.Duration
+	Failed   bool
+}
+
+// Stats 协程池的运行快照
+type Stats struct {
+	Total      int // 预期任务总数，未调用 SetTotal 时等于已提交数
+	Submitted  int // 已提交的任务数
+	Queued     int // 已提交但尚未开始的任务数
+	Running    int
+	Done       int // 成功结束的任务数
+	Failed     int // 返回错误、panic 或因关闭未执行的任务数
+	AvgLatency time.Duration
+	MaxLatency time.Duration
+	Latencies  []TaskLatency // 最近结束的任务（最多 maxRecentLatencies 个）的耗时，按结束顺序排列
+}
+
+// counters 协程池内部计数，由 WorkerPool.mu 保护
+type counters struct {
+	total     int
+	submitted int
+	running   int
+	done      int
+	failed    int
+
+	// 耗时统计：全部任务的次数、总和和最大值，以及最近任务的环形缓冲，长期运行时内存不会增长
+	finished   int
+	latencySum time.Duration
+	latencyMax time.Duration
+	recent     []TaskLatency
+	next       int // recent 写满后下一次覆盖的位置
+}
+
+// observe 记录一个已结束任务的耗时
+func (c *counters) observe(l TaskLatency) {
+	c.finished++
+	c.latencySum += l.Duration
+	c.latencyMax = max(c.latencyMax, l.Duration)
+	if len(c.recent) < maxRecentLatencies {
+		c.recent = append(c.recent, l)
+		return
+	}
+	c.recent[c.next] = l
+	c.next = (c.next + 1) % maxRecentLatencies
+}
+
+// recentLatencies 按结束顺序返回最近任务的耗时
+func (c *counters) recentLatencies() []TaskLatency {
+	res := make([]TaskLatency, 0, len(c.recent))
+	res = append(res, c.recent[c.next:]...)
+	return append(res, c.recent[:c.next]...)
+}
+
+// SetTotal 设置预期的任务总数，用于进度日志
+// 任务边执行边提交时，已提交数不能代表总进度
+func (wp *WorkerPool) SetTotal(n int) {
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+	wp.counters.total = n
+}
+
+// Stats 返回协程池当前的运行快照
+func (wp *WorkerPool) Stats() Stats {
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+
+	c := wp.counters
+	s := Stats{
+		Total:      c.total,
+		Submitted:  c.submitted,
+		Queued:     c.submitted - c.running - c.done - c.failed,
+		Running:    c.running,
+		Done:       c.done,
+		Failed:     c.failed,
+		MaxLatency: c.latencyMax,
+		Latencies:  c.recentLatencies(),
+	}
+	if s.Total < s.Submitted {
+		s.Total = s.Submitted
+	}
+	if c.finished > 0 {
+		s.AvgLatency = c.latencySum / time.Duration(c.finished)
+	}
+	return s
+}
+
+// logProgress 按 interval 打印进度，协程池关闭或取消时打印最后一次
+func (wp *WorkerPool) logProgress(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			wp.printProgress()
+		case <-wp.ctx.Done():
+			wp.printProgress()
+			return
+		}
+	}
+}
+
+func (wp *WorkerPool) printProgress() {
+	s := wp.Stats()
+	prefix := ""
+	if wp.opts.Name != "" {
+		prefix = "[" + wp.opts.Name + "] "
+	}
+	log.Printf("%s%d/%d %s done, %d failed, %d running, %d queued, avg %v, max %v",
+		prefix, s.Done+s.Failed, s.Total, wp.opts.Unit, s.Failed, s.Running, s.Queued,
+		s.AvgLatency.Truncate(time.Millisecond), s.MaxLatency.Truncate(time.Millisecond))
+}
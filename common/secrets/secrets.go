@@ -0,0 +1,226 @@
+// Package secrets 按名称解析连接器的密钥，代码中不再保存任何凭证
+// 查找顺序：挂载目录中的同名文件 > 环境变量 > Apollo（local 环境为本地配置文件）
+// 解析结果按 TTL 缓存，过期后重新读取，密钥轮换后无需重启进程
+package secrets
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"wm-func/common/config"
+)
+
+// Name 密钥名称，同时决定在各来源中的位置
+//   - 挂载目录：<dir>/<name>
+//   - 环境变量：WM_SECRETS_<NAME>，如 WM_SECRETS_PINTEREST_CLIENT_CREDENTIALS
+//   - Apollo：application namespace 下的 secrets.<name>
+type Name string
+
+const (
+	PinterestClientCredentials Name = "pinterest.client.credentials" // base64(client_id:client_secret)
+	GoogleAdsDeveloperToken    Name = "google_ads.developer_token"
+	GoogleAdsClientID          Name = "google_ads.client_id"
+	GoogleAdsClientSecret      Name = "google_ads.client_secret"
+	GeminiAPIKey               Name = "gemini.api_key"
+	AirbyteBasicAuth           Name = "airbyte.basic_auth" // base64(user:password)
+	AirbyteAPIKey              Name = "airbyte.api_key"
+	AirbyteWebCookie           Name = "airbyte.web_cookie"       // airbyte web 控制台的登录 Cookie
+	ApplovinGCSCredentials     Name = "applovin.gcs.credentials" // GCS service account JSON
+	AmazonVendorClientID       Name = "amazon_vendor.client_id"
+	AmazonVendorClientSecret   Name = "amazon_vendor.client_secret"
+)
+
+// DirEnv 挂载目录的环境变量，未设置时使用 DefaultDir
+const DirEnv = "WM_SECRETS_DIR"
+
+// DefaultDir 默认的挂载目录
+const DefaultDir = "/var/run/secrets/wm"
+
+// DefaultTTL 缓存的有效期
+const DefaultTTL = 5 * time.Minute
+
+// ErrNotFound 所有来源中都没有该密钥
+var ErrNotFound = errors.New("secret not found")
+
+// Source 密钥来源
+type Source interface {
+	// Lookup 查找密钥，不存在时 ok 为 false
+	Lookup(name Name) (value string, ok bool, err error)
+	Name() string
+}
+
+// DirSource 从挂载目录读取，每个密钥一个文件，去掉首尾空白
+type DirSource struct {
+	Dir string
+}
+
+func (s DirSource) Lookup(name Name) (string, bool, error) {
+	b, err := os.ReadFile(filepath.Join(s.Dir, string(name)))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	v := strings.TrimSpace(string(b))
+	return v, v != "", nil
+}
+
+func (s DirSource) Name() string {
+	return "dir:" + s.Dir
+}
+
+// ConfigSource 从配置来源读取，默认使用 config.Default()，即环境变量加 Apollo 或本地文件
+type ConfigSource struct {
+	Provider config.Provider // 为空时使用 config.Default()
+}
+
+func (s ConfigSource) Lookup(name Name) (string, bool, error) {
+	p := s.Provider
+	if p == nil {
+		p = config.Default()
+	}
+	v, ok := p.Get(config.NamespaceApplication, "secrets."+string(name))
+	return v, ok, nil
+}
+
+func (s ConfigSource) Name() string {
+	return "config"
+}
+
+type entry struct {
+	value     string
+	expiresAt time.Time
+}
+
+// Store 按顺序查找多个来源并缓存结果，可并发使用
+type Store struct {
+	sources []Source
+	ttl     time.Duration
+	now     func() time.Time
+
+	mu    sync.Mutex
+	cache map[Name]entry
+}
+
+// NewStore 创建 Store，ttl <= 0 时不缓存
+func NewStore(ttl time.Duration, sources ...Source) *Store {
+	return &Store{
+		sources: sources,
+		ttl:     ttl,
+		now:     time.Now,
+		cache:   make(map[Name]entry),
+	}
+}
+
+// Get 返回密钥，缓存过期后重新解析
+// 重新解析时来源出错则继续使用旧值，避免 Apollo 短暂不可用导致任务失败
+func (s *Store) Get(name Name) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cached, hit := s.cache[name]
+	if hit && s.now().Before(cached.expiresAt) {
+		return cached.value, nil
+	}
+
+	value, err := s.resolve(name)
+	if err != nil {
+		if hit && !errors.Is(err, ErrNotFound) {
+			log.Printf("secrets: 刷新 %s 失败，继续使用缓存: %v", name, err)
+			return cached.value, nil
+		}
+		return "", err
+	}
+	if hit && cached.value != value {
+		log.Printf("secrets: %s 已轮换", name)
+	}
+	if s.ttl > 0 {
+		s.cache[name] = entry{value: value, expiresAt: s.now().Add(s.ttl)}
+	}
+	return value, nil
+}
+
+// MustGet 返回密钥，找不到时 panic，用于启动阶段
+func (s *Store) MustGet(name Name) string {
+	v, err := s.Get(name)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// Invalidate 清除缓存，下次 Get 时重新解析；不传名称时清除全部
+func (s *Store) Invalidate(names ...Name) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(names) == 0 {
+		s.cache = make(map[Name]entry)
+		return
+	}
+	for _, name := range names {
+		delete(s.cache, name)
+	}
+}
+
+func (s *Store) resolve(name Name) (string, error) {
+	var errs []error
+	for _, src := range s.sources {
+		v, ok, err := src.Lookup(name)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", src.Name(), err))
+			continue
+		}
+		if ok {
+			return v, nil
+		}
+	}
+	if len(errs) > 0 {
+		return "", fmt.Errorf("secrets: resolve %s: %w", name, errors.Join(errs...))
+	}
+	return "", fmt.Errorf("%w: %s", ErrNotFound, name)
+}
+
+var (
+	defaultStore *Store
+	defaultMu    sync.Mutex
+)
+
+// Default 返回默认的 Store：挂载目录优先，其次是 config 包的配置来源
+func Default() *Store {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+
+	if defaultStore == nil {
+		dir := os.Getenv(DirEnv)
+		if dir == "" {
+			dir = DefaultDir
+		}
+		defaultStore = NewStore(DefaultTTL, DirSource{Dir: dir}, ConfigSource{})
+	}
+	return defaultStore
+}
+
+// SetDefault 替换默认的 Store，用于测试
+func SetDefault(s *Store) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultStore = s
+}
+
+// Get 从默认 Store 获取密钥
+func Get(name Name) (string, error) {
+	return Default().Get(name)
+}
+
+// MustGet 从默认 Store 获取密钥，找不到时 panic
+func MustGet(name Name) string {
+	return Default().MustGet(name)
+}
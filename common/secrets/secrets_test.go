@@ -0,0 +1,79 @@
+package secrets
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"wm-func/common/config"
+)
+
+type failingSource struct{ fail bool }
+
+func (s *failingSource) Lookup(name Name) (string, bool, error) {
+	if s.fail {
+		return "", false, errors.New("apollo unavailable")
+	}
+	return "from-source", true, nil
+}
+
+func (s *failingSource) Name() string { return "failing" }
+
+func TestStoreLookupOrder(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, string(GeminiAPIKey)), []byte("from-file\n"), 0o644)
+	t.Setenv(config.EnvKey("secrets."+string(GeminiAPIKey)), "from-env")
+	t.Setenv(config.EnvKey("secrets."+string(AirbyteAPIKey)), "from-env")
+
+	s := NewStore(time.Minute, DirSource{Dir: dir}, ConfigSource{Provider: config.EnvProvider{}})
+	if v, err := s.Get(GeminiAPIKey); err != nil || v != "from-file" {
+		t.Fatalf("挂载文件应优先: %q %v", v, err)
+	}
+	if v, err := s.Get(AirbyteAPIKey); err != nil || v != "from-env" {
+		t.Fatalf("应从环境变量读取: %q %v", v, err)
+	}
+	if _, err := s.Get(PinterestClientCredentials); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("期望 ErrNotFound，实际 %v", err)
+	}
+}
+
+func TestStoreRotation(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, string(GoogleAdsDeveloperToken))
+	os.WriteFile(path, []byte("v1"), 0o644)
+
+	now := time.Now()
+	src := &failingSource{}
+	s := NewStore(time.Minute, DirSource{Dir: dir}, src)
+	s.now = func() time.Time { return now }
+
+	if v, _ := s.Get(GoogleAdsDeveloperToken); v != "v1" {
+		t.Fatalf("期望 v1，实际 %q", v)
+	}
+	os.WriteFile(path, []byte("v2"), 0o644)
+	if v, _ := s.Get(GoogleAdsDeveloperToken); v != "v1" {
+		t.Fatalf("缓存有效期内应返回旧值，实际 %q", v)
+	}
+	now = now.Add(2 * time.Minute)
+	if v, _ := s.Get(GoogleAdsDeveloperToken); v != "v2" {
+		t.Fatalf("缓存过期后应读取轮换后的值，实际 %q", v)
+	}
+
+	// 文件被删除后回退到下一个来源；来源出错时继续使用缓存
+	os.Remove(path)
+	s.Invalidate(GoogleAdsDeveloperToken)
+	if v, _ := s.Get(GoogleAdsDeveloperToken); v != "from-source" {
+		t.Fatalf("期望从下一个来源读取，实际 %q", v)
+	}
+	src.fail = true
+	now = now.Add(2 * time.Minute)
+	if v, err := s.Get(GoogleAdsDeveloperToken); err != nil || v != "from-source" {
+		t.Fatalf("来源出错时应继续使用缓存: %q %v", v, err)
+	}
+	s.Invalidate()
+	if _, err := s.Get(GoogleAdsDeveloperToken); err == nil || errors.Is(err, ErrNotFound) {
+		t.Fatalf("没有缓存时应返回来源的错误，实际 %v", err)
+	}
+}
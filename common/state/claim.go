@@ -0,0 +1,192 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+	"wm-func/common/db/platform_db"
+
+	"gorm.io/gorm"
+)
+
+/*
+ALTER TABLE platform_offline.thirds_integration_sync_increment_info
+	ADD COLUMN `owner_id` VARCHAR(128) NOT NULL DEFAULT '' COMMENT '当前认领任务的实例',
+	ADD COLUMN `heartbeat_at` DATETIME NULL COMMENT '认领者最近一次心跳时间',
+	ADD COLUMN `attempts` INT NOT NULL DEFAULT 0 COMMENT '自上次成功以来的认领次数';
+*/
+
+// DefaultStaleAfter 心跳超过该时长未更新的任务视为认领者已失效
+const DefaultStaleAfter = 10 * time.Minute
+
+// DefaultHeartbeatInterval 默认心跳间隔，需明显小于 staleAfter
+const DefaultHeartbeatInterval = time.Minute
+
+var ErrNotTaskOwner = errors.New("not the owner of this task")
+
+// TaskClaim 任务认领信息
+type TaskClaim struct {
+	TenantId    int64      `gorm:"primaryKey;column:tenant_id" json:"tenant_id"`
+	AccountId   string     `gorm:"primaryKey;column:account_id" json:"account_id"`
+	RawPlatform string     `gorm:"primaryKey;column:raw_platform" json:"raw_platform"`
+	SubType     string     `gorm:"primaryKey;column:sub_type" json:"sub_type"`
+	IsRunning   int        `gorm:"column:is_running" json:"is_running"`
+	OwnerId     string     `gorm:"column:owner_id" json:"owner_id"`
+	HeartbeatAt *time.Time `gorm:"column:heartbeat_at" json:"heartbeat_at"`
+	Attempts    int        `gorm:"column:attempts" json:"attempts"`
+	CreateTime  string     `gorm:"column:create_time" json:"create_time"`
+}
+
+func (TaskClaim) TableName() string {
+	return "platform_offline.thirds_integration_sync_increment_info"
+}
+
+// ClaimTask 认领任务
+// 任务未运行、认领者就是 owner、或原认领者的心跳已超过 staleAfter 时认领成功，并将 attempts 加一
+func ClaimTask(key StateKey, owner string, staleAfter time.Duration) (TaskResult, error) {
+	if staleAfter <= 0 {
+		staleAfter = DefaultStaleAfter
+	}
+
+	conn := platform_db.GetDB()
+	now := time.Now()
+	cutoff := now.Add(-staleAfter)
+
+	// 旧版本认领的行没有 heartbeat_at，退回到按 create_time 判断
+	result := key.where(conn.Model(&TaskClaim{})).
+		Where("is_running = 0 OR owner_id = ? OR heartbeat_at < ? OR (heartbeat_at IS NULL AND create_time < ?)",
+			owner, cutoff, cutoff.Format("2006-01-02 15:04:05")).
+		Updates(map[string]interface{}{
+			"is_running":   1,
+			"owner_id":     owner,
+			"heartbeat_at": now,
+			"create_time":  now.Format("2006-01-02 15:04:05"),
+			"attempts":     gorm.Expr("attempts + 1"),
+		})
+	if result.Error != nil {
+		return TaskResult{}, fmt.Errorf("claim task %s: %w", key, result.Error)
+	}
+
+	var claim TaskClaim
+	err := key.where(conn).Take(&claim).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return TaskResult{Status: TaskStatusNotFound}, nil
+		}
+		return TaskResult{}, fmt.Errorf("claim task %s: %w", key, err)
+	}
+
+	if result.RowsAffected == 0 {
+		return TaskResult{Status: TaskStatusAlreadyRunning, Claim: &claim}, nil
+	}
+
+	var record Records
+	if err := key.where(conn).Take(&record).Error; err != nil {
+		return TaskResult{}, fmt.Errorf("claim task %s: %w", key, err)
+	}
+	return TaskResult{Status: TaskStatusAcquired, Record: &record, Claim: &claim}, nil
+}
+
+// Heartbeat 刷新认领者的心跳，任务已被他人认领或已停止时返回 ErrNotTaskOwner
+func Heartbeat(key StateKey, owner string) error {
+	conn := platform_db.GetDB()
+	result := key.where(conn.Model(&TaskClaim{})).
+		Where("owner_id = ? AND is_running = 1", owner).
+		Update("heartbeat_at", time.Now())
+	if result.Error != nil {
+		return fmt.Errorf("heartbeat %s: %w", key, result.Error)
+	}
+	if result.RowsAffected > 0 {
+		return nil
+	}
+
+	// heartbeat_at 精确到秒，MySQL 返回的是实际修改的行数，
+	// 同一秒内的重复心跳影响 0 行，需要再查一次确认是否仍是认领者
+	var n int64
+	err := key.where(conn.Model(&TaskClaim{})).
+		Where("owner_id = ? AND is_running = 1", owner).
+		Count(&n).Error
+	if err != nil {
+		return fmt.Errorf("heartbeat %s: %w", key, err)
+	}
+	if n == 0 {
+		return ErrNotTaskOwner
+	}
+	return nil
+}
+
+// KeepAlive 在后台按 interval 发送心跳，返回的函数用于停止
+// 失去认领时停止心跳并记录日志，任务本身不会被中断
+func KeepAlive(ctx context.Context, key StateKey, owner string, interval time.Duration) (stop func()) {
+	if interval <= 0 {
+		interval = DefaultHeartbeatInterval
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				err := Heartbeat(key, owner)
+				if errors.Is(err, ErrNotTaskOwner) {
+					log.Printf("[%s] 任务已被其他实例认领，停止心跳", key)
+					return
+				}
+				if err != nil {
+					log.Printf("[%s] 心跳失败: %v", key, err)
+				}
+			}
+		}
+	}()
+
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// ReleaseTask 释放认领，只有当前认领者可以释放
+// success 为 true 时清零 attempts，否则保留以便发现反复失败的账户
+func ReleaseTask(key StateKey, owner string, success bool) error {
+	updates := map[string]interface{}{
+		"is_running":  0,
+		"owner_id":    "",
+		"create_time": time.Now().Format("2006-01-02 15:04:05"),
+	}
+	if success {
+		updates["attempts"] = 0
+	}
+
+	conn := platform_db.GetDB()
+	result := key.where(conn.Model(&TaskClaim{})).
+		Where("owner_id = ?", owner).
+		Updates(updates)
+	if result.Error != nil {
+		return fmt.Errorf("release task %s: %w", key, result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotTaskOwner
+	}
+	return nil
+}
+
+// ListRepeatedFailures 列出 attempts 不少于 minAttempts 的任务，用于发现反复崩溃的账户
+func ListRepeatedFailures(platform string, minAttempts int) ([]TaskClaim, error) {
+	var claims []TaskClaim
+	conn := platform_db.GetDB()
+	err := conn.Where("raw_platform = ? AND attempts >= ?", platform, minAttempts).
+		Order("attempts DESC").
+		Find(&claims).Error
+	if err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
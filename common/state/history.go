@@ -0,0 +1,201 @@
+package state
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+	"wm-func/common/db/platform_db"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+/*
+CREATE TABLE `sync_info_history` (
+	`id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+	`tenant_id` BIGINT NOT NULL,
+	`account_id` VARCHAR(255) NOT NULL,
+	`raw_platform` VARCHAR(128) NOT NULL,
+	`sub_type` VARCHAR(128) NOT NULL,
+	`version` BIGINT NOT NULL COMMENT '同一主键下从 1 开始递增',
+	`old_value` JSON NULL COMMENT '写入前的 sync_info',
+	`new_value` JSON NULL COMMENT '写入后的 sync_info',
+	`run_id` VARCHAR(255) NOT NULL DEFAULT '',
+	`host` VARCHAR(255) NOT NULL DEFAULT '',
+	`created_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (`id`),
+	UNIQUE KEY `uk_key_version` (`tenant_id`, `account_id`, `raw_platform`, `sub_type`, `version`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='同步状态变更历史';
+*/
+
+// HistoryEntry 一次同步状态变更
+type HistoryEntry struct {
+	ID          int64     `gorm:"primaryKey;autoIncrement;column:id" json:"id"`
+	TenantId    int64     `gorm:"column:tenant_id" json:"tenant_id"`
+	AccountId   string    `gorm:"column:account_id" json:"account_id"`
+	RawPlatform string    `gorm:"column:raw_platform" json:"raw_platform"`
+	SubType     string    `gorm:"column:sub_type" json:"sub_type"`
+	Version     int64     `gorm:"column:version" json:"version"`
+	OldValue    []byte    `gorm:"column:old_value" json:"old_value"`
+	NewValue    []byte    `gorm:"column:new_value" json:"new_value"`
+	RunId       string    `gorm:"column:run_id" json:"run_id"`
+	Host        string    `gorm:"column:host" json:"host"`
+	CreatedAt   time.Time `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+}
+
+func (HistoryEntry) TableName() string {
+	return "platform_offline.sync_info_history"
+}
+
+var ErrVersionNotFound = errors.New("state version not found")
+
+var (
+	runMu       sync.Mutex
+	runId       string
+	hostname, _ = os.Hostname()
+)
+
+// SetRunID 设置写入历史时记录的运行 ID，未设置时从环境变量推断
+func SetRunID(id string) {
+	runMu.Lock()
+	defer runMu.Unlock()
+	runId = id
+}
+
+// currentRunID 依次使用 WM_RUN_ID、Cloud Run 的执行 ID，都没有时按主机和进程生成
+func currentRunID() string {
+	runMu.Lock()
+	defer runMu.Unlock()
+
+	if runId != "" {
+		return runId
+	}
+	for _, env := range []string{"WM_RUN_ID", "CLOUD_RUN_EXECUTION", "K_REVISION"} {
+		if v := os.Getenv(env); v != "" {
+			runId = v
+			return runId
+		}
+	}
+	runId = fmt.Sprintf("%s-%d-%d", hostname, os.Getpid(), time.Now().Unix())
+	return runId
+}
+
+// lockSyncInfo 在事务内锁住并读取当前 sync_info，记录不存在或为空时返回 nil
+// 记录不存在时先插入一条空记录再加锁：对不存在的行 FOR UPDATE 锁不住任何东西，
+// 并发的首次写入会同时分配相同的历史版本号
+func lockSyncInfo(tx *gorm.DB, key StateKey) ([]byte, error) {
+	placeholder := Records{
+		TenantId:    key.Tenant,
+		AccountId:   key.Account,
+		RawPlatform: key.Platform,
+		SubType:     key.SubType,
+		SyncInfo:    []byte{},
+		CreateTime:  time.Now().Format("2006-01-02 15:04:05"),
+	}
+	if err := tx.Clauses(clause.Insert{Modifier: "IGNORE"}).Create(&placeholder).Error; err != nil {
+		return nil, err
+	}
+
+	var result SyncInfoResult
+	err := key.where(tx.Clauses(clause.Locking{Strength: "UPDATE"}).Model(&Records{}).Select("sync_info")).
+		Take(&result).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(result.SyncInfo) == 0 {
+		return nil, nil
+	}
+	return result.SyncInfo, nil
+}
+
+// recordHistory 追加一条变更历史，值没有变化时不记录
+func recordHistory(tx *gorm.DB, key StateKey, oldValue, newValue []byte) error {
+	if bytes.Equal(oldValue, newValue) {
+		return nil
+	}
+
+	// 调用方已锁住 sync_info 记录，同一个键的历史写入是串行的；
+	// 这里用加锁读取拿到已提交的最新版本，而不是事务开始时的快照
+	var version int64
+	err := key.where(tx.Clauses(clause.Locking{Strength: "UPDATE"}).Model(&HistoryEntry{})).
+		Select("COALESCE(MAX(version), 0)").
+		Scan(&version).Error
+	if err != nil {
+		return err
+	}
+
+	entry := HistoryEntry{
+		TenantId:    key.Tenant,
+		AccountId:   key.Account,
+		RawPlatform: key.Platform,
+		SubType:     key.SubType,
+		Version:     version + 1,
+		OldValue:    oldValue,
+		NewValue:    newValue,
+		RunId:       currentRunID(),
+		Host:        hostname,
+	}
+	return tx.Create(&entry).Error
+}
+
+// writeSyncInfo 在事务内写入 sync_info 并记录历史，不影响任务运行标记
+func writeSyncInfo(tx *gorm.DB, key StateKey, value []byte) error {
+	old, err := lockSyncInfo(tx, key)
+	if err != nil {
+		return err
+	}
+
+	record := Records{
+		TenantId:    key.Tenant,
+		AccountId:   key.Account,
+		RawPlatform: key.Platform,
+		SubType:     key.SubType,
+		SyncInfo:    value,
+		CreateTime:  time.Now().Format("2006-01-02 15:04:05"),
+	}
+	err = tx.Clauses(clause.OnConflict{
+		DoUpdates: clause.AssignmentColumns([]string{"sync_info", "create_time"}),
+	}).Create(&record).Error
+	if err != nil {
+		return err
+	}
+
+	return recordHistory(tx, key, old, value)
+}
+
+// History 返回最近 n 次变更，按版本从新到旧排列
+func History(key StateKey, n int) ([]HistoryEntry, error) {
+	return history(platform_db.GetDB(), key, n)
+}
+
+func history(db *gorm.DB, key StateKey, n int) ([]HistoryEntry, error) {
+	var entries []HistoryEntry
+	err := key.where(db).Order("version DESC").Limit(n).Find(&entries).Error
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// Rollback 将同步状态恢复为指定版本写入后的值
+// 回滚本身也会作为一次新的变更记录到历史中
+func Rollback(key StateKey, version int64) error {
+	return rollback(platform_db.GetDB(), key, version)
+}
+
+func rollback(db *gorm.DB, key StateKey, version int64) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var entry HistoryEntry
+		err := key.where(tx).Where("version = ?", version).Take(&entry).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("%w: %s version %d", ErrVersionNotFound, key, version)
+			}
+			return err
+		}
+		return writeSyncInfo(tx, key, entry.NewValue)
+	})
+}
@@ -1,9 +1,12 @@
 package state
 
 import (
+	"errors"
 	"fmt"
+	"os"
 	"time"
 	"wm-func/common/db/platform_db"
+	"wm-func/common/lock"
 
 	"gorm.io/gorm"
 )
@@ -22,13 +25,37 @@ func (r Records) TableName() string {
 	return "platform_offline.thirds_integration_sync_increment_info"
 }
 
+// keyOf 返回记录的主键
+func (r Records) keyOf() StateKey {
+	return StateKey{Tenant: r.TenantId, Account: r.AccountId, Platform: r.RawPlatform, SubType: r.SubType}
+}
+
+// Save 保存同步状态并记录变更历史
 func Save(records Records) {
-	conn := platform_db.GetDB()
-	if err := conn.Save(records).Error; err != nil {
+	if err := saveRecords(platform_db.GetDB(), records); err != nil {
 		panic(err)
 	}
 }
 
+// saveRecords 在事务内保存 sync_info，并把变化写入历史
+// 不写入 is_running 和认领信息，任务运行中保存进度不会释放认领
+func saveRecords(conn *gorm.DB, records Records) error {
+	return conn.Transaction(func(tx *gorm.DB) error {
+		return writeSyncInfo(tx, records.keyOf(), records.SyncInfo)
+	})
+}
+
+// SaveWithFence 在同一个事务中校验 fencing token 并保存同步状态
+// 当锁已被其他持有者重新获取时返回 lock.ErrStaleToken，不会写入
+func SaveWithFence(records Records, fence lock.Fence) error {
+	return platform_db.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := fence.Check(tx); err != nil {
+			return err
+		}
+		return writeSyncInfo(tx, records.keyOf(), records.SyncInfo)
+	})
+}
+
 func SaveSyncInfo(tenantId int64, accountId string, platform string, subtype string, info []byte) {
 	record := Records{
 		TenantId:    tenantId,
@@ -54,21 +81,17 @@ func (s *SyncInfoResult) TableName() string {
 	return "platform_offline.thirds_integration_sync_increment_info"
 }
 
+// GetSyncInfo 按完整主键读取单个账户的同步状态，不存在时返回 nil
 func GetSyncInfo(tenantId int64, accountId, platform, subType string) []byte {
-	sql := `select
-    sync_info
-from platform_offline.thirds_integration_sync_increment_info
-where tenant_id = %d
--- and account_id = '%s'
-and raw_platform = '%s'
-and sub_type = '%s'
-`
-	sql = fmt.Sprintf(sql, tenantId, accountId, platform, subType)
-
 	var result SyncInfoResult
 	client := platform_db.GetDB()
-	if err := client.Raw(sql).First(&result).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
+	err := client.Model(&Records{}).
+		Select("sync_info").
+		Where("tenant_id = ? AND account_id = ? AND raw_platform = ? AND sub_type = ?",
+			tenantId, accountId, platform, subType).
+		Take(&result).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil
 		}
 		panic(err)
@@ -79,6 +102,21 @@ and sub_type = '%s'
 	return result.SyncInfo
 }
 
+// GetTenantSyncInfos 读取租户在某个平台、子类型下所有账户的同步状态
+// 仅用于确实需要跨账户查看的场景，普通同步请使用 GetSyncInfo
+func GetTenantSyncInfos(tenantId int64, platform, subType string) ([]Records, error) {
+	var result []Records
+	client := platform_db.GetDB()
+	err := client.
+		Where("tenant_id = ? AND raw_platform = ? AND sub_type = ?", tenantId, platform, subType).
+		Order("account_id").
+		Find(&result).Error
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
 // TaskStatus 任务状态枚举
 type TaskStatus int
 
@@ -92,84 +130,22 @@ const (
 type TaskResult struct {
 	Status TaskStatus
 	Record *Records
+	Claim  *TaskClaim // 认领信息，任务不存在时为 nil
 }
 
-// GetAvailableTask 获取可执行的任务，使用乐观锁
-func GetAvailableTask(tenantId int64, accountId, platform, subType string) TaskResult {
-	conn := platform_db.GetDB()
-
-	// 计算1小时前的时间
-	oneHourAgo := time.Now().Add(-time.Hour).Format("2006-01-02 15:04:05")
-
-	// 查询符合条件的记录 (is_running = 0 或 create_time > 1小时前)
-	var record Records
-	err := conn.Where("tenant_id = ? AND account_id = ? AND raw_platform = ? AND sub_type = ? AND (is_running = 0 OR create_time < ?)",
-		tenantId, accountId, platform, subType, oneHourAgo).First(&record).Error
+// defaultOwner 未指定认领者时使用的进程级标识
+var defaultOwner = fmt.Sprintf("%s-%d", hostname, os.Getpid())
 
+// GetAvailableTask 获取可执行的任务，认领者为当前进程，心跳超过1小时视为失效
+//
+// Deprecated: 使用 ClaimTask 指定认领者和失效时间，并通过 Heartbeat/KeepAlive 保持认领
+func GetAvailableTask(tenantId int64, accountId, platform, subType string) TaskResult {
+	key := StateKey{Tenant: tenantId, Account: accountId, Platform: platform, SubType: subType}
+	result, err := ClaimTask(key, defaultOwner, time.Hour)
 	if err != nil {
-		if err == gorm.ErrRecordNotFound {
-			return TaskResult{Status: TaskStatusNotFound}
-		}
 		panic(err)
 	}
-
-	// 如果记录的create_time大于1小时且is_running=1，先重置为0
-	if record.IsRunning == 1 && record.CreateTime < oneHourAgo {
-		// 使用乐观锁先更新为0
-		result := conn.Model(&Records{}).
-			Where("tenant_id = ? AND account_id = ? AND raw_platform = ? AND sub_type = ? AND is_running = ?",
-				tenantId, accountId, platform, subType, 1, record.CreateTime).
-			Updates(map[string]interface{}{
-				"is_running":  0,
-				"create_time": time.Now().Format("2006-01-02 15:04:05"),
-			})
-
-		if result.Error != nil {
-			panic(result.Error)
-		}
-
-		if result.RowsAffected == 0 {
-			// 乐观锁失败，说明其他进程已经处理了
-			return TaskResult{Status: TaskStatusAlreadyRunning}
-		}
-
-		// 更新本地记录状态
-		record.IsRunning = 0
-		record.CreateTime = time.Now().Format("2006-01-02 15:04:05")
-	}
-
-	// 如果当前状态不是0，说明正在运行
-	if record.IsRunning != 0 {
-		return TaskResult{Status: TaskStatusAlreadyRunning}
-	}
-
-	// 使用乐观锁更新状态为运行中
-	currentTime := time.Now().Format("2006-01-02 15:04:05")
-	result := conn.Model(&Records{}).
-		Where("tenant_id = ? AND account_id = ? AND raw_platform = ? AND sub_type = ? AND is_running = ?",
-			tenantId, accountId, platform, subType, record.IsRunning).
-		Updates(map[string]interface{}{
-			"is_running":  1,
-			"create_time": currentTime,
-		})
-
-	if result.Error != nil {
-		panic(result.Error)
-	}
-
-	if result.RowsAffected == 0 {
-		// 乐观锁失败，说明其他进程已经获取了这个任务
-		return TaskResult{Status: TaskStatusAlreadyRunning}
-	}
-
-	// 更新本地记录
-	record.IsRunning = 1
-	record.CreateTime = currentTime
-
-	return TaskResult{
-		Status: TaskStatusAcquired,
-		Record: &record,
-	}
+	return result
 }
 
 // SetRunning 设置任务为运行状态
@@ -192,22 +168,15 @@ func SetRunning(tenantId int64, accountId, platform, subType string) bool {
 	return result.RowsAffected > 0
 }
 
-// SetStop 设置任务为停止状态
-func SetStop(tenantId int64, accountId, platform, subType string) bool {
-	conn := platform_db.GetDB()
-	currentTime := time.Now().Format("2006-01-02 15:04:05")
-
-	result := conn.Model(&Records{}).
-		Where("tenant_id = ? AND account_id = ? AND raw_platform = ? AND sub_type = ?",
-			tenantId, accountId, platform, subType).
-		Updates(map[string]interface{}{
-			"is_running":  0,
-			"create_time": currentTime,
-		})
-
-	if result.Error != nil {
-		panic(result.Error)
+// SetStop 设置任务为停止状态，只有当前认领者可以停止，任务已被其他实例认领时返回 false
+func SetStop(tenantId int64, accountId, platform, subType, ownerId string) bool {
+	key := StateKey{Tenant: tenantId, Account: accountId, Platform: platform, SubType: subType}
+	err := ReleaseTask(key, ownerId, true)
+	if errors.Is(err, ErrNotTaskOwner) {
+		return false
 	}
-
-	return result.RowsAffected > 0
+	if err != nil {
+		panic(err)
+	}
+	return true
 }
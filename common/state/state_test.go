@@ -1,8 +1,13 @@
 package state
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
 )
 
 func TestGetSyncInfo(t *testing.T) {
@@ -12,3 +17,88 @@ func TestGetSyncInfo(t *testing.T) {
 	fmt.Println(res)
 
 }
+
+func TestStoreUpdate(t *testing.T) {
+	type cursor struct {
+		LastSync string `json:"last_sync"`
+		Runs     int    `json:"runs"`
+	}
+
+	store := NewStore[cursor]()
+	key := StateKey{Tenant: 133944, Account: "test-account", Platform: "test", SubType: "store"}
+
+	res, err := store.Update(context.Background(), key, func(cur cursor, found bool) (cursor, error) {
+		cur.Runs++
+		cur.LastSync = "2025-01-01"
+		return cur, nil
+	})
+	if err != nil {
+		t.Fatalf("Update 失败: %v", err)
+	}
+
+	loaded, found, err := store.Load(context.Background(), key)
+	if err != nil || !found {
+		t.Fatalf("Load 失败: found=%v err=%v", found, err)
+	}
+	if loaded != res {
+		t.Fatalf("Load 结果与 Update 不一致: %+v != %+v", loaded, res)
+	}
+}
+
+func TestIsRetryableConflict(t *testing.T) {
+	cases := []struct {
+		err  error
+		want bool
+	}{
+		{&mysql.MySQLError{Number: 1213, Message: "Deadlock found when trying to get lock"}, true},
+		{fmt.Errorf("save state: %w", &mysql.MySQLError{Number: 1062, Message: "Duplicate entry"}), true},
+		{&mysql.MySQLError{Number: 1205, Message: "Lock wait timeout exceeded"}, true},
+		{&mysql.MySQLError{Number: 1146, Message: "Table doesn't exist"}, false},
+		// 只看错误码，不匹配消息文本
+		{errors.New("Duplicate entry 'x' for key 'PRIMARY'"), false},
+	}
+	for _, c := range cases {
+		if got := isRetryableConflict(c.err); got != c.want {
+			t.Errorf("isRetryableConflict(%v) = %v, want %v", c.err, got, c.want)
+		}
+	}
+}
+
+func TestClaimTask(t *testing.T) {
+	key := StateKey{Tenant: 133944, Account: "test-account", Platform: "test", SubType: "claim"}
+	SaveSyncInfo(key.Tenant, key.Account, key.Platform, key.SubType, []byte(`{}`))
+
+	res, err := ClaimTask(key, "owner-a", time.Minute)
+	if err != nil || res.Status != TaskStatusAcquired {
+		t.Fatalf("owner-a 认领失败: status=%v err=%v", res.Status, err)
+	}
+
+	res, err = ClaimTask(key, "owner-b", time.Minute)
+	if err != nil || res.Status != TaskStatusAlreadyRunning {
+		t.Fatalf("owner-b 不应认领成功: status=%v err=%v", res.Status, err)
+	}
+	if err := Heartbeat(key, "owner-b"); !errors.Is(err, ErrNotTaskOwner) {
+		t.Fatalf("非认领者心跳应返回 ErrNotTaskOwner: %v", err)
+	}
+	if SetStop(key.Tenant, key.Account, key.Platform, key.SubType, "owner-b") {
+		t.Fatal("非认领者不应能停止任务")
+	}
+
+	if err := Heartbeat(key, "owner-a"); err != nil {
+		t.Fatalf("认领者心跳失败: %v", err)
+	}
+	if err := ReleaseTask(key, "owner-a", false); err != nil {
+		t.Fatalf("释放失败: %v", err)
+	}
+
+	res, err = ClaimTask(key, "owner-b", time.Minute)
+	if err != nil || res.Status != TaskStatusAcquired {
+		t.Fatalf("释放后 owner-b 认领失败: status=%v err=%v", res.Status, err)
+	}
+	if res.Claim.Attempts < 2 {
+		t.Fatalf("失败释放后尝试次数应累加: %d", res.Claim.Attempts)
+	}
+	if !SetStop(key.Tenant, key.Account, key.Platform, key.SubType, "owner-b") {
+		t.Fatal("认领者停止任务失败")
+	}
+}
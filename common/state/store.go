@@ -0,0 +1,182 @@
+package state
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"wm-func/common/db/platform_db"
+	"wm-func/common/lock"
+
+	"github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// StateKey 同步状态的主键
+type StateKey struct {
+	Tenant   int64
+	Account  string
+	Platform string
+	SubType  string
+}
+
+func (k StateKey) String() string {
+	return fmt.Sprintf("%d-%s-%s-%s", k.Tenant, k.Account, k.Platform, k.SubType)
+}
+
+// where 按完整主键过滤
+func (k StateKey) where(db *gorm.DB) *gorm.DB {
+	return db.Where("tenant_id = ? AND account_id = ? AND raw_platform = ? AND sub_type = ?",
+		k.Tenant, k.Account, k.Platform, k.SubType)
+}
+
+// ErrUpdateConflict Update 多次重试后仍与其他写入方冲突
+var ErrUpdateConflict = errors.New("state update conflict")
+
+// updateRetries Update 遇到并发插入冲突时的重试次数
+const updateRetries = 3
+
+// Store 类型化的同步状态存储，sync_info 以 JSON 形式保存 T
+// 所有方法返回错误而不是 panic
+type Store[T any] struct {
+	db *gorm.DB // 为空时使用 platform_db
+}
+
+// NewStore 创建使用 platform_db 连接的状态存储，连接在首次使用时建立
+func NewStore[T any]() *Store[T] {
+	return &Store[T]{}
+}
+
+// NewStoreWithDB 使用指定的数据库连接创建状态存储
+func NewStoreWithDB[T any](db *gorm.DB) *Store[T] {
+	return &Store[T]{db: db}
+}
+
+func (s *Store[T]) conn(ctx context.Context) *gorm.DB {
+	if s.db == nil {
+		return platform_db.GetDB().WithContext(ctx)
+	}
+	return s.db.WithContext(ctx)
+}
+
+// Load 读取状态，记录不存在或 sync_info 为空时 found 为 false
+func (s *Store[T]) Load(ctx context.Context, key StateKey) (T, bool, error) {
+	return s.load(s.conn(ctx), key, false)
+}
+
+// Save 保存状态，只更新 sync_info 和 create_time，不影响任务运行标记
+func (s *Store[T]) Save(ctx context.Context, key StateKey, v T) error {
+	return s.conn(ctx).Transaction(func(tx *gorm.DB) error {
+		return s.save(tx, key, v)
+	})
+}
+
+// SaveWithFence 在同一个事务中校验 fencing token 并保存状态
+// 锁已被其他持有者重新获取时返回 lock.ErrStaleToken，不会写入；锁需使用 MySQL 后端
+func (s *Store[T]) SaveWithFence(ctx context.Context, key StateKey, v T, fence lock.Fence) error {
+	return s.conn(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := fence.Check(tx); err != nil {
+			return err
+		}
+		return s.save(tx, key, v)
+	})
+}
+
+// History 返回最近 n 次变更，按版本从新到旧排列
+func (s *Store[T]) History(ctx context.Context, key StateKey, n int) ([]HistoryEntry, error) {
+	return history(s.conn(ctx), key, n)
+}
+
+// Rollback 将状态恢复为指定版本写入后的值
+func (s *Store[T]) Rollback(ctx context.Context, key StateKey, version int64) error {
+	return rollback(s.conn(ctx), key, version)
+}
+
+// Update 原子地读取-修改-写入状态
+// fn 收到当前值（不存在时为零值和 false），返回新值；fn 返回错误时放弃写入
+func (s *Store[T]) Update(ctx context.Context, key StateKey, fn func(cur T, found bool) (T, error)) (T, error) {
+	var res T
+	var err error
+	for i := 0; i < updateRetries; i++ {
+		err = s.conn(ctx).Transaction(func(tx *gorm.DB) error {
+			// 锁住当前行，保证读取和写入之间没有其他写入方
+			cur, found, err := s.load(tx, key, true)
+			if err != nil {
+				return err
+			}
+			next, err := fn(cur, found)
+			if err != nil {
+				return err
+			}
+			if err := s.save(tx, key, next); err != nil {
+				return err
+			}
+			res = next
+			return nil
+		})
+		// 记录不存在时并发插入可能触发死锁或唯一键冲突，重试一次即可读到对方写入的值
+		if err == nil || !isRetryableConflict(err) {
+			return res, err
+		}
+	}
+	var zero T
+	return zero, fmt.Errorf("%w: %s: %v", ErrUpdateConflict, key, err)
+}
+
+func (s *Store[T]) load(db *gorm.DB, key StateKey, forUpdate bool) (T, bool, error) {
+	var zero T
+	if forUpdate {
+		db = db.Clauses(clause.Locking{Strength: "UPDATE"})
+	}
+
+	var result SyncInfoResult
+	err := key.where(db.Model(&Records{}).Select("sync_info")).Take(&result).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return zero, false, nil
+		}
+		return zero, false, fmt.Errorf("load state %s: %w", key, err)
+	}
+	if len(result.SyncInfo) == 0 {
+		return zero, false, nil
+	}
+
+	var v T
+	if err := json.Unmarshal(result.SyncInfo, &v); err != nil {
+		return zero, false, fmt.Errorf("decode state %s: %w", key, err)
+	}
+	return v, true, nil
+}
+
+func (s *Store[T]) save(db *gorm.DB, key StateKey, v T) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("encode state %s: %w", key, err)
+	}
+
+	if err := writeSyncInfo(db, key, b); err != nil {
+		return fmt.Errorf("save state %s: %w", key, err)
+	}
+	return nil
+}
+
+// MySQL 中并发写入导致的可重试错误码
+const (
+	errDupEntry        = 1062 // ER_DUP_ENTRY
+	errLockWaitTimeout = 1205 // ER_LOCK_WAIT_TIMEOUT
+	errLockDeadlock    = 1213 // ER_LOCK_DEADLOCK
+)
+
+// isRetryableConflict 判断是否为并发写入导致的可重试错误
+func isRetryableConflict(err error) bool {
+	var myErr *mysql.MySQLError
+	if !errors.As(err, &myErr) {
+		return false
+	}
+	switch myErr.Number {
+	case errDupEntry, errLockWaitTimeout, errLockDeadlock:
+		return true
+	}
+	return false
+}
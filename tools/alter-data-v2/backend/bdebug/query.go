@@ -5,52 +5,37 @@ import (
 	"log"
 	"strings"
 	"wm-func/common/db/platform_db"
+	"wm-func/common/model"
 	"wm-func/tools/alter-data-v2/backend"
 )
 
+// selectSep 由 schema 生成的列之间的分隔，与手写部分的缩进一致
+const selectSep = ",\n      "
+
 /*+resource_group=job_production*/
 var query_data = `
 with
   tiktok_gmv_max_metrics as (
     select
-      wm_tenant_id                                                                            as tenant_id,
-      cast(json_extract(_airbyte_data, '$.campaign_id') as varchar)                           as campaign_id,
-      cast(json_extract(_airbyte_data, '$.advertiser_id') as varchar)                         as advertiser_id,
-      date(cast(json_extract(_airbyte_data, '$.stat_time_day') as varchar))                           as stat_date,
-      cast(json_extract(_airbyte_data, '$.metrics.cost') as double)                           as ad_spend,
-      cast(json_extract(_airbyte_data, '$.metrics.gross_revenue') as double)                  as gross_revenue,
-      cast(json_extract(_airbyte_data, '$.metrics.orders') as bigint)                         as orders
+      wm_tenant_id as tenant_id,
+      ` + model.TiktokGmvMaxMetricsSchema.Select(selectSep) + `
     from airbyte_destination_v2.raw_tiktok_marketing_gmv_max_metrics
   ),
   applovin_metrics as (
     select
       wm_tenant_id as tenant_id,
-      date(cast(json_extract (_airbyte_data, '$.day') as varchar)) as stat_date,
-      cast(json_extract (_airbyte_data, '$.account_id') as varchar) as account_id,
-      cast(json_extract (_airbyte_data, '$.campaign_id_external') as varchar) as campaign_id,
+      ` + model.ApplovinAdsMetricsSchema.Select(selectSep, "stat_date", "account_id", "campaign_id") + `,
       '' as adset_id,
-      cast(json_extract (_airbyte_data, '$.ad_id') as varchar) as ad_id,
-      cast(json_extract (_airbyte_data, '$.cost') as double) as ad_spend,
-      cast(json_extract (_airbyte_data, '$.impressions') as bigint) as impressions,
-      cast(json_extract (_airbyte_data, '$.clicks') as bigint) as clicks,
-      cast(json_extract (_airbyte_data, '$.chka_7d') as bigint) as ads_orders,
-      cast(json_extract (_airbyte_data, '$.chka_usd_7d') as double) as ads_sales
+      ` + model.ApplovinAdsMetricsSchema.Select(selectSep, "ad_id", "ad_spend", "impressions", "clicks", "ads_orders", "ads_sales") + `
     from
       airbyte_destination_v2.raw_applovin_ads_ads_metrics as api
   ),
   applovin_metrics_v2 as (
     select
       wm_tenant_id as tenant_id,
-      date(cast(json_extract (_airbyte_data, '$.day') as varchar)) as stat_date,
-      cast(json_extract (_airbyte_data, '$.account_id') as varchar) as account_id,
-      cast(json_extract (_airbyte_data, '$.campaign_id') as varchar) as campaign_id,
+      ` + model.ApplovinAdsV2MetricsSchema.Select(selectSep, "stat_date", "account_id", "campaign_id") + `,
       '' as adset_id,
-      cast(json_extract (_airbyte_data, '$.creative_set_id') as varchar) as ad_id,
-      cast(json_extract (_airbyte_data, '$.cost') as double) as ad_spend,
-      cast(json_extract (_airbyte_data, '$.impressions') as bigint) as impressions,
-      cast(json_extract (_airbyte_data, '$.clicks') as bigint) as clicks,
-      cast(json_extract (_airbyte_data, '$.chka_7d') as bigint) as ads_orders,
-      cast(json_extract (_airbyte_data, '$.chka_usd_7d') as double) as ads_sales
+      ` + model.ApplovinAdsV2MetricsSchema.Select(selectSep, "ad_id", "ad_spend", "impressions", "clicks", "ads_orders", "ads_sales") + `
     from
       airbyte_destination_v2.raw_applovin_ads_v2_ads_metrics as api
   ),
@@ -0,0 +1,54 @@
+package bdebug
+
+import (
+	"strings"
+	"testing"
+)
+
+// normalizeSQL 合并空白，去掉 json_extract 与括号之间的空格，只比较语义
+func normalizeSQL(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	return strings.ReplaceAll(s, "json_extract (", "json_extract(")
+}
+
+// TestQueryDataMatchesHandWritten 由 schema 生成的列必须与原来手写的列名、JSON 路径和类型转换一致
+func TestQueryDataMatchesHandWritten(t *testing.T) {
+	handWritten := []string{
+		// raw_tiktok_marketing_gmv_max_metrics
+		`wm_tenant_id                                                                            as tenant_id,
+      cast(json_extract(_airbyte_data, '$.campaign_id') as varchar)                           as campaign_id,
+      cast(json_extract(_airbyte_data, '$.advertiser_id') as varchar)                         as advertiser_id,
+      date(cast(json_extract(_airbyte_data, '$.stat_time_day') as varchar))                           as stat_date,
+      cast(json_extract(_airbyte_data, '$.metrics.cost') as double)                           as ad_spend,
+      cast(json_extract(_airbyte_data, '$.metrics.gross_revenue') as double)                  as gross_revenue,
+      cast(json_extract(_airbyte_data, '$.metrics.orders') as bigint)                         as orders
+    from airbyte_destination_v2.raw_tiktok_marketing_gmv_max_metrics`,
+		// applovin 广告
+		`date(cast(json_extract (_airbyte_data, '$.day') as varchar)) as stat_date,
+      cast(json_extract (_airbyte_data, '$.account_id') as varchar) as account_id,
+      cast(json_extract (_airbyte_data, '$.campaign_id_external') as varchar) as campaign_id,`,
+		`cast(json_extract (_airbyte_data, '$.ad_id') as varchar) as ad_id,
+      cast(json_extract (_airbyte_data, '$.cost') as double) as ad_spend,
+      cast(json_extract (_airbyte_data, '$.impressions') as bigint) as impressions,
+      cast(json_extract (_airbyte_data, '$.clicks') as bigint) as clicks,
+      cast(json_extract (_airbyte_data, '$.chka_7d') as bigint) as ads_orders,
+      cast(json_extract (_airbyte_data, '$.chka_usd_7d') as double) as ads_sales`,
+		// applovin 广告 v2
+		`date(cast(json_extract (_airbyte_data, '$.day') as varchar)) as stat_date,
+      cast(json_extract (_airbyte_data, '$.account_id') as varchar) as account_id,
+      cast(json_extract (_airbyte_data, '$.campaign_id') as varchar) as campaign_id,`,
+		`cast(json_extract (_airbyte_data, '$.creative_set_id') as varchar) as ad_id,
+      cast(json_extract (_airbyte_data, '$.cost') as double) as ad_spend,
+      cast(json_extract (_airbyte_data, '$.impressions') as bigint) as impressions,
+      cast(json_extract (_airbyte_data, '$.clicks') as bigint) as clicks,
+      cast(json_extract (_airbyte_data, '$.chka_7d') as bigint) as ads_orders,
+      cast(json_extract (_airbyte_data, '$.chka_usd_7d') as double) as ads_sales`,
+	}
+
+	got := normalizeSQL(query_data)
+	for _, want := range handWritten {
+		if !strings.Contains(got, normalizeSQL(want)) {
+			t.Errorf("生成的 SQL 与手写的不一致，缺少:\n%s", want)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package controller
 import (
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 	"wm-func/common/config"
 	"wm-func/tools/alter-data-v2/backend"
@@ -14,8 +15,10 @@ import (
 	"wm-func/tools/alter-data-v2/backend/tags"
 )
 
-// applovinLog 平台需要监控的租户ID列表
-var applovinLogTenantIds = []int64{150090}
+// applovinLogSettings applovinLog 平台的连接器配置，TenantAllow 为需要监控的租户，Apollo 修改后实时生效
+var applovinLogSettings = sync.OnceValue(func() *config.Value[config.ConnectorSettings] {
+	return config.Connector(backend.PLATFORN_APPLOVIN_LOG, config.ConnectorSettings{TenantAllow: []int64{150090}})
+})
 
 // filterEmptyTags 过滤掉空字符串的标签
 func filterEmptyTags(tags []string) []string {
@@ -285,7 +288,7 @@ func getWmOnlyAlterData(needRefresh bool, platform string, tenantId int64) AllTe
 
 	// 为 applovinLog 平台手动添加租户映射
 	if platform == "applovinLog" {
-		for _, tenantId := range applovinLogTenantIds {
+		for _, tenantId := range applovinLogSettings().Get().TenantAllow {
 			if tenantPlatformMap[tenantId] == nil {
 				tenantPlatformMap[tenantId] = make(map[string]bool)
 			}
@@ -0,0 +1,72 @@
+// raw-schema 由 common/model 中注册的 schema 生成类型化视图、select 片段和 JSON Schema
+//
+// 用法：
+//
+//	go run ./tools/raw-schema list
+//	go run ./tools/raw-schema view       [-table raw_applovin_ads_v2_ads_metrics] [-database airbyte_destination_v2]
+//	go run ./tools/raw-schema select     -table raw_applovin_ads_v2_ads_metrics
+//	go run ./tools/raw-schema jsonschema -table raw_applovin_ads_v2_ads_metrics
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"wm-func/common/model"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	cmd := os.Args[1]
+
+	fs := flag.NewFlagSet(cmd, flag.ExitOnError)
+	table := fs.String("table", "", "表名，不指定时输出所有已注册的表（select、jsonschema 必填）")
+	database := fs.String("database", "airbyte_destination_v2", "视图所在的库")
+	fs.Parse(os.Args[2:])
+
+	schemas := model.DefaultSchemas.All()
+	if *table != "" {
+		s, ok := model.DefaultSchemas.Lookup(*table)
+		if !ok {
+			log.Fatalf("%s 没有注册 schema", *table)
+		}
+		schemas = []*model.Schema{s}
+	}
+
+	switch cmd {
+	case "list":
+		for _, s := range schemas {
+			fmt.Printf("%s\t%s\t%d 列\n", s.Table, s.Type, len(s.Columns()))
+		}
+	case "view":
+		for _, s := range schemas {
+			fmt.Printf("%s;\n\n", s.ViewSQL(*database))
+		}
+	case "select":
+		requireTable(*table)
+		fmt.Println(schemas[0].Select(",\n"))
+	case "jsonschema":
+		requireTable(*table)
+		b, err := schemas[0].JSONSchema()
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println(string(b))
+	default:
+		usage()
+	}
+}
+
+func requireTable(table string) {
+	if table == "" {
+		log.Fatal("必须指定 -table")
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "用法: raw-schema list|view|select|jsonschema [-table 表名] [-database 库名]")
+	os.Exit(2)
+}
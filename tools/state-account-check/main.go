@@ -0,0 +1,112 @@
+// state-account-check 一次性检查工具
+// 旧版 state.GetSyncInfo 查询时没有过滤 account_id，同一租户下多个账户会读到彼此的游标，
+// 再以自己的 account_id 写回。本工具找出这类疑似写错账户的状态记录，只输出报告，不修改数据。
+//
+// 用法：go run ./tools/state-account-check -platform knocommerce [-subtype response]
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"log"
+	"sort"
+	"wm-func/common/db/platform_db"
+	"wm-func/common/state"
+	"wm-func/wm_account"
+)
+
+// Finding 一条可疑的状态记录
+type Finding struct {
+	Record state.Records
+	Reason string
+}
+
+func main() {
+	platform := flag.String("platform", "", "要检查的平台，例如 knocommerce（必填）")
+	subType := flag.String("subtype", "", "只检查指定子类型，为空时检查全部")
+	flag.Parse()
+
+	if *platform == "" {
+		log.Fatal("必须指定 -platform")
+	}
+
+	records := loadRecords(*platform, *subType)
+	log.Printf("[%s] 读取到状态记录 %d 条", *platform, len(records))
+
+	accounts := map[int64]map[string]bool{}
+	for _, a := range wm_account.GetAccountsWithPlatform(*platform) {
+		if accounts[a.TenantId] == nil {
+			accounts[a.TenantId] = map[string]bool{}
+		}
+		accounts[a.TenantId][a.AccountId] = true
+	}
+
+	findings := findMisattributed(records, accounts)
+	for _, f := range findings {
+		r := f.Record
+		fmt.Printf("%d\t%s\t%s\t%s\t%s\t%s\n", r.TenantId, r.AccountId, r.RawPlatform, r.SubType, r.CreateTime, f.Reason)
+	}
+	log.Printf("[%s] 检查完成，可疑记录 %d 条", *platform, len(findings))
+}
+
+func loadRecords(platform, subType string) []state.Records {
+	var records []state.Records
+	db := platform_db.GetDB().Where("raw_platform = ?", platform)
+	if subType != "" {
+		db = db.Where("sub_type = ?", subType)
+	}
+	if err := db.Order("tenant_id, sub_type, account_id").Find(&records).Error; err != nil {
+		panic(err)
+	}
+	return records
+}
+
+// findMisattributed 找出疑似写错账户的状态记录
+//   - 同一租户、平台、子类型下，与其他账户的 sync_info 完全相同：多半是读到了别的账户的游标后原样写回
+//   - account_id 不属于该租户当前连接的账户
+func findMisattributed(records []state.Records, accounts map[int64]map[string]bool) []Finding {
+	type groupKey struct {
+		tenantId int64
+		subType  string
+	}
+	groups := map[groupKey][]state.Records{}
+	for _, r := range records {
+		k := groupKey{r.TenantId, r.SubType}
+		groups[k] = append(groups[k], r)
+	}
+
+	var findings []Finding
+	for _, group := range groups {
+		for i, r := range group {
+			if known, ok := accounts[r.TenantId]; ok && !known[r.AccountId] {
+				findings = append(findings, Finding{Record: r, Reason: "account not connected to tenant"})
+				continue
+			}
+			if len(r.SyncInfo) == 0 {
+				continue
+			}
+			for j, other := range group {
+				if i != j && bytes.Equal(r.SyncInfo, other.SyncInfo) {
+					findings = append(findings, Finding{
+						Record: r,
+						Reason: fmt.Sprintf("sync_info identical to account %s", other.AccountId),
+					})
+					break
+				}
+			}
+		}
+	}
+
+	sort.Slice(findings, func(i, j int) bool {
+		a, b := findings[i].Record, findings[j].Record
+		if a.TenantId != b.TenantId {
+			return a.TenantId < b.TenantId
+		}
+		if a.SubType != b.SubType {
+			return a.SubType < b.SubType
+		}
+		return a.AccountId < b.AccountId
+	})
+	return findings
+}
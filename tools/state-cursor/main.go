@@ -0,0 +1,96 @@
+// state-cursor 查看和恢复同步游标
+//
+// 用法：
+//
+//	go run ./tools/state-cursor show     -tenant 150203 -platform knocommerce -subtype response [-account xxx]
+//	go run ./tools/state-cursor history  -tenant 150203 -platform knocommerce -subtype response -account xxx [-n 20]
+//	go run ./tools/state-cursor rollback -tenant 150203 -platform knocommerce -subtype response -account xxx -version 12
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"wm-func/common/state"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	cmd := os.Args[1]
+
+	fs := flag.NewFlagSet(cmd, flag.ExitOnError)
+	tenant := fs.Int64("tenant", 0, "租户 ID（必填）")
+	account := fs.String("account", "", "账户 ID")
+	platform := fs.String("platform", "", "平台，例如 knocommerce（必填）")
+	subType := fs.String("subtype", "", "子类型（必填）")
+	n := fs.Int("n", 20, "history 显示的条数")
+	version := fs.Int64("version", 0, "rollback 恢复到的版本")
+	fs.Parse(os.Args[2:])
+
+	if *tenant == 0 || *platform == "" || *subType == "" {
+		log.Fatal("必须指定 -tenant、-platform 和 -subtype")
+	}
+	key := state.StateKey{Tenant: *tenant, Account: *account, Platform: *platform, SubType: *subType}
+
+	switch cmd {
+	case "show":
+		show(key)
+	case "history":
+		requireAccount(key)
+		showHistory(key, *n)
+	case "rollback":
+		requireAccount(key)
+		if *version <= 0 {
+			log.Fatal("rollback 必须指定 -version")
+		}
+		if err := state.Rollback(key, *version); err != nil {
+			log.Fatalf("[%s] 回滚失败: %v", key, err)
+		}
+		log.Printf("[%s] 已恢复到版本 %d", key, *version)
+		show(key)
+	default:
+		usage()
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: state-cursor show|history|rollback -tenant ID -platform P -subtype S [-account A] [-n N] [-version V]")
+	os.Exit(2)
+}
+
+func requireAccount(key state.StateKey) {
+	if key.Account == "" {
+		log.Fatal("必须指定 -account")
+	}
+}
+
+// show 显示当前游标，未指定账户时列出租户下所有账户
+func show(key state.StateKey) {
+	if key.Account != "" {
+		fmt.Printf("%s\t%s\n", key.Account, state.GetSyncInfo(key.Tenant, key.Account, key.Platform, key.SubType))
+		return
+	}
+
+	records, err := state.GetTenantSyncInfos(key.Tenant, key.Platform, key.SubType)
+	if err != nil {
+		log.Fatalf("[%s] 读取同步状态失败: %v", key, err)
+	}
+	for _, r := range records {
+		fmt.Printf("%s\t%s\t%s\n", r.AccountId, r.CreateTime, r.SyncInfo)
+	}
+}
+
+// showHistory 显示最近 n 次变更
+func showHistory(key state.StateKey, n int) {
+	entries, err := state.History(key, n)
+	if err != nil {
+		log.Fatalf("[%s] 读取历史失败: %v", key, err)
+	}
+	for _, e := range entries {
+		fmt.Printf("v%d\t%s\t%s\t%s\n\told: %s\n\tnew: %s\n",
+			e.Version, e.CreatedAt.Format("2006-01-02 15:04:05"), e.RunId, e.Host, e.OldValue, e.NewValue)
+	}
+}